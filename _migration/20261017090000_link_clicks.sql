@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE "link_clicks"(
+    "id" BIGSERIAL PRIMARY KEY,
+    "link_id" INTEGER NOT NULL,
+    "clicked_at" TIMESTAMP NOT NULL,
+    "referrer" VARCHAR(255) NOT NULL DEFAULT '',
+    "user_agent" VARCHAR(255) NOT NULL DEFAULT '',
+    "ip_hash" CHAR(64) NOT NULL,
+
+    FOREIGN KEY ("link_id")
+        REFERENCES "links"("id")
+        ON DELETE CASCADE);
+
+CREATE INDEX "link_clicks_link_id_clicked_at_idx" 
+    ON "link_clicks"("link_id", "clicked_at");
+
+CREATE TABLE "link_click_rollups"(
+    "link_id" INTEGER NOT NULL,
+    "granularity" VARCHAR(7) NOT NULL, -- `hour` or `day`
+    "bucket" TIMESTAMP NOT NULL, -- start of the period
+    "clicks" BIGINT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY ("link_id", "granularity", "bucket"),
+    FOREIGN KEY ("link_id")
+        REFERENCES "links"("id")
+        ON DELETE CASCADE);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE "link_click_rollups";
+DROP TABLE "link_clicks";
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "password" VARCHAR(127) NOT NULL DEFAULT ''; -- empty means unprotected
+ALTER TABLE "short_configured_outbox"
+    ADD COLUMN "password" VARCHAR(127) NOT NULL DEFAULT '';
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "short_configured_outbox" DROP COLUMN "password";
+ALTER TABLE "links" DROP COLUMN "password";
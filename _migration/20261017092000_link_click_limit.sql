@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "max_clicks" INTEGER DEFAULT NULL, -- NULL means unlimited
+    ADD COLUMN "remaining_clicks" INTEGER DEFAULT NULL,
+    ADD CONSTRAINT "links_remaining_clicks_check" 
+        CHECK ("remaining_clicks" >= 0 AND "remaining_clicks" <= "max_clicks");
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links" 
+    DROP CONSTRAINT "links_remaining_clicks_check",
+    DROP COLUMN "remaining_clicks",
+    DROP COLUMN "max_clicks";
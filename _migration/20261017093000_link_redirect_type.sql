@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "redirect_type" SMALLINT NOT NULL DEFAULT 307,
+    ADD CONSTRAINT "links_redirect_type_check" 
+        CHECK ("redirect_type" IN (301, 302, 307, 308));
+
+CREATE TABLE IF NOT EXISTS "link_preferences" (
+    "user_id" BIGINT PRIMARY KEY,
+    "redirect_type" SMALLINT NOT NULL DEFAULT 307
+        CHECK ("redirect_type" IN (301, 302, 307, 308))
+);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE IF EXISTS "link_preferences";
+
+ALTER TABLE "links" 
+    DROP CONSTRAINT "links_redirect_type_check",
+    DROP COLUMN "redirect_type";
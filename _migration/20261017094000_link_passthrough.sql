@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "pass_query" BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN "pass_path" BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links" 
+    DROP COLUMN "pass_path",
+    DROP COLUMN "pass_query";
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "preview" BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- Best guess for the links made before the column existed
+UPDATE "links" SET "created_at" = "updated_at";
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links" 
+    DROP COLUMN "created_at",
+    DROP COLUMN "preview";
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE "bulk_shortened_outbox"(
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL,
+    "is_done" BOOLEAN DEFAULT false);
+
+CREATE TABLE "bulk_shortened_links"(
+    "outbox_id" INTEGER NOT NULL,
+    "link_id" INTEGER UNIQUE NOT NULL,
+
+    FOREIGN KEY ("outbox_id")
+        REFERENCES "bulk_shortened_outbox"("id")
+        ON DELETE CASCADE);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE "bulk_shortened_links";
+DROP TABLE "bulk_shortened_outbox";
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "folder" VARCHAR(63) NOT NULL DEFAULT '';
+
+CREATE INDEX "links_user_id_folder_idx" ON "links"("user_id", "folder");
+
+CREATE TABLE "link_tags"(
+    "link_id" INTEGER NOT NULL,
+    "tag" VARCHAR(31) NOT NULL,
+
+    PRIMARY KEY ("link_id", "tag"),
+    FOREIGN KEY ("link_id")
+        REFERENCES "links"("id")
+        ON DELETE CASCADE);
+
+CREATE INDEX "link_tags_tag_idx" ON "link_tags"("tag");
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE "link_tags";
+
+DROP INDEX "links_user_id_folder_idx";
+
+ALTER TABLE "links" 
+    DROP COLUMN "folder";
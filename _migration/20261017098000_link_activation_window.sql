@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "active_from" TIMESTAMP,
+    ADD COLUMN "active_until" TIMESTAMP,
+    ADD CONSTRAINT "links_active_window_check"
+        CHECK ("active_from" IS NULL OR "active_until" IS NULL OR "active_from" < "active_until");
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    DROP CONSTRAINT "links_active_window_check",
+    DROP COLUMN "active_until",
+    DROP COLUMN "active_from";
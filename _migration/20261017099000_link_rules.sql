@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE "link_rules"(
+    "id" SERIAL PRIMARY KEY,
+    "link_id" INTEGER NOT NULL,
+    "position" INTEGER NOT NULL DEFAULT 0,
+    "condition" VARCHAR(15) NOT NULL
+        CHECK ("condition" IN ('platform', 'language', 'country')),
+    "match_values" TEXT NOT NULL, -- Comma-separated
+    "destination" VARCHAR(255) NOT NULL,
+
+    FOREIGN KEY ("link_id")
+        REFERENCES "links"("id")
+        ON DELETE CASCADE);
+
+CREATE INDEX "link_rules_link_id_position_idx" ON "link_rules"("link_id", "position");
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE "link_rules";
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE "link_variants"(
+    "id" SERIAL PRIMARY KEY,
+    "link_id" INTEGER NOT NULL,
+    "destination" VARCHAR(255) NOT NULL,
+    "weight" INTEGER NOT NULL CHECK ("weight" > 0),
+    "hits" BIGINT NOT NULL DEFAULT 0,
+
+    UNIQUE ("link_id", "destination"),
+    FOREIGN KEY ("link_id")
+        REFERENCES "links"("id")
+        ON DELETE CASCADE);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE "link_variants";
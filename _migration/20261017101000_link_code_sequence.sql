@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Numbers behind the short codes of the `sequence` strategy
+CREATE SEQUENCE "link_code_seq" AS BIGINT;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP SEQUENCE "link_code_seq";
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE "link_revisions"(
+    "id" SERIAL PRIMARY KEY,
+    "link_id" INTEGER NOT NULL,
+    "number" INTEGER NOT NULL,
+    "editor_id" INTEGER, -- NULL when made by the system
+    "old_alias" VARCHAR(32), -- Old values are NULL on the revision creating the link
+    "old_destination" VARCHAR(255),
+    "old_is_open" BOOLEAN,
+    "new_alias" VARCHAR(32) NOT NULL,
+    "new_destination" VARCHAR(255) NOT NULL,
+    "new_is_open" BOOLEAN NOT NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE ("link_id", "number"),
+    FOREIGN KEY ("link_id")
+        REFERENCES "links"("id")
+        ON DELETE CASCADE,
+    FOREIGN KEY ("editor_id")
+        REFERENCES "users"("id")
+        ON DELETE SET NULL);
+
+-- Existing links start their history from where they are now
+INSERT INTO "link_revisions"(
+    "link_id",
+    "number",
+    "editor_id",
+    "new_alias",
+    "new_destination",
+    "new_is_open",
+    "created_at")
+SELECT "id", 1, "user_id", "alias", "destination", COALESCE("is_open", false), "updated_at"
+FROM "links";
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE "link_revisions";
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE "link_transfers"(
+    "id" SERIAL PRIMARY KEY,
+    "link_id" INTEGER NOT NULL,
+    "from_user_id" INTEGER NOT NULL,
+    "to_user_id" INTEGER NOT NULL,
+    "status" VARCHAR(15) NOT NULL,
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY ("link_id")
+        REFERENCES "links"("id")
+        ON DELETE CASCADE,
+    FOREIGN KEY ("from_user_id")
+        REFERENCES "users"("id")
+        ON DELETE CASCADE,
+    FOREIGN KEY ("to_user_id")
+        REFERENCES "users"("id")
+        ON DELETE CASCADE);
+
+-- A link could only be in one open transfer at a time
+CREATE UNIQUE INDEX "link_transfers_open_link_id_key"
+    ON "link_transfers"("link_id")
+    WHERE "status" IN ('pending', 'accepted');
+
+CREATE TABLE "transfer_accepted_outbox"(
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL,
+    "transfer_id" INTEGER UNIQUE NOT NULL,
+    "is_done" BOOLEAN DEFAULT false);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE "transfer_accepted_outbox";
+DROP TABLE "link_transfers";
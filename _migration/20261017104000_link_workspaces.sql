@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE "workspaces"(
+    "id" SERIAL PRIMARY KEY,
+    "name" VARCHAR(63) NOT NULL,
+    "subscriber_id" INTEGER NOT NULL, -- Whose subscription the workspace runs on
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY ("subscriber_id")
+        REFERENCES "users"("id")
+        ON DELETE CASCADE);
+
+CREATE TABLE "workspace_members"(
+    "workspace_id" INTEGER NOT NULL,
+    "user_id" INTEGER NOT NULL,
+    "role" VARCHAR(15) NOT NULL,
+
+    PRIMARY KEY ("workspace_id", "user_id"),
+    FOREIGN KEY ("workspace_id")
+        REFERENCES "workspaces"("id")
+        ON DELETE CASCADE,
+    FOREIGN KEY ("user_id")
+        REFERENCES "users"("id")
+        ON DELETE CASCADE);
+
+-- Links of a removed workspace go back to whoever made them
+ALTER TABLE "links"
+    ADD COLUMN "workspace_id" INTEGER
+        REFERENCES "workspaces"("id")
+        ON DELETE SET NULL;
+
+ALTER TABLE "link_shortened_outbox" ADD COLUMN "workspace_id" INTEGER;
+ALTER TABLE "short_configured_outbox" ADD COLUMN "workspace_id" INTEGER;
+ALTER TABLE "bulk_shortened_outbox" ADD COLUMN "workspace_id" INTEGER;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "bulk_shortened_outbox" DROP COLUMN "workspace_id";
+ALTER TABLE "short_configured_outbox" DROP COLUMN "workspace_id";
+ALTER TABLE "link_shortened_outbox" DROP COLUMN "workspace_id";
+ALTER TABLE "links" DROP COLUMN "workspace_id";
+DROP TABLE "workspace_members";
+DROP TABLE "workspaces";
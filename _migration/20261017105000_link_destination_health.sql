@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "health_status" INTEGER, -- Null when the destination couldn't be reached
+    ADD COLUMN "health_latency_ms" INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN "health_failure_streak" INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN "health_checked_at" TIMESTAMP; -- Null when the destination is yet to be checked
+
+CREATE INDEX "links_health_checked_at_idx"
+    ON "links"("health_checked_at" NULLS FIRST)
+    WHERE "is_open";
+
+CREATE TABLE "destination_unhealthy_outbox"(
+    "id" SERIAL PRIMARY KEY,
+    "link_id" INTEGER NOT NULL,
+    "user_id" INTEGER NOT NULL,
+    "workspace_id" INTEGER,
+    "destination" VARCHAR(255) NOT NULL,
+    "status" INTEGER,
+    "failure_streak" INTEGER NOT NULL,
+    "checked_at" TIMESTAMP NOT NULL,
+    "is_done" BOOLEAN DEFAULT false);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE "destination_unhealthy_outbox";
+DROP INDEX "links_health_checked_at_idx";
+ALTER TABLE "links"
+    DROP COLUMN "health_checked_at",
+    DROP COLUMN "health_failure_streak",
+    DROP COLUMN "health_latency_ms",
+    DROP COLUMN "health_status";
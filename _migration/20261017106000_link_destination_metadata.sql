@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "meta_title" VARCHAR(300) NOT NULL DEFAULT '',
+    ADD COLUMN "meta_og_title" VARCHAR(300) NOT NULL DEFAULT '',
+    ADD COLUMN "meta_og_description" VARCHAR(300) NOT NULL DEFAULT '',
+    ADD COLUMN "meta_og_image" VARCHAR(2048) NOT NULL DEFAULT '',
+    ADD COLUMN "meta_favicon" VARCHAR(2048) NOT NULL DEFAULT '',
+    ADD COLUMN "meta_fetched_at" TIMESTAMP; -- Null when the destination is yet to be fetched
+
+CREATE INDEX "links_meta_unfetched_idx"
+    ON "links"("id")
+    WHERE "meta_fetched_at" IS NULL;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX "links_meta_unfetched_idx";
+ALTER TABLE "links"
+    DROP COLUMN "meta_fetched_at",
+    DROP COLUMN "meta_favicon",
+    DROP COLUMN "meta_og_image",
+    DROP COLUMN "meta_og_description",
+    DROP COLUMN "meta_og_title",
+    DROP COLUMN "meta_title";
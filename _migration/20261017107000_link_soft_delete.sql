@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "links"
+    ADD COLUMN "deleted_at" TIMESTAMP; -- Null unless the link is in the trash
+
+CREATE INDEX "links_deleted_at_idx"
+    ON "links"("deleted_at")
+    WHERE "deleted_at" IS NOT NULL;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX "links_deleted_at_idx";
+ALTER TABLE "links"
+    DROP COLUMN "deleted_at";
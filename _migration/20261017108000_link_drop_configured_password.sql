@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Passwords are applied right away rather than through the subscription check
+ALTER TABLE "short_configured_outbox" DROP COLUMN "password";
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "short_configured_outbox"
+    ADD COLUMN "password" VARCHAR(127) NOT NULL DEFAULT '';
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Users as announced by `account`, so `link` wouldn't read "users" directly
+CREATE TABLE "link_owners"(
+    "id" BIGINT PRIMARY KEY,
+    "username" VARCHAR(31) NOT NULL);
+
+CREATE INDEX "link_owners_username_idx"
+    ON "link_owners"("username");
+
+-- Users registered before the announcements began
+INSERT INTO "link_owners"("id", "username")
+SELECT "id", "username" FROM "users";
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE "link_owners";
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Lets the links be sorted by their clicks off the index alone
+CREATE INDEX "link_click_rollups_link_id_granularity_idx"
+    ON "link_click_rollups"("link_id", "granularity")
+    INCLUDE ("clicks");
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX "link_click_rollups_link_id_granularity_idx";
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Who requested the change, which isn't necessarily the owner of the link on
+-- workspaces. Null on the rows made before, which fall back to the owner
+ALTER TABLE "short_configured_outbox" ADD COLUMN "editor_id" INTEGER;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE "short_configured_outbox" DROP COLUMN "editor_id";
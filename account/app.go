@@ -51,6 +51,7 @@ func RunApp() {
 		strings.Repeat(envTokenSecret, 2),
 		time.Duration(envRefreshTokenLifetime))
 	createSubscriptionMessenger := messaging.CreateSubscriptionMessenger{Version: 1}
+	registerOwnerMessenger := messaging.RegisterOwnerMessenger{Version: 1}
 
 	// Props to: https://medium.com/@lokeahnming/that-time-i-took-down-my-production-site-with-too-many-database-connections-8758406445e5
 	dbCfg, err := pgx.ParseConfig(envDbUrl)
@@ -133,7 +134,9 @@ func RunApp() {
 			interval: time.Second * 2,
 			callback: func() error {
 				return accountService.HandleRegisteredUsers(
-					20, createSubscriptionMessenger.FromManyUserRegistered)
+					20,
+					createSubscriptionMessenger.FromManyUserRegistered,
+					registerOwnerMessenger.FromManyUserRegistered)
 			}}}
 	for _, p := range publishers {
 		go func() {
@@ -1,16 +1,18 @@
 package messaging
 
 type UserRegistered struct {
-	id     uint64
-	userId uint64
-	isDone bool
+	id       uint64
+	userId   uint64
+	username string
+	isDone   bool
 }
 
 func (ur *UserRegistered) Done() { ur.isDone = true }
 
-func (ur UserRegistered) Id() uint64     { return ur.id }
-func (ur UserRegistered) UserId() uint64 { return ur.userId }
+func (ur UserRegistered) Id() uint64       { return ur.id }
+func (ur UserRegistered) UserId() uint64   { return ur.userId }
+func (ur UserRegistered) Username() string { return ur.username }
 
-func NewRegister(id, userId uint64, isDone bool) UserRegistered {
-	return UserRegistered{id, userId, isDone}
+func NewRegister(id, userId uint64, username string, isDone bool) UserRegistered {
+	return UserRegistered{id, userId, username, isDone}
 }
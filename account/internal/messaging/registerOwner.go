@@ -0,0 +1,53 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/solsteace/kochira/account/internal/domain/account/messaging"
+)
+
+type registerOwnerUser struct {
+	Id       uint64 `json:"id"`
+	Username string `json:"username"`
+}
+
+type registerOwnerData struct {
+	Owners []registerOwnerUser `json:"owners"`
+}
+
+type RegisterOwnerMessenger struct {
+	Version uint
+}
+
+// Tells `link` who the new users are, so it wouldn't have to read them from
+// `account`
+func (rom RegisterOwnerMessenger) FromManyUserRegistered(
+	msg []messaging.UserRegistered,
+) ([]byte, error) {
+	owners := []registerOwnerUser{}
+	for _, u := range msg {
+		owners = append(owners, registerOwnerUser{
+			Id:       u.UserId(),
+			Username: u.Username()})
+	}
+	payload := struct {
+		Meta meta              `json:"meta"`
+		Data registerOwnerData `json:"data"`
+	}{
+		Meta: meta{
+			Version:  rom.Version,
+			IssuedAt: time.Now(),
+		},
+		Data: registerOwnerData{
+			Owners: owners},
+	}
+
+	marshalledPayload, err := json.Marshal(payload)
+	if err != nil {
+		return []byte{}, fmt.Errorf(
+			"messaging<RegisterOwnerMessenger.FromManyUserRegistered>: %w", err)
+	}
+	return marshalledPayload, nil
+}
@@ -44,17 +44,18 @@ func newPgAccountUser(a account.User) pgAccountUser {
 }
 
 type pgAccountRegistrationOutbox struct {
-	Id     uint64 `db:"id"`
-	UserId uint64 `db:"user_id"`
-	IsDone bool   `db:"is_done"`
+	Id       uint64 `db:"id"`
+	UserId   uint64 `db:"user_id"`
+	Username string `db:"username"`
+	IsDone   bool   `db:"is_done"`
 }
 
 func (row pgAccountRegistrationOutbox) toOutbox() messaging.UserRegistered {
-	return messaging.NewRegister(row.Id, row.UserId, row.IsDone)
+	return messaging.NewRegister(row.Id, row.UserId, row.Username, row.IsDone)
 }
 
-func newPgRegistrationOutboxRow(id uint64, userId uint64, isDone bool) pgAccountRegistrationOutbox {
-	return pgAccountRegistrationOutbox{id, userId, isDone}
+func newPgRegistrationOutboxRow(id uint64, userId uint64, username string, isDone bool) pgAccountRegistrationOutbox {
+	return pgAccountRegistrationOutbox{id, userId, username, isDone}
 }
 
 func (repo pgAccount) GetById(id uint) (account.User, error) {
@@ -134,9 +135,10 @@ func (repo pgAccount) Update(a account.User) error {
 func (repo pgAccount) GetRegisterOutbox(count uint) ([]messaging.UserRegistered, error) {
 	rows := new([]pgAccountRegistrationOutbox)
 	query := `
-		SELECT *
-		FROM register_outbox
-		WHERE is_done = false
+		SELECT o.id, o.user_id, u.username, o.is_done
+		FROM register_outbox o
+		JOIN users u ON u.id = o.user_id
+		WHERE o.is_done = false
 		LIMIT $1 `
 	args := []any{count}
 	if err := repo.db.Select(rows, query, args...); err != nil {
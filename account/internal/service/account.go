@@ -10,7 +10,10 @@ import (
 	"github.com/solsteace/kochira/account/internal/utility/hash"
 )
 
-const CreateSubscriptionQueue = "subscription.creator" // `depends on `subscription` service
+const (
+	CreateSubscriptionQueue = "subscription.creator" // `depends on `subscription` service
+	RegisterOwnerQueue      = "link.owner_registrar" // `depends on `link` service
+)
 
 type Account struct {
 	accountStore accountStore.User
@@ -50,9 +53,12 @@ func (as Account) Register(username, password, email string) error {
 // Event handling
 // ========================
 
+// Announces the new users to `subscription` and `link`. Both are told again
+// when either announcement fails, hence they should take repeated users well
 func (as Account) HandleRegisteredUsers(
 	maxCount uint,
-	serialize func(msg []messaging.UserRegistered) ([]byte, error),
+	serializeSubscription func(msg []messaging.UserRegistered) ([]byte, error),
+	serializeOwner func(msg []messaging.UserRegistered) ([]byte, error),
 ) error {
 	outbox, err := as.accountStore.GetRegisterOutbox(maxCount)
 	if err != nil {
@@ -61,15 +67,20 @@ func (as Account) HandleRegisteredUsers(
 		return nil
 	}
 
-	payload, err := serialize(outbox)
-	if err != nil {
-		return fmt.Errorf("service<Account.HandleNewUsers>: %w", err)
-	}
+	for queue, serialize := range map[string]func([]messaging.UserRegistered) ([]byte, error){
+		CreateSubscriptionQueue: serializeSubscription,
+		RegisterOwnerQueue:      serializeOwner,
+	} {
+		payload, err := serialize(outbox)
+		if err != nil {
+			return fmt.Errorf("service<Account.HandleNewUsers>: %w", err)
+		}
 
-	err = as.messenger.Publish("default", payload, utility.NewDefaultAmqpPublishOpts(
-		"", CreateSubscriptionQueue, "application/json"))
-	if err != nil {
-		return fmt.Errorf("service<Account.HandleNewUsers>: %w", err)
+		err = as.messenger.Publish("default", payload, utility.NewDefaultAmqpPublishOpts(
+			"", queue, "application/json"))
+		if err != nil {
+			return fmt.Errorf("service<Account.HandleNewUsers>: %w", err)
+		}
 	}
 
 	resolved := []uint64{}
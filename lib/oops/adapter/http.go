@@ -18,6 +18,8 @@ func HttpStatusCode(err error) int {
 		return http.StatusForbidden
 	case errors.As(err, &oops.NotFound{}):
 		return http.StatusNotFound
+	case errors.As(err, &oops.TooManyRequests{}):
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -35,7 +37,8 @@ func HttpErrorMsg(err error) string {
 		errors.As(lastErr, &oops.BadValues{}),
 		errors.As(lastErr, &oops.Unauthorized{}),
 		errors.As(lastErr, &oops.Forbidden{}),
-		errors.As(lastErr, &oops.NotFound{}):
+		errors.As(lastErr, &oops.NotFound{}),
+		errors.As(lastErr, &oops.TooManyRequests{}):
 		return lastErr.Error()
 	}
 	return "internal server error"
@@ -0,0 +1,17 @@
+package oops
+
+// An error equivalent to 429 Too Many Requests HTTP error.
+type TooManyRequests struct {
+	// Message to be sent to client
+	Msg string
+
+	// Actual error
+	Err error
+}
+
+func (e TooManyRequests) Error() string {
+	if e.Msg == "" {
+		return "You have made too many attempts, please try again later"
+	}
+	return e.Msg
+}
@@ -0,0 +1,26 @@
+package reqres
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+)
+
+// Renders the template before writing anything, so a failing render could
+// still be answered with a proper error response
+func HttpHtml(
+	w http.ResponseWriter,
+	statusCode int,
+	tmpl *template.Template,
+	payload any,
+) error {
+	body := new(bytes.Buffer)
+	if err := tmpl.Execute(body, payload); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(body.Bytes())
+	return nil
+}
@@ -3,7 +3,9 @@ package link
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -11,12 +13,20 @@ import (
 	_ "github.com/jackc/pgx/v5"
 	"github.com/jmoiron/sqlx"
 	"github.com/solsteace/kochira/link/internal/controller"
+	shorteningDomainService "github.com/solsteace/kochira/link/internal/domain/shortening/service"
 	"github.com/solsteace/kochira/link/internal/messaging"
 	"github.com/solsteace/kochira/link/internal/middleware"
 	"github.com/solsteace/kochira/link/internal/persistence"
 	"github.com/solsteace/kochira/link/internal/route"
 	"github.com/solsteace/kochira/link/internal/service"
 	"github.com/solsteace/kochira/link/internal/utility"
+	"github.com/solsteace/kochira/link/internal/utility/blocklist"
+	"github.com/solsteace/kochira/link/internal/utility/hash"
+	"github.com/solsteace/kochira/link/internal/utility/netguard"
+	"github.com/solsteace/kochira/link/internal/utility/probe"
+	"github.com/solsteace/kochira/link/internal/utility/realip"
+	"github.com/solsteace/kochira/link/internal/utility/unfurl"
+	"github.com/valkey-io/valkey-go"
 )
 
 type publisher struct {
@@ -37,12 +47,20 @@ func RunApp() {
 	// ========================================
 	upSince := time.Now().Unix()
 	userContext := middleware.NewUserContext("X-User-Id")
+	hasher := hash.NewBcrypt(10)
 
 	dbClient, err := sqlx.Connect("pgx", envDbUrl)
 	if err != nil {
 		log.Fatalf("%s: DB connect: %v", moduleName, err)
 	}
 
+	cacheClient, err := valkey.NewClient(
+		valkey.MustParseURL(envCacheUrl))
+	if err != nil {
+		log.Fatalf("%s: cache init: %v", moduleName, err)
+	}
+	defer cacheClient.Close()
+
 	mq := utility.NewAmqp()
 	mqInitReady := make(chan struct{})
 	go mq.Start(envMqUrl, mqInitReady)
@@ -51,10 +69,20 @@ func RunApp() {
 	if err := mq.AddChannel("default"); err != nil {
 		log.Fatalf("%s: channel init: %v", moduleName, err)
 	}
+	exchanges := map[string]string{
+		service.DestinationUnhealthyExchange: "fanout"}
+	for name, kind := range exchanges {
+		err := mq.AddExchange("default", utility.NewDefaultAmqpExchangeOpts(name, kind))
+		if err != nil {
+			log.Fatalf("%s: exchange init: %v", moduleName, err)
+		}
+	}
+
 	queues := map[string][]string{
 		"default": []string{
 			service.FinishShorteningQueue,
-			service.SubscriptionExpiredQueue}}
+			service.SubscriptionExpiredQueue,
+			service.OwnerRegisteredQueue}}
 	for c, queue := range queues {
 		for _, q := range queue {
 			err := mq.AddQueue(c, utility.NewDefaultAmqpQueueOpts(q))
@@ -78,14 +106,80 @@ func RunApp() {
 	// Layers
 	// ========================================
 	linkRepo := persistence.NewPgLink(dbClient)
+	redirectCache := persistence.NewValkeyRedirect(
+		cacheClient,
+		linkRepo,
+		envCacheRetention,
+		envCacheMissRetention,
+		envCacheStaleRetention)
 
-	shorteningService := service.NewShortening(linkRepo, &mq)
-	shorteningController := controller.NewShortening(shorteningService)
+	aliasPolicy := shorteningDomainService.NewAliasPolicy(
+		"abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_",
+		4,
+		32,
+		append([]string{
+			"link", "my", "alias", "account", "subscription", "auth",
+			"api", "admin", "health", "static", "login", "logout", "workspace"},
+			envAliasReserved...),
+		envAliasProfanity)
+	destinationBlocklist, err := blocklist.NewFile(envDestinationBlocklist)
+	if err != nil {
+		log.Fatalf("%s: blocklist init: %v", moduleName, err)
+	}
+	ownHosts := []string{}
+	if publicUrl, err := url.Parse(envPublicUrl); err == nil {
+		ownHosts = append(ownHosts, publicUrl.Hostname())
+	}
+	destinationPolicy := shorteningDomainService.NewDestinationPolicy(
+		[]string{"http", "https"},
+		destinationBlocklist,
+		ownHosts,
+		net.LookupIP,
+		netguard.IsPublic)
+	codeGenerator, err := shorteningDomainService.NewCodeGenerator(
+		envCodeStrategy,
+		envCodeMinLen,
+		envCodeMaxLen,
+		linkRepo)
+	if err != nil {
+		log.Fatalf("%s: code generator init: %v", moduleName, err)
+	}
+	shorteningService := service.NewShortening(
+		linkRepo,
+		linkRepo,
+		linkRepo,
+		linkRepo,
+		linkRepo,
+		linkRepo,
+		linkRepo,
+		redirectCache,
+		hasher,
+		aliasPolicy,
+		destinationPolicy,
+		codeGenerator,
+		&mq)
+	shorteningController := controller.NewShortening(shorteningService, envPublicUrl)
 	shorteningRoute := route.NewShortening(shorteningController, userContext)
 
-	redirectSerivce := service.NewRedirect(linkRepo)
-	redirectController := controller.NewRedirect(redirectSerivce)
-	redirectionRoute := route.NewRedirect(redirectController)
+	ownerService := service.NewOwner(linkRepo)
+	ownerController := controller.NewOwner(ownerService)
+
+	workspaceService := service.NewWorkspace(linkRepo)
+	workspaceController := controller.NewWorkspace(workspaceService)
+	workspaceRoute := route.NewWorkspace(workspaceController, userContext)
+
+	healthService := service.NewHealth(
+		linkRepo,
+		probe.NewHttp(
+			&http.Client{Transport: netguard.NewTransport(envHealthTimeout)},
+			envHealthTimeout,
+			envHealthHostInterval),
+		envHealthInterval,
+		envHealthThreshold,
+		&mq)
+	metadataService := service.NewMetadata(
+		linkRepo,
+		unfurl.NewHttp(envMetadataTimeout, int64(envMetadataMaxBytes)))
 
 	// ========================================
 	// Routings
@@ -93,18 +187,20 @@ func RunApp() {
 	app := chi.NewRouter()
 	v1 := chi.NewRouter()
 	app.Use(chiMiddleware.RequestID)
+	app.Use(realip.Middleware(envTrustedProxies))
 	app.Use(chiMiddleware.Logger)
 	app.Use(chiMiddleware.Recoverer)
 
 	shorteningRoute.Use(v1)
-	redirectionRoute.Use(v1)
+	workspaceRoute.Use(v1)
 	app.Mount("/api/v1", v1)
 	route.NewApi(upSince).Use(app)
 
 	// ========================================
 	// Subscriptions, messaging, side-effects
 	// ========================================
-	checkSubscriptionMsg := messaging.CheckSubscriptionMessenger{Version: 1}
+	checkSubscriptionMsg := messaging.CheckSubscriptionMessenger{Version: 2}
+	destinationUnhealthyMsg := messaging.DestinationUnhealthyMessenger{Version: 1}
 	publishers := []publisher{
 		publisher{
 			interval: time.Second * 2,
@@ -117,6 +213,48 @@ func RunApp() {
 			callback: func() error {
 				return shorteningService.PublishShortConfigured(
 					20, checkSubscriptionMsg.FromShortConfigured)
+			}},
+		publisher{
+			interval: time.Second * 2,
+			callback: func() error {
+				return shorteningService.PublishBulkShortened(
+					20, checkSubscriptionMsg.FromBulkShortened)
+			}},
+		publisher{
+			interval: time.Second * 2,
+			callback: func() error {
+				return shorteningService.PublishTransferAccepted(
+					20, checkSubscriptionMsg.FromTransferAccepted)
+			}},
+		publisher{
+			interval: time.Second * 2,
+			callback: func() error {
+				return healthService.PublishDestinationUnhealthy(
+					20, destinationUnhealthyMsg.FromDestinationUnhealthy)
+			}},
+		publisher{
+			interval: time.Minute,
+			callback: func() error {
+				return healthService.CheckDue(100)
+			}},
+		publisher{
+			interval: time.Second * 5,
+			callback: func() error {
+				return metadataService.FetchUnfetched(40)
+			}},
+		publisher{
+			interval: time.Minute,
+			callback: func() error {
+				return shorteningService.PurgeTrash(envTrashRetention, 500)
+			}},
+		publisher{
+			interval: time.Second * 30,
+			callback: func() error {
+				// The last good blocklist stays in effect meanwhile
+				if err := destinationBlocklist.Reload(); err != nil {
+					log.Printf("%s: blocklist reload: %v\n", moduleName, err)
+				}
+				return nil
 			}}}
 	for _, p := range publishers {
 		go func() {
@@ -136,6 +274,9 @@ func RunApp() {
 		listener{
 			shorteningController.ListenSubscriptionExpired,
 			service.SubscriptionExpiredQueue},
+		listener{
+			ownerController.ListenOwnerRegistered,
+			service.OwnerRegisteredQueue},
 	}
 	for _, l := range listeners {
 		opts := utility.NewDefaultAmqpConsumeOpts(l.queue, false)
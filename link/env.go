@@ -1,16 +1,51 @@
 package link
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/solsteace/kochira/link/internal/utility/realip"
+	"github.com/valkey-io/valkey-go"
 )
 
 var (
 	envPort int
 
-	envMqUrl string
-	envDbUrl string
+	envMqUrl    string
+	envDbUrl    string
+	envCacheUrl string
+
+	envPublicUrl      string         // Base URL the shortened links are served from
+	envTrustedProxies []netip.Prefix // Proxies whose `X-Forwarded-For` tells the client address
+
+	envAliasReserved  []string // Aliases users couldn't take, on top of the built-in ones
+	envAliasProfanity []string // Words that shouldn't appear in an alias
+
+	envDestinationBlocklist string // Path to the file listing blocked destination hosts
+
+	envCodeStrategy string // How short codes are made: `random`, `sequence`, or `hash`
+	envCodeMinLen   uint   // Length of short codes, before collisions make them grow
+	envCodeMaxLen   uint   // Length short codes could grow up to
+
+	envHealthInterval     time.Duration // How long a checked destination is left alone?
+	envHealthTimeout      time.Duration // How long a single check could take?
+	envHealthHostInterval time.Duration // How long to wait between checks on the same host?
+	envHealthThreshold    uint          // How many failed checks in a row make a destination unhealthy?
+
+	envMetadataTimeout  time.Duration // How long fetching a destination page could take?
+	envMetadataMaxBytes uint          // How much of a destination page is read?
+
+	envTrashRetention time.Duration // How long deleted links stay in the trash before purged?
+
+	// Shared with the redirect app, as both reach the same cache
+	envCacheRetention      time.Duration // How long a found link is cached?
+	envCacheMissRetention  time.Duration // How long a missing link is cached?
+	envCacheStaleRetention time.Duration // How long an invalidated link is read off the primary? Should outlast the replica lag
 )
 
 func LoadEnv() error {
@@ -27,5 +62,116 @@ func LoadEnv() error {
 
 	envMqUrl = os.Getenv("LINK_MQ_URL")
 	envDbUrl = os.Getenv("LINK_DB_URL")
+	envCacheUrl = os.Getenv("LINK_CACHE_URL")
+	if _, err := valkey.ParseURL(envCacheUrl); err != nil {
+		err := fmt.Errorf("`LINK_CACHE_URL`: %s", err)
+		return fmt.Errorf("internal<LoadEnv>: %w", err)
+	}
+	envPublicUrl = strings.TrimSuffix(os.Getenv("LINK_PUBLIC_URL"), "/")
+	if envPublicUrl == "" {
+		err := errors.New("`LINK_PUBLIC_URL`: should be set, as QR codes point to it")
+		return fmt.Errorf("internal<LoadEnv>: %w", err)
+	}
+	trustedProxies, err := realip.ParseTrusted(os.Getenv("LINK_TRUSTED_PROXIES"))
+	if err != nil {
+		err := fmt.Errorf("`LINK_TRUSTED_PROXIES`: %s", err)
+		return fmt.Errorf("internal<LoadEnv>: %w", err)
+	}
+	envTrustedProxies = trustedProxies
+	envAliasReserved = splitEnvList(os.Getenv("LINK_ALIAS_RESERVED"))
+	envAliasProfanity = splitEnvList(os.Getenv("LINK_ALIAS_PROFANITY"))
+	envDestinationBlocklist = os.Getenv("LINK_DESTINATION_BLOCKLIST")
+
+	envCodeStrategy = os.Getenv("LINK_CODE_STRATEGY")
+	for key, dst := range map[string]*uint{
+		"LINK_CODE_MIN_LEN": &envCodeMinLen,
+		"LINK_CODE_MAX_LEN": &envCodeMaxLen,
+	} {
+		length, err := parseEnvUint(key, os.Getenv(key))
+		if err != nil {
+			return fmt.Errorf("internal<LoadEnv>: %w", err)
+		}
+		*dst = length
+	}
+	if envCodeMinLen == 0 {
+		envCodeMinLen = 6
+	}
+	if envCodeMaxLen == 0 {
+		envCodeMaxLen = max(8, envCodeMinLen)
+	}
+
+	for key, dst := range map[string]struct {
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		"LINK_HEALTH_INTERVAL":      {&envHealthInterval, time.Hour},
+		"LINK_HEALTH_TIMEOUT":       {&envHealthTimeout, time.Second * 10},
+		"LINK_HEALTH_HOST_INTERVAL": {&envHealthHostInterval, time.Second},
+		"LINK_METADATA_TIMEOUT":     {&envMetadataTimeout, time.Second * 5},
+		"LINK_TRASH_RETENTION":      {&envTrashRetention, time.Hour * 24 * 30},
+
+		"LINK_REDIRECT_CACHE_RETENTION":       {&envCacheRetention, time.Minute * 10},
+		"LINK_REDIRECT_CACHE_MISS_RETENTION":  {&envCacheMissRetention, time.Second * 30},
+		"LINK_REDIRECT_CACHE_STALE_RETENTION": {&envCacheStaleRetention, time.Minute},
+	} {
+		d, err := parseEnvDuration(key, os.Getenv(key), dst.fallback)
+		if err != nil {
+			return fmt.Errorf("internal<LoadEnv>: %w", err)
+		}
+		*dst.value = d
+	}
+	threshold, err := parseEnvUint("LINK_HEALTH_THRESHOLD", os.Getenv("LINK_HEALTH_THRESHOLD"))
+	if err != nil {
+		return fmt.Errorf("internal<LoadEnv>: %w", err)
+	} else if threshold == 0 {
+		threshold = 3
+	}
+	envHealthThreshold = threshold
+
+	maxBytes, err := parseEnvUint("LINK_METADATA_MAX_BYTES", os.Getenv("LINK_METADATA_MAX_BYTES"))
+	if err != nil {
+		return fmt.Errorf("internal<LoadEnv>: %w", err)
+	} else if maxBytes == 0 {
+		maxBytes = 512 * 1024
+	}
+	envMetadataMaxBytes = maxBytes
 	return nil
 }
+
+// Parses env values like `90s` or `1h30m`. Empty values are parsed as
+// `fallback`
+func parseEnvDuration(key string, raw string, fallback time.Duration) (time.Duration, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("`%s`: %s", key, err)
+	} else if d <= 0 {
+		return 0, fmt.Errorf("`%s`: duration should be positive (get: %s)", key, raw)
+	}
+	return d, nil
+}
+
+// Parses unsigned env values. Empty values are parsed as zero
+func parseEnvUint(key string, raw string) (uint, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("`%s`: %s", key, err)
+	}
+	return uint(n), nil
+}
+
+// Splits comma-separated env values, dropping the empty entries
+func splitEnvList(raw string) []string {
+	list := []string{}
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
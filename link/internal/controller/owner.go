@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+	"github.com/solsteace/kochira/link/internal/messaging"
+	"github.com/solsteace/kochira/link/internal/service"
+)
+
+type Owner struct {
+	service         service.Owner
+	ownerRegistered messaging.OwnerRegisteredMessenger
+}
+
+func (oc Owner) ListenOwnerRegistered(msg []byte) error {
+	payload, err := oc.ownerRegistered.FromMsg(msg)
+	if err != nil {
+		return fmt.Errorf("controller<Owner.ListenOwnerRegistered>: %w", err)
+	}
+
+	if oc.ownerRegistered.Version != payload.Meta.Version {
+		return fmt.Errorf(
+			"controller<Owner.ListenOwnerRegistered>: "+
+				"incompatible version between messenger(v:%d) and message(v:%d)",
+			oc.ownerRegistered.Version, payload.Meta.Version)
+	}
+
+	owners := []shortening.Owner{}
+	for _, o := range payload.Data.Owners {
+		owners = append(owners, shortening.NewOwner(o.Id, o.Username))
+	}
+	if err := oc.service.HandleOwnerRegistered(owners); err != nil {
+		return fmt.Errorf("controller<Owner.ListenOwnerRegistered>: %w", err)
+	}
+	return nil
+}
+
+func NewOwner(service service.Owner) Owner {
+	return Owner{
+		service:         service,
+		ownerRegistered: messaging.OwnerRegisteredMessenger{Version: 1}}
+}
@@ -1,12 +1,22 @@
 package controller
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/go-lib/reqres"
+	"github.com/solsteace/kochira/link/internal/domain/redirect"
 	"github.com/solsteace/kochira/link/internal/service"
+	"github.com/solsteace/kochira/link/internal/view"
 )
 
 type Redirect struct {
@@ -15,16 +25,209 @@ type Redirect struct {
 
 func (rc Redirect) Go(w http.ResponseWriter, r *http.Request) error {
 	reqId := chiMiddleware.GetReqID(r.Context())
-	shortened := chi.URLParam(r, "shortened")
-	destination, err := rc.service.Go(shortened)
-	if err != nil {
+	shortened, wantsPreview := strings.CutSuffix(chi.URLParam(r, "shortened"), "+")
+	if wantsPreview {
+		if err := rc.preview(w, r, shortened); err != nil {
+			return fmt.Errorf("[%s] controller<Redirection.Go>: %w", reqId, err)
+		}
+		return nil
+	}
+
+	target, err := rc.service.Go(shortened, newVisit(r))
+	switch {
+	case errors.As(err, &redirect.UnlockChallenge{}) && acceptsHtml(r):
+		if err := reqres.HttpHtml(w, http.StatusUnauthorized, view.Unlock, view.UnlockData{}); err != nil {
+			return fmt.Errorf("[%s] controller<Redirection.Go>: %w", reqId, err)
+		}
+		return nil
+	case errors.As(err, &redirect.PreviewChallenge{}):
+		if err := rc.preview(w, r, shortened); err != nil {
+			return fmt.Errorf("[%s] controller<Redirection.Go>: %w", reqId, err)
+		}
+		return nil
+	case err != nil:
 		return fmt.Errorf("[%s] controller<Redirection.Go>: %w", reqId, err)
 	}
 
-	http.Redirect(w, r, destination, http.StatusTemporaryRedirect)
+	rememberVariant(w, shortened, target.Variant)
+	w.Header().Set("Cache-Control", target.CacheControl)
+	http.Redirect(w, r, target.Destination, target.StatusCode)
+	return nil
+}
+
+type redirectPreviewView struct {
+	Destination string    `json:"destination"`
+	Owner       string    `json:"owner"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiredAt   time.Time `json:"expired_at"`
+}
+
+// Shows the preview page, or its JSON counterpart for non-browser clients.
+// Continuing from the page posts back to `Unlock`
+func (rc Redirect) preview(w http.ResponseWriter, r *http.Request, shortened string) error {
+	p, err := rc.service.Preview(shortened, newVisit(r))
+	if errors.As(err, &redirect.UnlockChallenge{}) && acceptsHtml(r) {
+		if err := reqres.HttpHtml(w, http.StatusUnauthorized, view.Unlock, view.UnlockData{}); err != nil {
+			return fmt.Errorf("controller<Redirection.preview>: %w", err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("controller<Redirection.preview>: %w", err)
+	}
+
+	rememberVariant(w, shortened, p.Variant)
+	w.Header().Set("Cache-Control", "no-store")
+	if acceptsHtml(r) {
+		data := view.PreviewData{
+			Destination: p.Destination,
+			Owner:       p.Owner,
+			CreatedAt:   p.CreatedAt,
+			ExpiredAt:   p.ExpiredAt}
+		if err := reqres.HttpHtml(w, http.StatusOK, view.Preview, data); err != nil {
+			return fmt.Errorf("controller<Redirection.preview>: %w", err)
+		}
+		return nil
+	}
+
+	resPayload := redirectPreviewView{
+		Destination: p.Destination,
+		Owner:       p.Owner,
+		CreatedAt:   p.CreatedAt,
+		ExpiredAt:   p.ExpiredAt}
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("controller<Redirection.preview>: %w", err)
+	}
 	return nil
 }
 
+// Answers the same way `Go` does, without counting the visit
+func (rc Redirect) Head(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	shortened, wantsPreview := strings.CutSuffix(chi.URLParam(r, "shortened"), "+")
+	target, err := rc.service.Peek(shortened, newVisit(r))
+	switch {
+	case errors.As(err, &redirect.PreviewChallenge{}),
+		err == nil && wantsPreview:
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		return nil
+	case err != nil:
+		return fmt.Errorf("[%s] controller<Redirection.Head>: %w", reqId, err)
+	}
+
+	w.Header().Set("Cache-Control", target.CacheControl)
+	w.Header().Set("Location", target.Destination)
+	w.WriteHeader(target.StatusCode)
+	return nil
+}
+
+// Redirects visitors of protected links, after checking the password they
+// sent through either the unlock form or a JSON payload
+func (rc Redirect) Unlock(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	password, err := unlockPassword(r)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Redirection.Unlock>: %w", reqId, err)
+	}
+
+	// Visitors continuing from the preview page post back to `/{alias}+`
+	shortened := strings.TrimSuffix(chi.URLParam(r, "shortened"), "+")
+	target, err := rc.service.Unlock(shortened, password, newVisit(r))
+	if errors.As(err, &oops.TooManyRequests{}) && acceptsHtml(r) {
+		data := view.UnlockData{Msg: "Too many wrong passwords, please try again later"}
+		if err := reqres.HttpHtml(w, http.StatusTooManyRequests, view.Unlock, data); err != nil {
+			return fmt.Errorf("[%s] controller<Redirection.Unlock>: %w", reqId, err)
+		}
+		return nil
+	} else if errors.As(err, &oops.Unauthorized{}) && acceptsHtml(r) {
+		data := view.UnlockData{Msg: "Wrong password, please try again"}
+		if err := reqres.HttpHtml(w, http.StatusUnauthorized, view.Unlock, data); err != nil {
+			return fmt.Errorf("[%s] controller<Redirection.Unlock>: %w", reqId, err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("[%s] controller<Redirection.Unlock>: %w", reqId, err)
+	}
+
+	// 303 makes the client follow the destination using GET. It's never
+	// cached, as the visitor would have to unlock the link again anyway
+	rememberVariant(w, shortened, target.Variant)
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target.Destination, http.StatusSeeOther)
+	return nil
+}
+
+func unlockPassword(r *http.Request) (string, error) {
+	defer r.Body.Close()
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		reqPayload := new(struct {
+			Password string `json:"password"`
+		})
+		if err := json.NewDecoder(r.Body).Decode(reqPayload); err != nil {
+			err := oops.BadRequest{Err: err}
+			return "", fmt.Errorf("controller<unlockPassword>: %w", err)
+		}
+		return reqPayload.Password, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		err := oops.BadRequest{Err: err}
+		return "", fmt.Errorf("controller<unlockPassword>: %w", err)
+	}
+	return r.PostForm.Get("password"), nil
+}
+
+func acceptsHtml(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// How long visitors keep the variant they were given?
+const vARIANT_COOKIE_MAX_AGE = 30 * 24 * time.Hour
+
+func variantCookieName(shortened string) string {
+	return "kochira_variant_" + shortened
+}
+
+// Lets the visitor keep seeing the same variant on their next visits
+func rememberVariant(w http.ResponseWriter, shortened string, variant uint64) {
+	if variant == 0 {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     variantCookieName(shortened),
+		Value:    strconv.FormatUint(variant, 10),
+		Path:     "/",
+		MaxAge:   int(vARIANT_COOKIE_MAX_AGE.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode})
+}
+
+func newVisit(r *http.Request) redirect.Visit {
+	// `RemoteAddr` may had been replaced by the real IP (without port) upstream
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	// A malformed cookie is treated as if there was none
+	var variant uint64
+	shortened := strings.TrimSuffix(chi.URLParam(r, "shortened"), "+")
+	if cookie, err := r.Cookie(variantCookieName(shortened)); err == nil {
+		variant, _ = strconv.ParseUint(cookie.Value, 10, 64)
+	}
+
+	return redirect.Visit{
+		At:        time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		Ip:        ip,
+		Path:      chi.URLParam(r, "*"),
+		Query:     r.URL.Query(),
+
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		Variant:        variant}
+}
+
 func NewRedirect(service service.Redirect) Redirect {
 	return Redirect{service}
 }
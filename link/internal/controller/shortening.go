@@ -1,24 +1,36 @@
 package controller
 
 import (
+	"crypto/sha256"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/go-lib/oops/adapter"
 	"github.com/solsteace/go-lib/reqres"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
 	shorteningMsg "github.com/solsteace/kochira/link/internal/domain/shortening/messaging"
 	"github.com/solsteace/kochira/link/internal/messaging"
 	"github.com/solsteace/kochira/link/internal/middleware"
 	"github.com/solsteace/kochira/link/internal/service"
+	"github.com/solsteace/kochira/link/internal/utility/qr"
 
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 )
 
 type Shortening struct {
 	service             service.Shortening
+	publicUrl           string // Base URL the shortened links are served from
 	checkSubscription   messaging.CheckSubscriptionMessenger
 	finishShortening    messaging.FinishShorteningMessenger
 	subscriptionExpired messaging.SubscriptionExpiredMessenger
@@ -26,156 +38,1437 @@ type Shortening struct {
 
 // Move later to a viewer object or something
 type shorteningLinkView struct {
-	Id          uint64    `json:"id"`
-	UserId      uint64    `json:"user_id"`
+	Id              uint64                  `json:"id"`
+	UserId          uint64                  `json:"user_id"`
+	Shortened       string                  `json:"shortened"`
+	Alias           string                  `json:"alias"`
+	Destination     string                  `json:"destination"`
+	IsOpen          bool                    `json:"is_open"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+	ExpiredAt       time.Time               `json:"expired_at"`
+	IsProtected     bool                    `json:"is_protected"`
+	MaxClicks       *uint                   `json:"max_clicks"`       // Null means unlimited
+	RemainingClicks *uint                   `json:"remaining_clicks"` // Null means unlimited
+	RedirectType    int                     `json:"redirect_type"`
+	PassQuery       bool                    `json:"pass_query"`
+	PassPath        bool                    `json:"pass_path"`
+	Preview         bool                    `json:"preview"`
+	Tags            []string                `json:"tags"`
+	Folder          string                  `json:"folder"`
+	ActiveFrom      *time.Time              `json:"active_from"`  // Null means active right away
+	ActiveUntil     *time.Time              `json:"active_until"` // Null means active until expired
+	Variants        []shorteningVariantView `json:"variants"`
+	WorkspaceId     *uint64                 `json:"workspace_id"` // Null for personal links
+	Health          shorteningHealthView    `json:"health"`
+	Metadata        shorteningMetadataView  `json:"metadata"`
+	DeletedAt       *time.Time              `json:"deleted_at"` // Null unless the link is in the trash
+}
+
+type shorteningHealthView struct {
+	Status        *int       `json:"status"` // Null when unreachable or yet to be checked
+	LatencyMs     int64      `json:"latency_ms"`
+	FailureStreak uint       `json:"failure_streak"`
+	IsHealthy     bool       `json:"is_healthy"`
+	CheckedAt     *time.Time `json:"checked_at"` // Null when yet to be checked
+}
+
+type shorteningMetadataView struct {
+	Title         string     `json:"title"`
+	OgTitle       string     `json:"og_title"`
+	OgDescription string     `json:"og_description"`
+	OgImage       string     `json:"og_image"`
+	Favicon       string     `json:"favicon"`
+	FetchedAt     *time.Time `json:"fetched_at"` // Null when yet to be fetched
+}
+
+type shorteningVariantView struct {
+	Id          uint64 `json:"id"`
+	Destination string `json:"destination"`
+	Weight      uint   `json:"weight"`
+	Hits        uint64 `json:"hits"`
+}
+
+func newShorteningLinkView(l shortening.Link) shorteningLinkView {
+	health := l.Health()
+	metadata := l.Metadata()
+	variants := []shorteningVariantView{}
+	for _, v := range l.Variants() {
+		variants = append(variants, shorteningVariantView{
+			Id:          v.Id(),
+			Destination: v.Destination(),
+			Weight:      v.Weight(),
+			Hits:        v.Hits()})
+	}
+
+	return shorteningLinkView{
+		Id:              l.Id(),
+		UserId:          l.UserId(),
+		Shortened:       l.Shortened(),
+		Alias:           l.Alias(),
+		Destination:     l.Destination(),
+		IsOpen:          l.IsOpen(),
+		UpdatedAt:       l.UpdatedAt(),
+		ExpiredAt:       l.ExpiredAt(),
+		IsProtected:     l.IsProtected(),
+		MaxClicks:       l.MaxClicks(),
+		RemainingClicks: l.RemainingClicks(),
+		RedirectType:    int(l.RedirectType()),
+		PassQuery:       l.PassesQuery(),
+		PassPath:        l.PassesPath(),
+		Preview:         l.RequiresPreview(),
+		Tags:            l.Tags(),
+		Folder:          l.Folder(),
+		ActiveFrom:      l.ActiveFrom(),
+		ActiveUntil:     l.ActiveUntil(),
+		Variants:        variants,
+		WorkspaceId:     l.WorkspaceId(),
+		Health: shorteningHealthView{
+			Status:        health.Status(),
+			LatencyMs:     health.Latency().Milliseconds(),
+			FailureStreak: health.FailureStreak(),
+			IsHealthy:     health.IsHealthy(),
+			CheckedAt:     health.CheckedAt()},
+		Metadata: shorteningMetadataView{
+			Title:         metadata.Title(),
+			OgTitle:       metadata.OgTitle(),
+			OgDescription: metadata.OgDescription(),
+			OgImage:       metadata.OgImage(),
+			Favicon:       metadata.Favicon(),
+			FetchedAt:     metadata.FetchedAt()},
+		DeletedAt: l.DeletedAt()}
+}
+
+type shorteningPageView struct {
+	Data       []shorteningLinkView `json:"data"`
+	NextCursor *string              `json:"nextCursor"` // Null when there's nothing after
+	PrevCursor *string              `json:"prevCursor"` // Null when there's nothing before
+	Total      *uint                `json:"total,omitempty"`
+}
+
+func newShorteningPageView(p shortening.LinkPage) shorteningPageView {
+	v := shorteningPageView{
+		Data:  []shorteningLinkView{},
+		Total: p.Total()}
+	for _, l := range p.Links() {
+		v.Data = append(v.Data, newShorteningLinkView(l))
+	}
+	if next := p.Next(); next != nil {
+		token := next.String()
+		v.NextCursor = &token
+	}
+	if prev := p.Prev(); prev != nil {
+		token := prev.String()
+		v.PrevCursor = &token
+	}
+	return v
+}
+
+type shorteningPreferenceView struct {
+	RedirectType int `json:"redirect_type"`
+}
+
+func (lr Shortening) GetPreference(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	p, err := lr.service.GetPreference(uint64(userId))
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetPreference>: %w", reqId, err)
+	}
+
+	resPayload := shorteningPreferenceView{RedirectType: int(p.RedirectType())}
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetPreference>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (lr Shortening) UpdatePreference(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	reqPayload := new(struct {
+		RedirectType int `json:"redirectType"`
+	})
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(reqPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.UpdatePreference>: %w", reqId, err)
+	}
+	defer r.Body.Close()
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	if err := lr.service.UpdatePreference(uint64(userId), reqPayload.RedirectType); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.UpdatePreference>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.UpdatePreference>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (lr Shortening) GetSelf(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	q, err := parseListingQuery(r.URL.Query())
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetSelf>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	result, err := lr.service.GetSelf(
+		uint64(userId),
+		q.page,
+		q.limit,
+		q.cursor,
+		q.withTotal,
+		q.filter)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetSelf>: %w", reqId, err)
+	}
+
+	resPayload := newShorteningPageView(result)
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetSelf>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (lr Shortening) GetByWorkspace(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	workspaceId, err := strconv.ParseUint(chi.URLParam(r, "workspaceId"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetByWorkspace>: %w", reqId, err)
+	}
+	q, err := parseListingQuery(r.URL.Query())
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetByWorkspace>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	result, err := lr.service.GetByWorkspace(
+		uint64(userId),
+		workspaceId,
+		q.page,
+		q.limit,
+		q.cursor,
+		q.withTotal,
+		q.filter)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetByWorkspace>: %w", reqId, err)
+	}
+
+	resPayload := newShorteningPageView(result)
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetByWorkspace>: %w", reqId, err)
+	}
+	return nil
+}
+
+// Query parameters shared by the link listings
+type shorteningListingQuery struct {
+	page      *uint
+	limit     *uint
+	cursor    *shortening.LinkCursor
+	withTotal bool
+	filter    shortening.LinkFilter
+}
+
+func parseListingQuery(rq url.Values) (shorteningListingQuery, error) {
+	q := shorteningListingQuery{}
+	qLimit, err := strconv.ParseUint(rq.Get("limit"), 10, 64)
+	if err == nil {
+		temp := uint(qLimit)
+		q.limit = &temp
+	}
+	qPage, err := strconv.ParseUint(rq.Get("page"), 10, 64)
+	if err == nil {
+		temp := uint(qPage)
+		q.page = &temp
+	}
+
+	var tag, folder *string
+	if rq.Has("tag") {
+		temp := rq.Get("tag")
+		tag = &temp
+	}
+	if rq.Has("folder") {
+		temp := rq.Get("folder")
+		folder = &temp
+	}
+	isOpen, err := parseBoolQuery(rq, "isOpen")
+	if err != nil {
+		return shorteningListingQuery{}, err
+	}
+	expired, err := parseBoolQuery(rq, "expired")
+	if err != nil {
+		return shorteningListingQuery{}, err
+	}
+	q.filter, err = shortening.NewLinkFilter(
+		tag,
+		folder,
+		isOpen,
+		expired,
+		rq.Get("q"),
+		rq.Get("sort"),
+		rq.Get("order"))
+	if err != nil {
+		return shorteningListingQuery{}, err
+	}
+
+	if rq.Has("cursor") {
+		temp, err := shortening.ParseLinkCursor(rq.Get("cursor"))
+		if err != nil {
+			return shorteningListingQuery{}, err
+		}
+		q.cursor = &temp
+	}
+	withTotal, err := parseBoolQuery(rq, "total")
+	if err != nil {
+		return shorteningListingQuery{}, err
+	}
+	q.withTotal = withTotal != nil && *withTotal
+	return q, nil
+}
+
+// Parses optional boolean query parameter. Absent parameter results in nil
+func parseBoolQuery(rq url.Values, key string) (*bool, error) {
+	if !rq.Has(key) {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(rq.Get(key))
+	if err != nil {
+		return nil, oops.BadRequest{
+			Err: err,
+			Msg: fmt.Sprintf("`%s` should be either `true` or `false`", key)}
+	}
+	return &value, nil
+}
+
+// Parses optional RFC 3339 time of the payload. Empty value results in zero
+// time, which is meant to clear the field
+func parseTimePayload(key string, value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	} else if *value == "" {
+		return &time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, oops.BadRequest{
+			Err: err,
+			Msg: fmt.Sprintf("`%s` should be an RFC 3339 time", key)}
+	}
+	return &t, nil
+}
+
+func (lr Shortening) GetById(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetById>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	result, err := lr.service.GetById(uint64(userId), id)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetById>: %w", reqId, err)
+	}
+
+	resPayload := newShorteningLinkView(result)
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetById>: %w", reqId, err)
+	}
+	return nil
+}
+
+type shorteningAliasAvailabilityView struct {
+	Alias     string `json:"alias"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func (lr Shortening) GetAliasAvailability(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	alias := chi.URLParam(r, "alias")
+
+	// An unavailable alias is still a successful answer, hence only the
+	// rejections from the policy are turned into the payload
+	resPayload := shorteningAliasAvailabilityView{Alias: alias, Available: true}
+	err := lr.service.CheckAlias(alias, 0)
+	if err != nil {
+		var rejection oops.BadValues
+		if !errors.As(err, &rejection) {
+			return fmt.Errorf("[%s] controller<Shortening.GetAliasAvailability>: %w", reqId, err)
+		}
+		resPayload.Available = false
+		resPayload.Reason = rejection.Msg
+	}
+
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetAliasAvailability>: %w", reqId, err)
+	}
+	return nil
+}
+
+type shorteningClickCountView struct {
+	At     time.Time `json:"at"`
+	Clicks uint64    `json:"clicks"`
+}
+
+type shorteningStatsView struct {
+	Granularity string                     `json:"granularity"`
+	From        time.Time                  `json:"from"`
+	To          time.Time                  `json:"to"`
+	Total       uint64                     `json:"total"`
+	Clicks      []shorteningClickCountView `json:"clicks"`
+}
+
+func (lr Shortening) GetStats(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetStats>: %w", reqId, err)
+	}
+
+	rq := r.URL.Query()
+	from, err := parseTimeQuery(rq, "from")
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetStats>: %w", reqId, err)
+	}
+	to, err := parseTimeQuery(rq, "to")
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetStats>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	result, window, err := lr.service.GetClickCounts(
+		uint64(userId),
+		id,
+		rq.Get("granularity"),
+		from,
+		to)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetStats>: %w", reqId, err)
+	}
+
+	resPayload := shorteningStatsView{
+		Granularity: string(window.Granularity()),
+		From:        window.From(),
+		To:          window.To(),
+		Clicks:      []shorteningClickCountView{}}
+	for _, c := range result {
+		resPayload.Total += c.Clicks()
+		resPayload.Clicks = append(resPayload.Clicks, shorteningClickCountView{
+			At:     c.At(),
+			Clicks: c.Clicks()})
+	}
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetStats>: %w", reqId, err)
+	}
+	return nil
+}
+
+// Parses optional RFC3339 query param. Returns nil when the param is absent
+func parseTimeQuery(rq url.Values, key string) (*time.Time, error) {
+	if rq.Get(key) == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, rq.Get(key))
+	if err != nil {
+		err := oops.BadRequest{
+			Err: err,
+			Msg: fmt.Sprintf("`%s` should be a RFC3339 timestamp", key)}
+		return nil, fmt.Errorf("controller<parseTimeQuery>: %w", err)
+	}
+	return &t, nil
+}
+
+func parseUintQuery(rq url.Values, key string) (*uint, error) {
+	if rq.Get(key) == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseUint(rq.Get(key), 10, 32)
+	if err != nil {
+		err := oops.BadRequest{
+			Err: err,
+			Msg: fmt.Sprintf("`%s` should be a non-negative integer", key)}
+		return nil, fmt.Errorf("controller<parseUintQuery>: %w", err)
+	}
+	temp := uint(n)
+	return &temp, nil
+}
+
+// Renders QR code of the link's public URL. The response could be cached by
+// the client and revalidated using the ETag
+func (lr Shortening) GetQr(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetQr>: %w", reqId, err)
+	}
+
+	rq := r.URL.Query()
+	size, err := parseUintQuery(rq, "size")
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetQr>: %w", reqId, err)
+	}
+	margin, err := parseUintQuery(rq, "margin")
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetQr>: %w", reqId, err)
+	}
+	opts, err := qr.NewOptions(rq.Get("format"), size, margin, rq.Get("ecc"))
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetQr>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	link, err := lr.service.GetById(uint64(userId), id)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetQr>: %w", reqId, err)
+	}
+
+	// The alias is part of the content, as changing it doesn't always bump `updatedAt`
+	content := fmt.Sprintf("%s/%s", lr.publicUrl, link.Alias())
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(fmt.Sprintf(
+		"%s|%d|%s|%d|%d|%s",
+		content,
+		link.UpdatedAt().UnixNano(),
+		opts.Format(),
+		opts.Size(),
+		opts.Margin(),
+		opts.Level()))))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if strings.Contains(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	body, err := qr.Render(content, opts)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetQr>: %w", reqId, err)
+	}
+	w.Header().Set("Content-Type", opts.ContentType())
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	return nil
+}
+
+func (lr Shortening) Create(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	reqPayload := new(struct {
+		Destination  string     `json:"destination"`
+		MaxClicks    *uint      `json:"maxClicks"`
+		RedirectType *int       `json:"redirectType"` // Omit to follow the preference
+		ActiveFrom   *time.Time `json:"activeFrom"`   // Omit to be active right away
+		ActiveUntil  *time.Time `json:"activeUntil"`  // Omit to be active until expired
+		WorkspaceId  *uint64    `json:"workspaceId"`  // Omit for a personal link
+	})
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(reqPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+	}
+	defer r.Body.Close()
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	err := lr.service.Create(
+		uint64(userId),
+		reqPayload.WorkspaceId,
+		reqPayload.Destination,
+		reqPayload.MaxClicks,
+		reqPayload.RedirectType,
+		reqPayload.ActiveFrom,
+		reqPayload.ActiveUntil)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusCreated, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+	}
+	return nil
+}
+
+type shorteningBulkResultView struct {
+	Row       int    `json:"row"` // Starts from 1, excluding CSV header
+	Ok        bool   `json:"ok"`
+	Shortened string `json:"shortened,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Creates many links from either a JSON array or a CSV file. The CSV could be
+// sent as the body or as the `file` field of a multipart form, and should have
+// a header naming its columns: `destination`, `max_clicks`, `redirect_type`.
+// The links are placed in the workspace given by `workspaceId` query, if any
+func (lr Shortening) CreateMany(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	r.Body = http.MaxBytesReader(w, r.Body, bULK_MAX_BODY_BYTES)
+	defer r.Body.Close()
+
+	var workspaceId *uint64
+	if rq := r.URL.Query(); rq.Has("workspaceId") {
+		temp, err := strconv.ParseUint(rq.Get("workspaceId"), 10, 64)
+		if err != nil {
+			err := oops.BadRequest{Err: err, Msg: "`workspaceId` should be a number"}
+			return fmt.Errorf("[%s] controller<Shortening.CreateMany>: %w", reqId, err)
+		}
+		workspaceId = &temp
+	}
+
+	var rows []service.BulkRow
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		rows, err = parseBulkCsv(r.Body)
+	case "multipart/form-data":
+		file, _, err2 := r.FormFile("file")
+		if err2 != nil {
+			err = oops.BadRequest{Err: err2, Msg: "CSV file should be sent as `file` field"}
+			break
+		}
+		defer file.Close()
+		rows, err = parseBulkCsv(file)
+	default:
+		reqPayload, err2 := decodeJsonRows[struct {
+			Destination  string `json:"destination"`
+			MaxClicks    *uint  `json:"maxClicks"`
+			RedirectType *int   `json:"redirectType"`
+		}](r.Body)
+		if err2 != nil {
+			err = err2
+			break
+		}
+		for _, p := range reqPayload {
+			rows = append(rows, service.BulkRow{
+				Destination:  p.Destination,
+				MaxClicks:    p.MaxClicks,
+				RedirectType: p.RedirectType})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.CreateMany>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	results, err := lr.service.CreateMany(uint64(userId), workspaceId, rows)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.CreateMany>: %w", reqId, err)
+	}
+
+	resPayload := []shorteningBulkResultView{}
+	for idx, result := range results {
+		resultView := shorteningBulkResultView{Row: idx + 1, Ok: result.Err == nil}
+		if result.Err != nil {
+			resultView.Error = adapter.HttpErrorMsg(result.Err)
+		} else {
+			resultView.Shortened = result.Link.Shortened()
+		}
+		resPayload = append(resPayload, resultView)
+	}
+
+	// The links are created, but only activated once the batch is approved
+	if err := reqres.HttpOk(w, http.StatusAccepted, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.CreateMany>: %w", reqId, err)
+	}
+	return nil
+}
+
+func parseBulkCsv(body io.Reader) ([]service.BulkRow, error) {
+	records, field, err := readCsvTable(body, "destination")
+	if err != nil {
+		return []service.BulkRow{}, fmt.Errorf("controller<parseBulkCsv>: %w", err)
+	}
+
+	rows := []service.BulkRow{}
+	for line, record := range records {
+		row := service.BulkRow{Destination: field(record, "destination")}
+		if raw := field(record, "max_clicks"); raw != "" {
+			maxClicks, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				err := oops.BadRequest{
+					Err: err,
+					Msg: fmt.Sprintf("`max_clicks` on row %d should be a number", line+1)}
+				return []service.BulkRow{}, fmt.Errorf("controller<parseBulkCsv>: %w", err)
+			}
+			actualMaxClicks := uint(maxClicks)
+			row.MaxClicks = &actualMaxClicks
+		}
+		if raw := field(record, "redirect_type"); raw != "" {
+			redirectType, err := strconv.Atoi(raw)
+			if err != nil {
+				err := oops.BadRequest{
+					Err: err,
+					Msg: fmt.Sprintf("`redirect_type` on row %d should be a number", line+1)}
+				return []service.BulkRow{}, fmt.Errorf("controller<parseBulkCsv>: %w", err)
+			}
+			row.RedirectType = &redirectType
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// How large could the body of a bulk request be?
+const bULK_MAX_BODY_BYTES = 4 << 20
+
+var errTooManyRows = oops.BadValues{
+	Err: errors.New("too many rows"),
+	Msg: fmt.Sprintf("Only up to %d links could be sent at once", service.BulkMaxRows)}
+
+// Reads a JSON array of rows, giving up once it holds more rows than a bulk
+// request could take
+func decodeJsonRows[T any](body io.Reader) ([]T, error) {
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		err := oops.BadRequest{Err: err, Msg: "JSON should be an array"}
+		return []T{}, fmt.Errorf("controller<decodeJsonRows>: %w", err)
+	}
+
+	rows := []T{}
+	for decoder.More() {
+		if len(rows) >= service.BulkMaxRows {
+			return []T{}, fmt.Errorf("controller<decodeJsonRows>: %w", errTooManyRows)
+		}
+		var row T
+		if err := decoder.Decode(&row); err != nil {
+			err := oops.BadRequest{Err: err, Msg: "JSON couldn't be parsed"}
+			return []T{}, fmt.Errorf("controller<decodeJsonRows>: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Reads a CSV file having a header naming its columns, which should include
+// `required`. Returns the records after the header, along with a lookup of the
+// trimmed field by column name, giving empty string for missing columns.
+// Reading stops once there are more records than a bulk request could take
+func readCsvTable(
+	body io.Reader,
+	required string,
+) ([][]string, func(record []string, name string) string, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records := [][]string{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			err := oops.BadRequest{Err: err, Msg: "CSV couldn't be parsed"}
+			return [][]string{}, nil, fmt.Errorf("controller<readCsvTable>: %w", err)
+		} else if len(records) > service.BulkMaxRows { // The header isn't a row
+			return [][]string{}, nil, fmt.Errorf("controller<readCsvTable>: %w", errTooManyRows)
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		err := oops.BadRequest{
+			Err: errors.New("missing CSV header"),
+			Msg: "CSV should have a header"}
+		return [][]string{}, nil, fmt.Errorf("controller<readCsvTable>: %w", err)
+	}
+
+	columns := map[string]int{}
+	for idx, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+	if _, ok := columns[required]; !ok {
+		err := oops.BadRequest{
+			Err: fmt.Errorf("missing %s column", required),
+			Msg: fmt.Sprintf("CSV should have `%s` column", required)}
+		return [][]string{}, nil, fmt.Errorf("controller<readCsvTable>: %w", err)
+	}
+	field := func(record []string, name string) string {
+		idx, ok := columns[name]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return unescapeCsvCell(strings.TrimSpace(record[idx]))
+	}
+	return records[1:], field, nil
+}
+
+// Spreadsheets evaluate cells starting with these as formulas
+const cSV_FORMULA_PREFIXES = "=+-@\t\r"
+
+// Keeps spreadsheets from evaluating the cell as a formula, by prefixing it
+// with `'`. Cells already starting with `'` are prefixed too, so they're read
+// back as they were. See `unescapeCsvCell`
+func escapeCsvCell(cell string) string {
+	if cell != "" && strings.ContainsRune(cSV_FORMULA_PREFIXES+"'", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// Reverses `escapeCsvCell`, so exported files are read back as they were
+func unescapeCsvCell(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(cSV_FORMULA_PREFIXES+"'", rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+// Exported form of a link, which `Import` reads back. CSV exports use the
+// JSON names as their header
+type shorteningExportView struct {
 	Shortened   string    `json:"shortened"`
 	Alias       string    `json:"alias"`
 	Destination string    `json:"destination"`
 	IsOpen      bool      `json:"is_open"`
-	UpdatedAt   time.Time `json:"updated_at"`
 	ExpiredAt   time.Time `json:"expired_at"`
 }
 
-func (lr Shortening) GetSelf(w http.ResponseWriter, r *http.Request) error {
+func newShorteningExportView(l shortening.Link) shorteningExportView {
+	return shorteningExportView{
+		Shortened:   l.Shortened(),
+		Alias:       l.Alias(),
+		Destination: l.Destination(),
+		IsOpen:      l.IsOpen(),
+		ExpiredAt:   l.ExpiredAt()}
+}
+
+// Texts are escaped, as links are free to start with what spreadsheets take
+// as formulas
+func (v shorteningExportView) csvRecord() []string {
+	return []string{
+		escapeCsvCell(v.Shortened),
+		escapeCsvCell(v.Alias),
+		escapeCsvCell(v.Destination),
+		strconv.FormatBool(v.IsOpen),
+		v.ExpiredAt.Format(time.RFC3339)}
+}
+
+var shorteningExportCsvHeader = []string{"shortened", "alias", "destination", "is_open", "expired_at"}
+
+// Streams every link the user owns outside workspaces as a file, in the format given by `format`
+// query: `json` (default) or `csv`
+func (lr Shortening) Export(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "json"
+	case "json", "csv":
+	default:
+		err := oops.BadRequest{
+			Err: fmt.Errorf("unknown format `%s`", format),
+			Msg: "`format` should be either `json` or `csv`"}
+		return fmt.Errorf("[%s] controller<Shortening.Export>: %w", reqId, err)
+	}
+
+	// Links are written as they're read, hence nothing is written until the
+	// first one arrives. Failures past that could only cut the file short
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	started := false
+	start := func() {
+		started = true
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	var err error
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		err = lr.service.Export(uint64(userId), func(l shortening.Link) error {
+			if !started {
+				start()
+				if err := writer.Write(shorteningExportCsvHeader); err != nil {
+					return err
+				}
+			}
+			return writer.Write(newShorteningExportView(l).csvRecord())
+		})
+		if err == nil && !started {
+			start()
+			err = writer.Write(shorteningExportCsvHeader)
+		}
+		writer.Flush()
+		if err == nil {
+			err = writer.Error()
+		}
+	default:
+		encoder := json.NewEncoder(w)
+		err = lr.service.Export(uint64(userId), func(l shortening.Link) error {
+			separator := ","
+			if !started {
+				start()
+				separator = "["
+			}
+			if _, err := io.WriteString(w, separator); err != nil {
+				return err
+			}
+			return encoder.Encode(newShorteningExportView(l))
+		})
+		if err == nil {
+			closing := "]"
+			if !started {
+				start()
+				closing = "[]"
+			}
+			_, err = io.WriteString(w, closing)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Export>: %w", reqId, err)
+	}
+	return nil
+}
+
+// Re-creates links from a file made by `Export`, sent either as the body or as
+// the `file` field of a multipart form. Only `alias` and `destination` are
+// read, while the rest is decided by the approval like any new link. Aliases
+// equal to `shortened` were generated, hence a fresh one is generated instead
+func (lr Shortening) Import(w http.ResponseWriter, r *http.Request) error {
 	reqId := chiMiddleware.GetReqID(r.Context())
-	var limit *uint
-	var page *uint
-	rq := r.URL.Query()
-	qLimit, err := strconv.ParseUint(rq.Get("limit"), 10, 64)
-	if err == nil {
-		temp := uint(qLimit)
-		limit = &temp
+	r.Body = http.MaxBytesReader(w, r.Body, bULK_MAX_BODY_BYTES)
+	defer r.Body.Close()
+
+	var rows []service.ImportRow
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		rows, err = parseImportCsv(r.Body)
+	case "multipart/form-data":
+		file, header, err2 := r.FormFile("file")
+		if err2 != nil {
+			err = oops.BadRequest{Err: err2, Msg: "Exported file should be sent as `file` field"}
+			break
+		}
+		defer file.Close()
+		if strings.HasSuffix(strings.ToLower(header.Filename), ".csv") {
+			rows, err = parseImportCsv(file)
+		} else {
+			rows, err = parseImportJson(file)
+		}
+	default:
+		rows, err = parseImportJson(r.Body)
 	}
-	qPage, err := strconv.ParseUint(rq.Get("page"), 10, 64)
-	if err == nil {
-		temp := uint(qPage)
-		page = &temp
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Import>: %w", reqId, err)
 	}
 
 	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
-	result, err := lr.service.GetSelf(uint64(userId), page, limit)
+	results, err := lr.service.Import(uint64(userId), rows)
 	if err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.GetSelf>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.Import>: %w", reqId, err)
+	}
+
+	// Rows created without their alias still report why
+	resPayload := []shorteningBulkResultView{}
+	for idx, result := range results {
+		resultView := shorteningBulkResultView{Row: idx + 1, Ok: result.Err == nil}
+		if result.Err != nil {
+			resultView.Error = adapter.HttpErrorMsg(result.Err)
+		}
+		if result.Link != nil {
+			resultView.Shortened = result.Link.Shortened()
+		}
+		resPayload = append(resPayload, resultView)
+	}
+
+	// The links are created, but only activated once the batch is approved
+	if err := reqres.HttpOk(w, http.StatusAccepted, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Import>: %w", reqId, err)
+	}
+	return nil
+}
+
+func parseImportJson(body io.Reader) ([]service.ImportRow, error) {
+	payload, err := decodeJsonRows[shorteningExportView](body)
+	if err != nil {
+		return []service.ImportRow{}, fmt.Errorf("controller<parseImportJson>: %w", err)
+	}
+
+	rows := []service.ImportRow{}
+	for _, p := range payload {
+		rows = append(rows, newImportRow(p.Shortened, p.Alias, p.Destination))
+	}
+	return rows, nil
+}
+
+func parseImportCsv(body io.Reader) ([]service.ImportRow, error) {
+	records, field, err := readCsvTable(body, "destination")
+	if err != nil {
+		return []service.ImportRow{}, fmt.Errorf("controller<parseImportCsv>: %w", err)
+	}
+
+	rows := []service.ImportRow{}
+	for _, record := range records {
+		rows = append(rows, newImportRow(
+			field(record, "shortened"),
+			field(record, "alias"),
+			field(record, "destination")))
+	}
+	return rows, nil
+}
+
+func newImportRow(shortened, alias, destination string) service.ImportRow {
+	if alias == shortened {
+		alias = ""
+	}
+	return service.ImportRow{Alias: alias, Destination: destination}
+}
+
+func (lr Shortening) UpdateById(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	reqPayload := new(struct {
+		Alias        string    `json:"alias"`
+		Destination  string    `json:"destination"`
+		IsOpen       bool      `json:"isOpen"`
+		Password     *string   `json:"password"`     // Omit to keep, empty to remove
+		MaxClicks    *uint     `json:"maxClicks"`    // Omit to keep, zero to remove
+		RedirectType *int      `json:"redirectType"` // Omit to keep
+		PassQuery    *bool     `json:"passQuery"`    // Omit to keep
+		PassPath     *bool     `json:"passPath"`     // Omit to keep
+		Preview      *bool     `json:"preview"`      // Omit to keep
+		Tags         *[]string `json:"tags"`         // Omit to keep, empty to remove
+		Folder       *string   `json:"folder"`       // Omit to keep, empty to remove
+		ActiveFrom   *string   `json:"activeFrom"`   // Omit to keep, empty to remove
+		ActiveUntil  *string   `json:"activeUntil"`  // Omit to keep, empty to remove
+		Variants     *[]struct {
+			Destination string `json:"destination"`
+			Weight      uint   `json:"weight"`
+		} `json:"variants"` // Omit to keep, empty to remove
+	})
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(reqPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.UpdateById>: %w", reqId, err)
+	}
+	defer r.Body.Close()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.UpdateById>: %w", reqId, err)
+	}
+	activeFrom, err := parseTimePayload("activeFrom", reqPayload.ActiveFrom)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.UpdateById>: %w", reqId, err)
+	}
+	activeUntil, err := parseTimePayload("activeUntil", reqPayload.ActiveUntil)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.UpdateById>: %w", reqId, err)
+	}
+
+	var variants *[]service.ShorteningVariant
+	if reqPayload.Variants != nil {
+		temp := []service.ShorteningVariant{}
+		for _, v := range *reqPayload.Variants {
+			temp = append(temp, service.ShorteningVariant{
+				Destination: v.Destination,
+				Weight:      v.Weight})
+		}
+		variants = &temp
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	err = lr.service.UpdateById(
+		uint64(userId),
+		id,
+		reqPayload.Alias,
+		reqPayload.Destination,
+		reqPayload.IsOpen,
+		service.ShorteningSettings{
+			Password:     reqPayload.Password,
+			MaxClicks:    reqPayload.MaxClicks,
+			RedirectType: reqPayload.RedirectType,
+			PassQuery:    reqPayload.PassQuery,
+			PassPath:     reqPayload.PassPath,
+			Preview:      reqPayload.Preview,
+			Tags:         reqPayload.Tags,
+			Folder:       reqPayload.Folder,
+			ActiveFrom:   activeFrom,
+			ActiveUntil:  activeUntil,
+			Variants:     variants})
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.UpdateById>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (lr Shortening) DeleteById(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.DeleteById>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	if err := lr.service.DeleteById(uint64(userId), id); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.DeleteById>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (lr Shortening) GetTrash(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	links, err := lr.service.GetTrash(uint64(userId))
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetTrash>: %w", reqId, err)
 	}
 
 	resPayload := []shorteningLinkView{}
-	for _, r := range result {
-		resPayload = append(resPayload, shorteningLinkView{
-			Id:          r.Id(),
-			UserId:      r.UserId(),
-			Shortened:   r.Shortened(),
-			Alias:       r.Alias(),
-			Destination: r.Destination(),
-			IsOpen:      r.IsOpen(),
-			UpdatedAt:   r.UpdatedAt(),
-			ExpiredAt:   r.ExpiredAt()})
+	for _, l := range links {
+		resPayload = append(resPayload, newShorteningLinkView(l))
 	}
 	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.GetSelf>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.GetTrash>: %w", reqId, err)
 	}
 	return nil
 }
 
-func (lr Shortening) GetById(w http.ResponseWriter, r *http.Request) error {
+func (lr Shortening) Restore(w http.ResponseWriter, r *http.Request) error {
 	reqId := chiMiddleware.GetReqID(r.Context())
 	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.GetById>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.Restore>: %w", reqId, err)
 	}
 
 	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
-	result, err := lr.service.GetById(uint64(userId), id)
+	if err := lr.service.Restore(uint64(userId), id); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Restore>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.Restore>: %w", reqId, err)
+	}
+	return nil
+}
+
+type shorteningSnapshotView struct {
+	Alias       string `json:"alias"`
+	Destination string `json:"destination"`
+	IsOpen      bool   `json:"is_open"`
+}
+
+func newShorteningSnapshotView(s shortening.Snapshot) shorteningSnapshotView {
+	return shorteningSnapshotView{
+		Alias:       s.Alias(),
+		Destination: s.Destination(),
+		IsOpen:      s.IsOpen()}
+}
+
+type shorteningRevisionView struct {
+	Number    uint                    `json:"number"`
+	EditorId  *uint64                 `json:"editor_id"` // Null when made by the system
+	Before    *shorteningSnapshotView `json:"before"`    // Null on the revision creating the link
+	After     shorteningSnapshotView  `json:"after"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+func newShorteningRevisionView(r shortening.Revision) shorteningRevisionView {
+	var before *shorteningSnapshotView
+	if snapshot, ok := r.Before(); ok {
+		temp := newShorteningSnapshotView(snapshot)
+		before = &temp
+	}
+	return shorteningRevisionView{
+		Number:    r.Number(),
+		EditorId:  r.EditorId(),
+		Before:    before,
+		After:     newShorteningSnapshotView(r.After()),
+		CreatedAt: r.CreatedAt()}
+}
+
+func (lr Shortening) GetRevisions(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.GetById>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.GetRevisions>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	revisions, err := lr.service.GetRevisions(uint64(userId), id)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetRevisions>: %w", reqId, err)
 	}
 
-	resPayload := shorteningLinkView{
-		Id:          result.Id(),
-		UserId:      result.UserId(),
-		Shortened:   result.Shortened(),
-		Alias:       result.Alias(),
-		Destination: result.Destination(),
-		IsOpen:      result.IsOpen(),
-		UpdatedAt:   result.UpdatedAt(),
-		ExpiredAt:   result.ExpiredAt()}
+	resPayload := []shorteningRevisionView{}
+	for _, rev := range revisions {
+		resPayload = append(resPayload, newShorteningRevisionView(rev))
+	}
 	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.GetById>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.GetRevisions>: %w", reqId, err)
 	}
 	return nil
 }
 
-func (lr Shortening) Create(w http.ResponseWriter, r *http.Request) error {
+func (lr Shortening) RestoreRevision(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.RestoreRevision>: %w", reqId, err)
+	}
+	rev, err := strconv.ParseUint(chi.URLParam(r, "rev"), 10, 32)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.RestoreRevision>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	if err := lr.service.RestoreRevision(uint64(userId), id, uint(rev)); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.RestoreRevision>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.RestoreRevision>: %w", reqId, err)
+	}
+	return nil
+}
+
+type shorteningTransferView struct {
+	Id         uint64    `json:"id"`
+	LinkId     uint64    `json:"link_id"`
+	FromUserId uint64    `json:"from_user_id"`
+	ToUserId   uint64    `json:"to_user_id"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func newShorteningTransferView(t shortening.Transfer) shorteningTransferView {
+	return shorteningTransferView{
+		Id:         t.Id(),
+		LinkId:     t.LinkId(),
+		FromUserId: t.FromUserId(),
+		ToUserId:   t.ToUserId(),
+		Status:     string(t.Status()),
+		CreatedAt:  t.CreatedAt(),
+		UpdatedAt:  t.UpdatedAt()}
+}
+
+func (lr Shortening) GetTransfers(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	transfers, err := lr.service.GetTransfers(uint64(userId))
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetTransfers>: %w", reqId, err)
+	}
+
+	resPayload := []shorteningTransferView{}
+	for _, t := range transfers {
+		resPayload = append(resPayload, newShorteningTransferView(t))
+	}
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetTransfers>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (lr Shortening) CreateTransfer(w http.ResponseWriter, r *http.Request) error {
 	reqId := chiMiddleware.GetReqID(r.Context())
 	reqPayload := new(struct {
-		Destination string `json:"destination"`
+		Username string `json:"username"`
 	})
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(reqPayload); err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.CreateTransfer>: %w", reqId, err)
 	}
 	defer r.Body.Close()
 
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.CreateTransfer>: %w", reqId, err)
+	}
+
 	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
-	err := lr.service.Create(uint64(userId), reqPayload.Destination)
+	if err := lr.service.CreateTransfer(uint64(userId), id, reqPayload.Username); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.CreateTransfer>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusCreated, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.CreateTransfer>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (lr Shortening) AcceptTransfer(w http.ResponseWriter, r *http.Request) error {
+	return lr.actOnTransfer(w, r, "AcceptTransfer", lr.service.AcceptTransfer)
+}
+
+func (lr Shortening) DeclineTransfer(w http.ResponseWriter, r *http.Request) error {
+	return lr.actOnTransfer(w, r, "DeclineTransfer", lr.service.DeclineTransfer)
+}
+
+func (lr Shortening) CancelTransfer(w http.ResponseWriter, r *http.Request) error {
+	return lr.actOnTransfer(w, r, "CancelTransfer", lr.service.CancelTransfer)
+}
+
+func (lr Shortening) actOnTransfer(
+	w http.ResponseWriter,
+	r *http.Request,
+	name string,
+	act func(userId, transferId uint64) error,
+) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	transferId, err := strconv.ParseUint(chi.URLParam(r, "transferId"), 10, 64)
 	if err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.%s>: %w", reqId, name, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	if err := act(uint64(userId), transferId); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.%s>: %w", reqId, name, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.%s>: %w", reqId, name, err)
+	}
+	return nil
+}
+
+type shorteningRuleView struct {
+	Id          uint64   `json:"id"`
+	Position    uint     `json:"position"`
+	Condition   string   `json:"condition"`
+	Values      []string `json:"values"`
+	Destination string   `json:"destination"`
+}
+
+func newShorteningRuleView(r shortening.Rule) shorteningRuleView {
+	return shorteningRuleView{
+		Id:          r.Id(),
+		Position:    r.Position(),
+		Condition:   string(r.Condition()),
+		Values:      r.Values(),
+		Destination: r.Destination()}
+}
+
+type shorteningRulePayload struct {
+	Position    *uint    `json:"position"` // Omit to put last when created, or to keep when updated
+	Condition   string   `json:"condition"`
+	Values      []string `json:"values"`
+	Destination string   `json:"destination"`
+}
+
+func (lr Shortening) GetRules(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetRules>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	rules, err := lr.service.GetRules(uint64(userId), id)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetRules>: %w", reqId, err)
+	}
+
+	resPayload := []shorteningRuleView{}
+	for _, rule := range rules {
+		resPayload = append(resPayload, newShorteningRuleView(rule))
+	}
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.GetRules>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (lr Shortening) CreateRule(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	reqPayload := new(shorteningRulePayload)
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(reqPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.CreateRule>: %w", reqId, err)
+	}
+	defer r.Body.Close()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.CreateRule>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	err = lr.service.CreateRule(
+		uint64(userId),
+		id,
+		reqPayload.Position,
+		reqPayload.Condition,
+		reqPayload.Values,
+		reqPayload.Destination)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.CreateRule>: %w", reqId, err)
 	}
 
 	if err := reqres.HttpOk(w, http.StatusCreated, nil); err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.CreateRule>: %w", reqId, err)
 	}
 	return nil
 }
 
-func (lr Shortening) UpdateById(w http.ResponseWriter, r *http.Request) error {
+func (lr Shortening) UpdateRuleById(w http.ResponseWriter, r *http.Request) error {
 	reqId := chiMiddleware.GetReqID(r.Context())
-	reqPayload := new(struct {
-		Alias       string `json:"alias"`
-		Destination string `json:"destination"`
-		IsOpen      bool   `json:"isOpen"`
-	})
+	reqPayload := new(shorteningRulePayload)
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(reqPayload); err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.UpdateById>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.UpdateRuleById>: %w", reqId, err)
 	}
 	defer r.Body.Close()
 
 	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.UpdateById>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.UpdateRuleById>: %w", reqId, err)
+	}
+	ruleId, err := strconv.ParseUint(chi.URLParam(r, "ruleId"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.UpdateRuleById>: %w", reqId, err)
 	}
 
 	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
-	err = lr.service.UpdateById(
+	err = lr.service.UpdateRuleById(
 		uint64(userId),
 		id,
-		reqPayload.Alias,
-		reqPayload.Destination,
-		reqPayload.IsOpen)
+		ruleId,
+		reqPayload.Position,
+		reqPayload.Condition,
+		reqPayload.Values,
+		reqPayload.Destination)
 	if err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.UpdateById>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.UpdateRuleById>: %w", reqId, err)
 	}
 
 	if err := reqres.HttpOk(w, http.StatusOK, nil); err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.UpdateRuleById>: %w", reqId, err)
 	}
 	return nil
 }
 
-func (lr Shortening) DeleteById(w http.ResponseWriter, r *http.Request) error {
+func (lr Shortening) DeleteRuleById(w http.ResponseWriter, r *http.Request) error {
 	reqId := chiMiddleware.GetReqID(r.Context())
 	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.DeleteById>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.DeleteRuleById>: %w", reqId, err)
+	}
+	ruleId, err := strconv.ParseUint(chi.URLParam(r, "ruleId"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.DeleteRuleById>: %w", reqId, err)
 	}
 
 	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
-	if err := lr.service.DeleteById(uint64(userId), id); err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.DeleteById>: %w", reqId, err)
+	if err := lr.service.DeleteRuleById(uint64(userId), id, ruleId); err != nil {
+		return fmt.Errorf("[%s] controller<Shortening.DeleteRuleById>: %w", reqId, err)
 	}
 
 	if err := reqres.HttpOk(w, http.StatusNoContent, nil); err != nil {
-		return fmt.Errorf("[%s] controller<Shortening.Create>: %w", reqId, err)
+		return fmt.Errorf("[%s] controller<Shortening.DeleteRuleById>: %w", reqId, err)
 	}
 	return nil
 }
@@ -203,13 +1496,36 @@ func (sc Shortening) ListenFinishShortening(msg []byte) error {
 			payload.Data.ContextId,
 			payload.Data.Perk.Lifetime,
 			payload.Data.Perk.Limit)
-		if err2 := sc.service.CompensateLinkShortened(payload.Data.ContextId); err != nil {
-			err = fmt.Errorf("%w [triggered by: %w]", err2, err)
+		if err != nil {
+			if err2 := sc.service.CompensateLinkShortened(payload.Data.ContextId); err2 != nil {
+				err = fmt.Errorf("%w [triggered by: %w]", err2, err)
+			}
+		}
+	case shorteningMsg.BulkShortenedName:
+		err = sc.service.HandleBulkShortened(
+			payload.Data.ContextId,
+			payload.Data.Perk.Lifetime,
+			payload.Data.Perk.Limit)
+		if err != nil {
+			if err2 := sc.service.CompensateBulkShortened(payload.Data.ContextId); err2 != nil {
+				err = fmt.Errorf("%w [triggered by: %w]", err2, err)
+			}
 		}
 	case shorteningMsg.ShortConfiguredName:
 		err = sc.service.HandleShortConfigured(
 			payload.Data.ContextId,
+			payload.Data.Perk.Limit,
+			payload.Data.Perk.AllowShortEdit)
+	case shorteningMsg.TransferAcceptedName:
+		err = sc.service.HandleTransferAccepted(
+			payload.Data.ContextId,
+			payload.Data.Perk.Limit,
 			payload.Data.Perk.AllowShortEdit)
+		if err != nil {
+			if err2 := sc.service.CompensateTransferAccepted(payload.Data.ContextId); err2 != nil {
+				err = fmt.Errorf("%w [triggered by: %w]", err2, err)
+			}
+		}
 	}
 	if err != nil {
 		return fmt.Errorf("controller<Shortening.ListenFinishShortening>: %w", err)
@@ -241,10 +1557,11 @@ func (sc Shortening) ListenSubscriptionExpired(msg []byte) error {
 }
 
 // Creates new `Shortening` and initiates essentials for messaging purposes
-func NewShortening(service service.Shortening) Shortening {
+func NewShortening(service service.Shortening, publicUrl string) Shortening {
 	return Shortening{
 		service:             service,
-		checkSubscription:   messaging.CheckSubscriptionMessenger{Version: 1},
+		publicUrl:           strings.TrimSuffix(publicUrl, "/"),
+		checkSubscription:   messaging.CheckSubscriptionMessenger{Version: 2},
 		finishShortening:    messaging.FinishShorteningMessenger{Version: 1},
 		subscriptionExpired: messaging.SubscriptionExpiredMessenger{Version: 1}}
 }
@@ -0,0 +1,60 @@
+package controller
+
+import "testing"
+
+func TestEscapeCsvCell(t *testing.T) {
+	cases := []struct {
+		name     string
+		cell     string
+		expected string
+	}{
+		{"empty", "", ""},
+		{"plain", "https://example.com", "https://example.com"},
+		{"formula", "=HYPERLINK(\"x\")", "'=HYPERLINK(\"x\")"},
+		{"plus", "+1", "'+1"},
+		{"minus", "-1", "'-1"},
+		{"at", "@SUM(A1)", "'@SUM(A1)"},
+		{"tab", "\t=1", "'\t=1"},
+		{"carriage return", "\r=1", "'\r=1"},
+		{"quoted", "'abc", "''abc"},
+		{"formula inside", "a=1", "a=1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := escapeCsvCell(c.cell); got != c.expected {
+				t.Fatalf("expected `%s`, got `%s`", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnescapeCsvCellRoundTrips(t *testing.T) {
+	cells := []string{
+		"", "'", "''", "https://example.com", "=1", "+1", "-1", "@a", "\t", "\r",
+		"'=1", "''=1", "'abc", "a'=1",
+	}
+	for _, cell := range cells {
+		if got := unescapeCsvCell(escapeCsvCell(cell)); got != cell {
+			t.Fatalf("expected %q back, got %q", cell, got)
+		}
+	}
+}
+
+func TestUnescapeCsvCellKeepsHandWrittenCells(t *testing.T) {
+	// Files not made by the export are read as they are, unless they look
+	// escaped
+	cases := []struct {
+		cell     string
+		expected string
+	}{
+		{"'", "'"},
+		{"'abc", "'abc"},
+		{"'=1", "=1"},
+		{"https://example.com", "https://example.com"},
+	}
+	for _, c := range cases {
+		if got := unescapeCsvCell(c.cell); got != c.expected {
+			t.Fatalf("expected %q, got %q", c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/solsteace/go-lib/reqres"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+	"github.com/solsteace/kochira/link/internal/middleware"
+	"github.com/solsteace/kochira/link/internal/service"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+type Workspace struct {
+	service service.Workspace
+}
+
+type workspaceView struct {
+	Id           uint64    `json:"id"`
+	Name         string    `json:"name"`
+	SubscriberId uint64    `json:"subscriber_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newWorkspaceView(w shortening.Workspace) workspaceView {
+	return workspaceView{
+		Id:           w.Id(),
+		Name:         w.Name(),
+		SubscriberId: w.SubscriberId(),
+		CreatedAt:    w.CreatedAt()}
+}
+
+type workspaceMemberView struct {
+	UserId uint64 `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+func (wc Workspace) GetSelf(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	workspaces, err := wc.service.GetSelf(uint64(userId))
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.GetSelf>: %w", reqId, err)
+	}
+
+	resPayload := []workspaceView{}
+	for _, ws := range workspaces {
+		resPayload = append(resPayload, newWorkspaceView(ws))
+	}
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.GetSelf>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (wc Workspace) Create(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	reqPayload := new(struct {
+		Name string `json:"name"`
+	})
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(reqPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.Create>: %w", reqId, err)
+	}
+	defer r.Body.Close()
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	id, err := wc.service.Create(uint64(userId), reqPayload.Name)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.Create>: %w", reqId, err)
+	}
+
+	resPayload := map[string]any{"id": id}
+	if err := reqres.HttpOk(w, http.StatusCreated, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.Create>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (wc Workspace) GetMembers(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.GetMembers>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	members, err := wc.service.GetMembers(uint64(userId), id)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.GetMembers>: %w", reqId, err)
+	}
+
+	resPayload := []workspaceMemberView{}
+	for _, m := range members {
+		resPayload = append(resPayload, workspaceMemberView{
+			UserId: m.UserId(),
+			Role:   string(m.Role())})
+	}
+	if err := reqres.HttpOk(w, http.StatusOK, resPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.GetMembers>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (wc Workspace) AddMember(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	reqPayload := new(struct {
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	})
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(reqPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.AddMember>: %w", reqId, err)
+	}
+	defer r.Body.Close()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.AddMember>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	err = wc.service.AddMember(uint64(userId), id, reqPayload.Username, reqPayload.Role)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.AddMember>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusCreated, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.AddMember>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (wc Workspace) UpdateMember(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	reqPayload := new(struct {
+		Role string `json:"role"`
+	})
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(reqPayload); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.UpdateMember>: %w", reqId, err)
+	}
+	defer r.Body.Close()
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.UpdateMember>: %w", reqId, err)
+	}
+	memberId, err := strconv.ParseUint(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.UpdateMember>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	err = wc.service.UpdateMember(uint64(userId), id, memberId, reqPayload.Role)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.UpdateMember>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.UpdateMember>: %w", reqId, err)
+	}
+	return nil
+}
+
+func (wc Workspace) RemoveMember(w http.ResponseWriter, r *http.Request) error {
+	reqId := chiMiddleware.GetReqID(r.Context())
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.RemoveMember>: %w", reqId, err)
+	}
+	memberId, err := strconv.ParseUint(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.RemoveMember>: %w", reqId, err)
+	}
+
+	userId := r.Context().Value(middleware.UserContextCtxKey).(middleware.UserContextCtxPayload)
+	if err := wc.service.RemoveMember(uint64(userId), id, memberId); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.RemoveMember>: %w", reqId, err)
+	}
+
+	if err := reqres.HttpOk(w, http.StatusNoContent, nil); err != nil {
+		return fmt.Errorf("[%s] controller<Workspace.RemoveMember>: %w", reqId, err)
+	}
+	return nil
+}
+
+func NewWorkspace(service service.Workspace) Workspace {
+	return Workspace{service}
+}
@@ -0,0 +1,47 @@
+package redirect
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	cLICK_REFERRER_MAX_LEN   = 255
+	cLICK_USER_AGENT_MAX_LEN = 255
+)
+
+// A successful access to a link, recorded for analytics purposes
+type Click struct {
+	LinkId    uint64
+	At        time.Time
+	Referrer  string
+	UserAgent string
+	IpHash    string // The visitor's IP is never stored as is
+	Variant   uint64 // Variant the visitor was sent to. Zero means none
+}
+
+func NewClick(linkId uint64, variant uint64, visit Visit, ipHash string) Click {
+	return Click{
+		LinkId:    linkId,
+		At:        visit.At,
+		Referrer:  cutClickText(visit.Referrer, cLICK_REFERRER_MAX_LEN),
+		UserAgent: cutClickText(visit.UserAgent, cLICK_USER_AGENT_MAX_LEN),
+		IpHash:    ipHash,
+		Variant:   variant}
+}
+
+// Cuts the text to at most `maxLen` bytes without splitting a character, as
+// the database would reject the whole batch over a broken one
+func cutClickText(s string, maxLen int) string {
+	s = strings.ToValidUTF8(s, "")
+	if len(s) <= maxLen {
+		return s
+	}
+
+	s = s[:maxLen]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -2,6 +2,9 @@ package redirect
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/solsteace/go-lib/oops"
@@ -9,22 +12,188 @@ import (
 
 type Link struct {
 	Id          uint64
+	UserId      uint64
 	Shortened   string
 	Destination string
 	IsOpen      bool
 	ExpiredAt   time.Time
+	Password    string // Digest of the password. Empty means unprotected
+
+	// How many visits were left when the link was retrieved? Nil means
+	// unlimited. The actual count is kept by the store, see `store.ConsumeClick`
+	RemainingClicks *uint
+
+	// HTTP status code the visitors are redirected with. Zero falls back to
+	// temporary redirect
+	RedirectType int
+
+	PassQuery bool // Should the query of the visit be merged into the destination?
+	PassPath  bool // Should the path after the alias be appended to the destination?
+	Preview   bool // Should the visitors see where they're going before being redirected?
+
+	ActiveFrom  *time.Time // Nil means active right away
+	ActiveUntil *time.Time // Nil means active until it expires
+
+	Rules    []Rule    // Ordered by their priority. See `Link.route`
+	Variants []Variant // Destinations the traffic is split across. See `Link.pick`
+
+	CreatedAt time.Time
 }
 
-func (l Link) Access() (string, error) {
+// How long permanent redirects could be remembered by the clients, at most
+const pERMANENT_MAX_AGE = 24 * time.Hour
+
+// Where and how the visitor should be redirected
+type Target struct {
+	Destination  string
+	StatusCode   int
+	CacheControl string
+	Variant      uint64 // Variant the visitor was given. Zero means none
+}
+
+// Returned when every click allowed for the link had been consumed
+var ErrClickLimitReached = oops.Forbidden{Msg: "This link had reached its click limit"}
+
+func (l Link) IsProtected() bool {
+	return l.Password != ""
+}
+func (l Link) HasClickLimit() bool {
+	return l.RemainingClicks != nil
+}
+
+// Checks whether the link could be visited at all
+func (l Link) available() error {
+	now := time.Now()
 	switch {
 	case !l.IsOpen:
-		return "", fmt.Errorf(
+		return fmt.Errorf(
 			"service<Redirect.Go>: %w",
 			oops.Forbidden{Msg: "This link is not opened by the owner"})
-	case time.Now().Sub(l.ExpiredAt) > 0:
-		return "", fmt.Errorf(
+	case l.ActiveFrom != nil && now.Before(*l.ActiveFrom):
+		return fmt.Errorf(
+			"service<Redirect.Go>: %w",
+			oops.Forbidden{Msg: fmt.Sprintf(
+				"This link is not active yet. It will be active from %s",
+				l.ActiveFrom.UTC().Format(time.RFC3339))})
+	case l.ActiveUntil != nil && !now.Before(*l.ActiveUntil):
+		return fmt.Errorf(
+			"service<Redirect.Go>: %w",
+			oops.Forbidden{Msg: "This link's active period had ended"})
+	case now.Sub(l.ExpiredAt) > 0:
+		return fmt.Errorf(
 			"service<Redirect.Go>: %w",
 			oops.Forbidden{Msg: "This link had already expired"})
+	case l.HasClickLimit() && *l.RemainingClicks == 0:
+		return fmt.Errorf("service<Redirect.Go>: %w", ErrClickLimitReached)
+	}
+	return nil
+}
+
+// Returns where the visitor should be redirected to. `confirmed` tells whether
+// the visitor had gone through the unlock form or the preview page, proving
+// they know the password of the link if it was protected
+func (l Link) Access(v Visit, confirmed bool) (Target, error) {
+	if err := l.available(); err != nil {
+		return Target{}, fmt.Errorf("service<Redirect.Go>: %w", err)
+	}
+
+	switch {
+	case l.IsProtected() && !confirmed:
+		return Target{}, fmt.Errorf(
+			"service<Redirect.Go>: %w",
+			UnlockChallenge{oops.Unauthorized{Msg: "This link is protected by password"}})
+	case l.Preview && !confirmed:
+		return Target{}, fmt.Errorf(
+			"service<Redirect.Go>: %w",
+			PreviewChallenge{oops.Forbidden{Msg: "This link should be previewed first"}})
 	}
-	return l.Destination, nil
+
+	base, variant := l.route(v)
+	destination, err := l.forward(base, v)
+	if err != nil {
+		return Target{}, fmt.Errorf("service<Redirect.Go>: %w", err)
+	}
+
+	t := l.target(destination, v.At)
+	t.Variant = variant
+	return t, nil
+}
+
+// Carries the path and query of the visit over to the destination picked for
+// the visit, as allowed by the link. Only the path and query of the
+// destination are ever touched, so the visitor couldn't steer the redirect to
+// another host
+func (l Link) forward(base string, v Visit) (string, error) {
+	if !l.PassPath && !l.PassQuery {
+		return base, nil
+	}
+
+	destination, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("domain<Link.forward>: %w", err)
+	}
+
+	if l.PassPath && v.Path != "" {
+		// Dot segments are dropped, so the suffix couldn't climb above the
+		// path of the destination
+		segments := []string{}
+		for _, s := range strings.Split(v.Path, "/") {
+			if s != "" && s != "." && s != ".." {
+				segments = append(segments, s)
+			}
+		}
+		destination = destination.JoinPath(segments...)
+	}
+
+	// Parameters set by the owner take precedence over the visitor's
+	if l.PassQuery && len(v.Query) > 0 {
+		query := destination.Query()
+		for key, values := range v.Query {
+			if _, ok := query[key]; !ok {
+				query[key] = values
+			}
+		}
+		destination.RawQuery = query.Encode()
+	}
+	return destination.String(), nil
 }
+
+func (l Link) target(destination string, now time.Time) Target {
+	t := Target{
+		Destination:  destination,
+		StatusCode:   http.StatusTemporaryRedirect,
+		CacheControl: "no-store"}
+	switch l.RedirectType {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusPermanentRedirect:
+		t.StatusCode = l.RedirectType
+	}
+
+	// Protected and click-limited links have to see every visit, even when
+	// the owner asked for permanent redirect. So do the routed and split ones,
+	// as their destination differs between visitors
+	isPermanent := t.StatusCode == http.StatusMovedPermanently ||
+		t.StatusCode == http.StatusPermanentRedirect
+	varies := len(l.Rules) > 0 || len(l.Variants) > 0
+	if !isPermanent || l.IsProtected() || l.HasClickLimit() || varies {
+		return t
+	}
+
+	// Clients shouldn't remember the link beyond its expiry or active period
+	deadline := l.ExpiredAt
+	if l.ActiveUntil != nil && l.ActiveUntil.Before(deadline) {
+		deadline = *l.ActiveUntil
+	}
+	if maxAge := min(deadline.Sub(now), pERMANENT_MAX_AGE); maxAge > 0 {
+		t.CacheControl = fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+	}
+	return t
+}
+
+// Returned in place of the destination when the visitor should provide the
+// password of the link first
+type UnlockChallenge struct {
+	Err error
+}
+
+func (uc UnlockChallenge) Error() string { return uc.Err.Error() }
+func (uc UnlockChallenge) Unwrap() error { return uc.Err }
@@ -0,0 +1,50 @@
+package redirect
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+// What the visitor is shown before deciding to continue to the destination
+type Preview struct {
+	Destination string
+	Owner       string // Username of the owner
+	CreatedAt   time.Time
+	ExpiredAt   time.Time
+	Variant     uint64 // Variant the visitor was given. Zero means none
+}
+
+// Describes the link to the visitor. The destination of protected links is
+// never revealed, as the password is asked first
+func (l Link) Describe(v Visit, owner string) (Preview, error) {
+	if err := l.available(); err != nil {
+		return Preview{}, fmt.Errorf("domain<Link.Describe>: %w", err)
+	} else if l.IsProtected() {
+		return Preview{}, fmt.Errorf(
+			"domain<Link.Describe>: %w",
+			UnlockChallenge{oops.Unauthorized{Msg: "This link is protected by password"}})
+	}
+
+	base, variant := l.route(v)
+	destination, err := l.forward(base, v)
+	if err != nil {
+		return Preview{}, fmt.Errorf("domain<Link.Describe>: %w", err)
+	}
+	return Preview{
+		Destination: destination,
+		Owner:       owner,
+		CreatedAt:   l.CreatedAt,
+		ExpiredAt:   l.ExpiredAt,
+		Variant:     variant}, nil
+}
+
+// Returned in place of the destination when the visitor should see the
+// preview of the link first
+type PreviewChallenge struct {
+	Err error
+}
+
+func (pc PreviewChallenge) Error() string { return pc.Err.Error() }
+func (pc PreviewChallenge) Unwrap() error { return pc.Err }
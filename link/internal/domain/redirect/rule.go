@@ -0,0 +1,108 @@
+package redirect
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sends the visitors matching the condition to another destination. See
+// `shortening.Rule` for the conditions and their values
+type Rule struct {
+	Condition   string
+	Values      []string
+	Destination string
+}
+
+func (r Rule) matches(v Visit) bool {
+	switch r.Condition {
+	case "platform":
+		return slices.Contains(r.Values, platformOf(v.UserAgent))
+	case "language":
+		language := preferredLanguage(v.AcceptLanguage)
+		if language == "" {
+			return false
+		}
+
+		// A primary language also covers its regional variants
+		primary, _, _ := strings.Cut(language, "-")
+		return slices.Contains(r.Values, language) || slices.Contains(r.Values, primary)
+	case "country":
+		return v.Country != "" && slices.Contains(r.Values, v.Country)
+	}
+	return false
+}
+
+// Does any of the rules need to know where the visit came from?
+func (l Link) RoutesByCountry() bool {
+	for _, r := range l.Rules {
+		if r.Condition == "country" {
+			return true
+		}
+	}
+	return false
+}
+
+// Picks the destination for the visit, along with the variant it came from.
+// The first matching rule wins, followed by the variants, falling back to the
+// destination of the link. Zero variant means none was picked
+func (l Link) route(v Visit) (string, uint64) {
+	for _, r := range l.Rules {
+		if r.matches(v) {
+			return r.Destination, 0
+		}
+	}
+	if variant, ok := l.pick(v); ok {
+		return variant.Destination, variant.Id
+	}
+	return l.Destination, 0
+}
+
+// Names the platform from the user-agent. Order matters, as the user-agents
+// of iOS and Android also mention macOS and Linux respectively
+func platformOf(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"),
+		strings.Contains(userAgent, "iPad"),
+		strings.Contains(userAgent, "iPod"):
+		return "ios"
+	case strings.Contains(userAgent, "Android"):
+		return "android"
+	case strings.Contains(userAgent, "Windows"):
+		return "windows"
+	case strings.Contains(userAgent, "Macintosh"),
+		strings.Contains(userAgent, "Mac OS X"):
+		return "macos"
+	case strings.Contains(userAgent, "Linux"):
+		return "linux"
+	}
+	return ""
+}
+
+// Returns the lowercased language with the highest weight in the
+// `Accept-Language` header. Earlier languages win the ties
+func preferredLanguage(header string) string {
+	language := ""
+	weight := 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		w := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			w = parsed
+		}
+		if w > weight {
+			language = tag
+			weight = w
+		}
+	}
+	return language
+}
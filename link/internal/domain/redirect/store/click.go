@@ -0,0 +1,7 @@
+package store
+
+import "github.com/solsteace/kochira/link/internal/domain/redirect"
+
+type Click interface {
+	AddClicks(c []redirect.Click) error // Records clicks and rolls them up per link
+}
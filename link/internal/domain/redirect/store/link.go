@@ -4,4 +4,5 @@ import "github.com/solsteace/kochira/link/internal/domain/redirect"
 
 type Shortening interface {
 	GetByAlias(shortened string) (redirect.Link, error)
+	ConsumeClick(id uint64) (bool, error) // Atomically takes one of the remaining clicks. False when none is left
 }
@@ -0,0 +1,5 @@
+package store
+
+type Owner interface {
+	GetUsernameById(userId uint64) (string, error)
+}
@@ -0,0 +1,17 @@
+package store
+
+import "time"
+
+// Keeps track of failed unlock attempts, so passwords couldn't be guessed by
+// trying them one after another
+type UnlockAttempt interface {
+	CountFailures(key string) (uint, error)            // Failures made within the current window
+	AddFailure(key string, window time.Duration) error // The window starts on the first failure
+
+	// Counts an attempt as failed up front, unless `limit` failures were made
+	// within the current window already. Returns how many were counted,
+	// including this one, and whether it was counted at all. Taking and
+	// checking at once keeps concurrent attempts from passing the limit
+	TakeAttempt(key string, limit uint, window time.Duration) (uint, bool, error)
+	ReturnAttempt(key string) error // Uncounts an attempt that turned out right
+}
@@ -0,0 +1,40 @@
+package redirect
+
+import "math/rand/v2"
+
+// One of the destinations the traffic of a link is split across. See
+// `shortening.Variant`
+type Variant struct {
+	Id          uint64
+	Destination string
+	Weight      uint
+}
+
+// Picks the variant for the visit. Visitors who had been given a variant
+// keep it, as long as the link still gives it any traffic. Otherwise, the
+// variant is drawn by weight
+func (l Link) pick(v Visit) (Variant, bool) {
+	if len(l.Variants) == 0 {
+		return Variant{}, false
+	}
+
+	var total uint
+	for _, variant := range l.Variants {
+		if variant.Id == v.Variant && variant.Weight > 0 {
+			return variant, true
+		}
+		total += variant.Weight
+	}
+	if total == 0 {
+		return Variant{}, false
+	}
+
+	n := rand.UintN(total)
+	for _, variant := range l.Variants {
+		if n < variant.Weight {
+			return variant, true
+		}
+		n -= variant.Weight
+	}
+	return Variant{}, false
+}
@@ -0,0 +1,21 @@
+package redirect
+
+import (
+	"net/url"
+	"time"
+)
+
+// Describes a single request made by a visitor trying to access a link
+type Visit struct {
+	At        time.Time
+	Referrer  string
+	UserAgent string
+	Ip        string
+
+	Path  string     // What came after the alias, without the leading slash
+	Query url.Values // Query parameters of the request
+
+	AcceptLanguage string // Raw `Accept-Language` header
+	Country        string // ISO 3166-1 alpha-2 code. Empty when unknown
+	Variant        uint64 // Variant the visitor had been given before. Zero means none
+}
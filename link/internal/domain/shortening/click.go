@@ -0,0 +1,122 @@
+package shortening
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+type Granularity string
+
+const (
+	GranularityHour Granularity = "hour"
+	GranularityDay  Granularity = "day"
+)
+
+// Returns the start of the period `t` belongs to
+func (g Granularity) Bucket(t time.Time) time.Time {
+	switch g {
+	case GranularityHour:
+		return t.Truncate(time.Hour)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// How far back a window reaches when its start isn't given
+func (g Granularity) defaultSpan() time.Duration {
+	switch g {
+	case GranularityHour:
+		return time.Hour * 24
+	default:
+		return time.Hour * 24 * 30
+	}
+}
+
+// How wide a window could be, to keep the number of buckets sane
+func (g Granularity) maxSpan() time.Duration {
+	switch g {
+	case GranularityHour:
+		return time.Hour * 24 * 31
+	default:
+		return time.Hour * 24 * 366
+	}
+}
+
+func NewGranularity(g string) (Granularity, error) {
+	switch actual := Granularity(g); actual {
+	case "":
+		return GranularityDay, nil
+	case GranularityHour, GranularityDay:
+		return actual, nil
+	}
+
+	err := oops.BadValues{
+		Err: errors.New("unknown granularity"),
+		Msg: fmt.Sprintf(
+			"Granularity should be either `%s` or `%s` (get: %s)",
+			GranularityHour, GranularityDay, g)}
+	return "", fmt.Errorf("domain<NewGranularity>: %w", err)
+}
+
+// The time range of click counts to be read, bounded by [from, to)
+type ClickWindow struct {
+	granularity Granularity
+	from        time.Time
+	to          time.Time
+}
+
+func (cw ClickWindow) Granularity() Granularity { return cw.granularity }
+func (cw ClickWindow) From() time.Time          { return cw.from }
+func (cw ClickWindow) To() time.Time            { return cw.to }
+
+func NewClickWindow(
+	granularity Granularity,
+	from *time.Time,
+	to *time.Time,
+) (ClickWindow, error) {
+	actualTo := time.Now()
+	if to != nil {
+		actualTo = *to
+	}
+	actualFrom := actualTo.Add(-1 * granularity.defaultSpan())
+	if from != nil {
+		actualFrom = *from
+	}
+
+	switch span := actualTo.Sub(actualFrom); {
+	case span <= 0:
+		err := oops.BadValues{
+			Err: errors.New("empty click window"),
+			Msg: "`from` should be earlier than `to`"}
+		return ClickWindow{}, fmt.Errorf("domain<NewClickWindow>: %w", err)
+	case span > granularity.maxSpan():
+		err := oops.BadValues{
+			Err: errors.New("click window too wide"),
+			Msg: fmt.Sprintf(
+				"Click window for `%s` granularity could only span %.0f hours at maximum",
+				granularity, granularity.maxSpan().Hours())}
+		return ClickWindow{}, fmt.Errorf("domain<NewClickWindow>: %w", err)
+	}
+
+	cw := ClickWindow{
+		granularity: granularity,
+		from:        granularity.Bucket(actualFrom),
+		to:          actualTo}
+	return cw, nil
+}
+
+// The number of clicks a link received within a period
+type ClickCount struct {
+	at     time.Time
+	clicks uint64
+}
+
+func (cc ClickCount) At() time.Time  { return cc.at }
+func (cc ClickCount) Clicks() uint64 { return cc.clicks }
+
+func NewClickCount(at time.Time, clicks uint64) ClickCount {
+	return ClickCount{at, clicks}
+}
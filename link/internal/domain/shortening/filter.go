@@ -0,0 +1,103 @@
+package shortening
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+const sEARCH_MAX_LEN = 255
+
+// How links are ordered when listed
+type LinkSort string
+
+const (
+	LinkSortUpdatedAt LinkSort = "updatedAt"
+	LinkSortExpiredAt LinkSort = "expiredAt"
+	LinkSortClicks    LinkSort = "clicks"
+)
+
+// Criteria for listing the links of a user. Nil criteria aren't applied
+type LinkFilter struct {
+	tag        *string
+	folder     *string
+	isOpen     *bool
+	expired    *bool
+	search     string // Substring of either the destination or the alias
+	sort       LinkSort
+	descending bool
+}
+
+func (f LinkFilter) Tag() *string     { return f.tag }
+func (f LinkFilter) Folder() *string  { return f.folder }
+func (f LinkFilter) IsOpen() *bool    { return f.isOpen }
+func (f LinkFilter) Expired() *bool   { return f.expired }
+func (f LinkFilter) Search() string   { return f.search }
+func (f LinkFilter) Sort() LinkSort   { return f.sort }
+func (f LinkFilter) Descending() bool { return f.descending }
+
+// Empty `sort` orders the links by `updatedAt`, while empty `order` puts the
+// latest ones first
+func NewLinkFilter(
+	tag *string,
+	folder *string,
+	isOpen *bool,
+	expired *bool,
+	search string,
+	sort string,
+	order string,
+) (LinkFilter, error) {
+	f := LinkFilter{
+		isOpen:     isOpen,
+		expired:    expired,
+		search:     search,
+		sort:       LinkSortUpdatedAt,
+		descending: true}
+
+	if tag != nil {
+		actualTag, err := normalizeTag(*tag)
+		if err != nil {
+			return LinkFilter{}, fmt.Errorf("domain<NewLinkFilter>: %w", err)
+		}
+		f.tag = &actualTag
+	}
+	if folder != nil {
+		actualFolder, err := normalizeFolder(*folder)
+		if err != nil {
+			return LinkFilter{}, fmt.Errorf("domain<NewLinkFilter>: %w", err)
+		}
+		f.folder = &actualFolder
+	}
+	if len(search) > sEARCH_MAX_LEN {
+		err := oops.BadValues{
+			Err: errors.New("search too long"),
+			Msg: fmt.Sprintf("Search could only be %d chars long at maximum", sEARCH_MAX_LEN)}
+		return LinkFilter{}, fmt.Errorf("domain<NewLinkFilter>: %w", err)
+	}
+
+	switch s := LinkSort(sort); s {
+	case "":
+	case LinkSortUpdatedAt, LinkSortExpiredAt, LinkSortClicks:
+		f.sort = s
+	default:
+		err := oops.BadValues{
+			Err: errors.New("unknown sort"),
+			Msg: fmt.Sprintf(
+				"Links could only be sorted by `%s`, `%s`, or `%s` (get: %q)",
+				LinkSortUpdatedAt, LinkSortExpiredAt, LinkSortClicks, sort)}
+		return LinkFilter{}, fmt.Errorf("domain<NewLinkFilter>: %w", err)
+	}
+
+	switch order {
+	case "", "desc":
+	case "asc":
+		f.descending = false
+	default:
+		err := oops.BadValues{
+			Err: errors.New("unknown order"),
+			Msg: fmt.Sprintf("Order should be either `asc` or `desc` (get: %q)", order)}
+		return LinkFilter{}, fmt.Errorf("domain<NewLinkFilter>: %w", err)
+	}
+	return f, nil
+}
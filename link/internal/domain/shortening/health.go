@@ -0,0 +1,70 @@
+package shortening
+
+import (
+	"net/http"
+	"time"
+)
+
+// Outcome of the checks on the link's destination, see `Link.RecordCheck`
+type Health struct {
+	status        *int // HTTP status of the latest check. Nil when the destination couldn't be reached
+	latency       time.Duration
+	failureStreak uint       // How many checks in a row had failed?
+	checkedAt     *time.Time // Nil means the destination is yet to be checked
+}
+
+func (h Health) Status() *int           { return h.status }
+func (h Health) Latency() time.Duration { return h.latency }
+func (h Health) FailureStreak() uint    { return h.failureStreak }
+func (h Health) CheckedAt() *time.Time  { return h.checkedAt }
+func (h Health) IsHealthy() bool        { return h.failureStreak == 0 }
+
+func NewHealth(
+	status *int,
+	latency time.Duration,
+	failureStreak uint,
+	checkedAt *time.Time,
+) Health {
+	return Health{
+		status:        status,
+		latency:       latency,
+		failureStreak: failureStreak,
+		checkedAt:     checkedAt}
+}
+
+func (l Link) Health() Health { return l.health }
+
+// Restores the health as stored. Use `RecordCheck` for new checks
+func (l *Link) SetHealth(h Health) {
+	l.health = h
+}
+
+// Records a check on the destination. Nil `status` means the destination
+// couldn't be reached. Returns true when the failures just reached
+// `threshold` in a row, so it's only reported once per streak
+func (l *Link) RecordCheck(
+	status *int,
+	latency time.Duration,
+	now time.Time,
+	threshold uint,
+) bool {
+	streak := uint(0)
+	if isFailedCheck(status) {
+		streak = l.health.failureStreak + 1
+	}
+	l.health = NewHealth(status, latency, streak, &now)
+	return threshold > 0 && streak == threshold
+}
+
+// Broken pages and failing servers count as failures, unlike statuses that
+// the destination might give on purpose, such as asking for credentials
+func isFailedCheck(status *int) bool {
+	switch {
+	case status == nil:
+		return true
+	case *status == http.StatusNotFound, *status == http.StatusGone:
+		return true
+	default:
+		return *status >= http.StatusInternalServerError
+	}
+}
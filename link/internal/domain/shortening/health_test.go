@@ -0,0 +1,72 @@
+package shortening
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRecordCheckStreak(t *testing.T) {
+	ok := http.StatusOK
+	notFound := http.StatusNotFound
+	unauthorized := http.StatusUnauthorized
+	serverError := http.StatusBadGateway
+
+	cases := []struct {
+		name     string
+		statuses []*int
+		streak   uint
+	}{
+		{"healthy", []*int{&ok, &ok}, 0},
+		{"unreachable", []*int{nil, nil}, 2},
+		{"broken page", []*int{&notFound}, 1},
+		{"failing server", []*int{&serverError, &serverError, &serverError}, 3},
+		{"recovered", []*int{nil, &serverError, &ok}, 0},
+		{"on purpose", []*int{&unauthorized}, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l := Link{}
+			for _, s := range c.statuses {
+				l.RecordCheck(s, time.Millisecond, time.Now(), 0)
+			}
+			if got := l.Health().FailureStreak(); got != c.streak {
+				t.Fatalf("expected streak %d, got %d", c.streak, got)
+			}
+			if got := l.Health().IsHealthy(); got != (c.streak == 0) {
+				t.Fatalf("expected healthy to be %v, got %v", c.streak == 0, got)
+			}
+		})
+	}
+}
+
+func TestRecordCheckReportsThresholdOnce(t *testing.T) {
+	l := Link{}
+	reported := []bool{}
+	for range 4 {
+		reported = append(reported, l.RecordCheck(nil, 0, time.Now(), 2))
+	}
+	expected := []bool{false, true, false, false}
+	for idx := range expected {
+		if reported[idx] != expected[idx] {
+			t.Fatalf("expected reports %v, got %v", expected, reported)
+		}
+	}
+
+	// A new streak is reported again
+	ok := http.StatusOK
+	l.RecordCheck(&ok, 0, time.Now(), 2)
+	l.RecordCheck(nil, 0, time.Now(), 2)
+	if !l.RecordCheck(nil, 0, time.Now(), 2) {
+		t.Fatal("expected the new streak to be reported")
+	}
+}
+
+func TestRecordCheckWithoutThreshold(t *testing.T) {
+	l := Link{}
+	for range 3 {
+		if l.RecordCheck(nil, 0, time.Now(), 0) {
+			t.Fatal("expected nothing to be reported without a threshold")
+		}
+	}
+}
@@ -3,8 +3,8 @@ package shortening
 import (
 	"errors"
 	"fmt"
-	"math/rand/v2"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/solsteace/go-lib/oops"
@@ -14,6 +14,9 @@ const (
 	sHORTENED_MAX_LEN   = 15
 	sHORTENED_CHARSET   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	dESTINATION_MAX_LEN = 255
+	aLIAS_MAX_LEN       = 32
+	pASSWORD_MIN_LEN    = 4
+	pASSWORD_MAX_LEN    = 72 // bcrypt ignores anything beyond this
 )
 
 type Link struct {
@@ -25,17 +28,63 @@ type Link struct {
 	isOpen      bool
 	updatedAt   time.Time
 	expiredAt   time.Time
+	workspaceId *uint64    // Which workspace shares the link? Nil means it's personal
+	health      Health     // Kept only while the destination stays the same
+	metadata    Metadata   // Kept only while the destination stays the same
+	deletedAt   *time.Time // Nil unless the link is in the trash
+
+	settings
+}
+
+// Optional per-link settings. These are carried over on `Reconfigure` and
+// aren't gated by subscription
+type settings struct {
+	password        string // Digest of the password. Empty means unprotected
+	maxClicks       *uint  // How many times the link could be visited? Nil means unlimited
+	remainingClicks *uint  // How many visits are left before the link stops resolving?
+	redirectType    RedirectType
+	passQuery       bool // Should the query of the visits be merged into the destination?
+	passPath        bool // Should the path after the alias be appended to the destination?
+	preview         bool // Should the visitors see where they're going before being redirected?
+	tags            []string
+	folder          string     // Empty means not in any folder
+	activeFrom      *time.Time // Since when the link could be visited? Nil means right away
+	activeUntil     *time.Time // Until when the link could be visited? Nil means until it expires
+	variants        []Variant  // Destinations the traffic is split across. Empty means no split
 }
 
-// Sets shortened link
-func (l *Link) Shorten() {
-	shortened := make([]byte, 15)
-	for i, _ := range shortened {
-		idx := rand.Int() % len(sHORTENED_CHARSET)
-		shortened[i] = sHORTENED_CHARSET[idx]
+// Sets shortened link, which also serves as its alias until configured
+// otherwise. See `service.CodeGenerator` for making the code
+func (l *Link) Shorten(code string) error {
+	if code == "" || len(code) > sHORTENED_MAX_LEN {
+		err := oops.BadValues{
+			Err: errors.New("shortened out of range"),
+			Msg: fmt.Sprintf(
+				"Shortened should be 1 - %d chars long", sHORTENED_MAX_LEN)}
+		return fmt.Errorf("domain<Link.Shorten>: %w", err)
+	}
+	for _, c := range code {
+		if !strings.ContainsRune(sHORTENED_CHARSET, c) {
+			err := oops.BadValues{
+				Err: errors.New("shortened outside charset"),
+				Msg: "Shortened should only contain letters and digits"}
+			return fmt.Errorf("domain<Link.Shorten>: %w", err)
+		}
 	}
-	l.shortened = string(shortened)
-	l.alias = l.shortened
+
+	l.shortened = code
+	l.alias = code
+	return nil
+}
+
+// Returned by the store when the shortened or alias of a new link had been
+// used by another link. `Index` points to the offending link within a batch
+type ShortenedTaken struct {
+	Index int
+}
+
+func (e ShortenedTaken) Error() string {
+	return fmt.Sprintf("shortened of link #%d had been taken", e.Index)
 }
 func (l *Link) Activate() {
 	l.isOpen = true
@@ -44,24 +93,229 @@ func (l *Link) Deactivate() {
 	l.isOpen = false
 }
 
+// Requires visitors to provide the password before being redirected. The
+// password should had already been hashed
+func (l *Link) Protect(passwordDigest string) {
+	l.password = passwordDigest
+}
+func (l *Link) Unprotect() {
+	l.password = ""
+}
+
+// Limits how many times the link could be visited. A limit of 1 makes the
+// link burn after being visited
+func (l *Link) LimitClicks(maxClicks, remainingClicks uint) error {
+	switch {
+	case maxClicks < 1:
+		err := oops.BadValues{
+			Err: errors.New("click limit out of range"),
+			Msg: "Click limit should be at least 1"}
+		return fmt.Errorf("domain<Link.LimitClicks>: %w", err)
+	case remainingClicks > maxClicks:
+		err := oops.BadValues{
+			Err: errors.New("remaining clicks out of range"),
+			Msg: fmt.Sprintf(
+				"Remaining clicks couldn't exceed the click limit (limit: %d; get: %d)",
+				maxClicks, remainingClicks)}
+		return fmt.Errorf("domain<Link.LimitClicks>: %w", err)
+	}
+
+	l.maxClicks = &maxClicks
+	l.remainingClicks = &remainingClicks
+	return nil
+}
+func (l *Link) UnlimitClicks() {
+	l.maxClicks = nil
+	l.remainingClicks = nil
+}
+func (l *Link) SetRedirectType(rt RedirectType) {
+	l.redirectType = rt
+}
+func (l *Link) SetPassthrough(passQuery, passPath bool) {
+	l.passQuery = passQuery
+	l.passPath = passPath
+}
+func (l *Link) SetPreview(preview bool) {
+	l.preview = preview
+}
+
+// Restricts when the link could be visited. Nil leaves that end of the
+// window open. The window is kept in UTC, as the rest of the timestamps are
+// stored without their zone
+func (l *Link) Schedule(activeFrom, activeUntil *time.Time) error {
+	if activeFrom != nil && activeUntil != nil && !activeFrom.Before(*activeUntil) {
+		err := oops.BadValues{
+			Err: errors.New("activation window out of order"),
+			Msg: "Link should become active before its active period ends"}
+		return fmt.Errorf("domain<Link.Schedule>: %w", err)
+	}
+	// Approved links already have their expiry, while the rest are checked on
+	// their approval
+	if activeFrom != nil && l.isOpen && !activeFrom.Before(l.expiredAt) {
+		err := oops.BadValues{
+			Err: errors.New("active period starts after expiry"),
+			Msg: fmt.Sprintf(
+				"Link should become active before it expires (at %s)",
+				l.expiredAt.UTC().Format(time.RFC3339))}
+		return fmt.Errorf("domain<Link.Schedule>: %w", err)
+	}
+
+	l.activeFrom = nil
+	if activeFrom != nil {
+		from := activeFrom.UTC()
+		l.activeFrom = &from
+	}
+	l.activeUntil = nil
+	if activeUntil != nil {
+		until := activeUntil.UTC()
+		l.activeUntil = &until
+	}
+	return nil
+}
+
+// Returns a copy of the link with its essentials replaced, while keeping its
+// optional settings intact
+func (l Link) Reconfigure(
+	alias string,
+	destination string,
+	isOpen bool,
+	updatedAt time.Time,
+	expiredAt time.Time,
+) (Link, error) {
+	id := l.id
+	newLink, err := NewLink(
+		&id,
+		l.userId,
+		l.shortened,
+		alias,
+		destination,
+		isOpen,
+		updatedAt,
+		expiredAt)
+	if err != nil {
+		return Link{}, fmt.Errorf("domain<Link.Reconfigure>: %w", err)
+	}
+
+	newLink.workspaceId = l.workspaceId
+	newLink.settings = l.settings
+	newLink.deletedAt = l.deletedAt
+	if destination == l.destination {
+		newLink.health = l.health
+		newLink.metadata = l.metadata
+	}
+	return newLink, nil
+}
+
 func (l Link) HadExpired() bool {
 	return time.Now().After(l.expiredAt)
 }
-func (l Link) AccessibleBy(userId uint64) bool {
+
+// When should the link expire if it's approved at `now` for `lifetime`? The
+// lifetime counts from the approval, not from when the link becomes active.
+// The link ends earlier when its active period does, but never later, hence
+// links that would only become active after the lifetime ran out are refused
+func (l Link) ExpiryFrom(now time.Time, lifetime time.Duration) (time.Time, error) {
+	expiredAt := now.Add(lifetime)
+	if l.activeFrom != nil && !l.activeFrom.Before(expiredAt) {
+		err := oops.BadValues{
+			Err: errors.New("active period starts after expiry"),
+			Msg: fmt.Sprintf(
+				"Link should become active before it expires, which is within %s of its approval",
+				lifetime)}
+		return time.Time{}, fmt.Errorf("domain<Link.ExpiryFrom>: %w", err)
+	}
+	if l.activeUntil != nil && l.activeUntil.Before(expiredAt) {
+		return *l.activeUntil, nil
+	}
+	return expiredAt, nil
+}
+
+// Checks whether the user could act on the link. `role` is the user's role in
+// the workspace of the link, nil when they aren't a member. Personal links
+// permit anything to their owner only
+func (l Link) Permits(userId uint64, role *Role, p Permission) bool {
+	if l.workspaceId == nil {
+		return l.userId == userId
+	}
+	return role != nil && role.Can(p)
+}
+
+// Was the link made by the user? Unlike `Permits`, this ignores the workspace
+func (l Link) OwnedBy(userId uint64) bool {
 	return l.userId == userId
 }
+
+// Shares the link with the members of the workspace
+func (l *Link) PlaceIn(workspaceId uint64) {
+	l.workspaceId = &workspaceId
+}
 func (l Link) HasCustomAlias() bool {
 	return l.shortened != l.alias
 }
+func (l Link) IsProtected() bool {
+	return l.password != ""
+}
+func (l Link) HasClickLimit() bool {
+	return l.maxClicks != nil
+}
 
 func (l Link) Id() uint64           { return l.id }
 func (l Link) UserId() uint64       { return l.userId }
+func (l Link) WorkspaceId() *uint64 { return l.workspaceId }
 func (l Link) Shortened() string    { return l.shortened }
 func (l Link) Alias() string        { return l.alias }
 func (l Link) Destination() string  { return l.destination }
 func (l Link) IsOpen() bool         { return l.isOpen }
 func (l Link) UpdatedAt() time.Time { return l.updatedAt }
 func (l Link) ExpiredAt() time.Time { return l.expiredAt }
+func (l Link) Password() string     { return l.password }
+
+func (l Link) RedirectType() RedirectType { return l.redirectType }
+func (l Link) PassesQuery() bool          { return l.passQuery }
+func (l Link) PassesPath() bool           { return l.passPath }
+func (l Link) RequiresPreview() bool      { return l.preview }
+
+func (l Link) MaxClicks() *uint {
+	if l.maxClicks == nil {
+		return nil
+	}
+	maxClicks := *l.maxClicks
+	return &maxClicks
+}
+func (l Link) ActiveFrom() *time.Time {
+	if l.activeFrom == nil {
+		return nil
+	}
+	from := *l.activeFrom
+	return &from
+}
+func (l Link) ActiveUntil() *time.Time {
+	if l.activeUntil == nil {
+		return nil
+	}
+	until := *l.activeUntil
+	return &until
+}
+func (l Link) RemainingClicks() *uint {
+	if l.remainingClicks == nil {
+		return nil
+	}
+	remaining := *l.remainingClicks
+	return &remaining
+}
+
+// Checks whether a raw password is acceptable for protecting a link
+func ValidatePassword(password string) error {
+	if len(password) < pASSWORD_MIN_LEN || len(password) > pASSWORD_MAX_LEN {
+		err := oops.BadValues{
+			Err: errors.New("password length out of range"),
+			Msg: fmt.Sprintf(
+				"Password should be %d - %d chars long",
+				pASSWORD_MIN_LEN, pASSWORD_MAX_LEN)}
+		return fmt.Errorf("domain<ValidatePassword>: %w", err)
+	}
+	return nil
+}
 
 func NewLink(
 	id *uint64,
@@ -84,6 +338,12 @@ func NewLink(
 				"Shortened could only be %d chars long at maximum",
 				sHORTENED_MAX_LEN))}
 		return Link{}, fmt.Errorf("domain<NewLink>: %w", err)
+	} else if len(alias) > aLIAS_MAX_LEN {
+		err := oops.BadValues{
+			Err: errors.New(fmt.Sprintf(
+				"Alias could only be %d chars long at maximum",
+				aLIAS_MAX_LEN))}
+		return Link{}, fmt.Errorf("domain<NewLink>: %w", err)
 	} else if len(destination) > dESTINATION_MAX_LEN {
 		err := oops.BadValues{
 			Err: errors.New(fmt.Sprintf(
@@ -109,6 +369,7 @@ func NewLink(
 		destination: destination,
 		isOpen:      isOpen,
 		updatedAt:   updatedAt,
-		expiredAt:   expiredAt}
+		expiredAt:   expiredAt,
+		settings:    settings{redirectType: DefaultRedirectType}}
 	return l, nil
 }
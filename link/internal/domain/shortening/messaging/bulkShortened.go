@@ -0,0 +1,40 @@
+package messaging
+
+const BulkShortenedName = "link.bulk_shortened"
+
+// Links created at once, which are approved or rejected as a whole
+type BulkShortened struct {
+	id          uint64
+	userId      uint64
+	linkIds     []uint64
+	workspaceId *uint64 // Which workspace are the links placed in, if any?
+
+	// Whose subscription to check? The workspace's subscriber, or the user
+	// when there's no workspace
+	subscriberId uint64
+}
+
+func (bs BulkShortened) Id() uint64           { return bs.id }
+func (bs BulkShortened) UserId() uint64       { return bs.userId }
+func (bs BulkShortened) WorkspaceId() *uint64 { return bs.workspaceId }
+func (bs BulkShortened) SubscriberId() uint64 { return bs.subscriberId }
+func (bs BulkShortened) LinkIds() []uint64 {
+	linkIds := make([]uint64, len(bs.linkIds))
+	copy(linkIds, bs.linkIds)
+	return linkIds
+}
+
+func NewBulkShortened(
+	id uint64,
+	userId uint64,
+	linkIds []uint64,
+	workspaceId *uint64,
+	subscriberId uint64,
+) BulkShortened {
+	return BulkShortened{
+		id:           id,
+		userId:       userId,
+		linkIds:      linkIds,
+		workspaceId:  workspaceId,
+		subscriberId: subscriberId}
+}
@@ -0,0 +1,47 @@
+package messaging
+
+import "time"
+
+const DestinationUnhealthyName = "link.destination_unhealthy"
+
+// Destination of the link kept failing its checks
+type DestinationUnhealthy struct {
+	id            uint64
+	linkId        uint64
+	userId        uint64
+	workspaceId   *uint64
+	destination   string
+	status        *int // HTTP status of the latest check. Nil when the destination couldn't be reached
+	failureStreak uint
+	checkedAt     time.Time
+}
+
+func (du DestinationUnhealthy) Id() uint64           { return du.id }
+func (du DestinationUnhealthy) LinkId() uint64       { return du.linkId }
+func (du DestinationUnhealthy) UserId() uint64       { return du.userId }
+func (du DestinationUnhealthy) WorkspaceId() *uint64 { return du.workspaceId }
+func (du DestinationUnhealthy) Destination() string  { return du.destination }
+func (du DestinationUnhealthy) Status() *int         { return du.status }
+func (du DestinationUnhealthy) FailureStreak() uint  { return du.failureStreak }
+func (du DestinationUnhealthy) CheckedAt() time.Time { return du.checkedAt }
+
+func NewDestinationUnhealthy(
+	id uint64,
+	linkId uint64,
+	userId uint64,
+	workspaceId *uint64,
+	destination string,
+	status *int,
+	failureStreak uint,
+	checkedAt time.Time,
+) DestinationUnhealthy {
+	return DestinationUnhealthy{
+		id:            id,
+		linkId:        linkId,
+		userId:        userId,
+		workspaceId:   workspaceId,
+		destination:   destination,
+		status:        status,
+		failureStreak: failureStreak,
+		checkedAt:     checkedAt}
+}
@@ -3,18 +3,33 @@ package messaging
 const LinkShortenedName = "link.shortened"
 
 type LinkShortened struct {
-	id     uint64
-	userId uint64
-	linkId uint64
+	id          uint64
+	userId      uint64
+	linkId      uint64
+	workspaceId *uint64 // Which workspace is the link placed in, if any?
+
+	// Whose subscription to check? The workspace's subscriber, or the user
+	// when there's no workspace
+	subscriberId uint64
 }
 
-func (ls LinkShortened) Id() uint64     { return ls.id }
-func (ls LinkShortened) UserId() uint64 { return ls.userId }
-func (ls LinkShortened) LinkId() uint64 { return ls.linkId }
+func (ls LinkShortened) Id() uint64           { return ls.id }
+func (ls LinkShortened) UserId() uint64       { return ls.userId }
+func (ls LinkShortened) LinkId() uint64       { return ls.linkId }
+func (ls LinkShortened) WorkspaceId() *uint64 { return ls.workspaceId }
+func (ls LinkShortened) SubscriberId() uint64 { return ls.subscriberId }
 
-func NewLinkShortened(id, userId, linkId uint64) LinkShortened {
+func NewLinkShortened(
+	id uint64,
+	userId uint64,
+	linkId uint64,
+	workspaceId *uint64,
+	subscriberId uint64,
+) LinkShortened {
 	return LinkShortened{
-		id:     id,
-		userId: userId,
-		linkId: linkId}
+		id:           id,
+		userId:       userId,
+		linkId:       linkId,
+		workspaceId:  workspaceId,
+		subscriberId: subscriberId}
 }
@@ -9,14 +9,23 @@ type ShortConfigured struct {
 	alias       string
 	destination string
 	isOpen      bool
+	workspaceId *uint64 // Which workspace is the link placed in, if any?
+
+	// Whose subscription to check? The workspace's subscriber, or the user
+	// when there's no workspace
+	subscriberId uint64
+	editorId     uint64 // Who requested the change? Differs from the user on workspaces
 }
 
-func (sc ShortConfigured) Id() uint64          { return sc.id }
-func (sc ShortConfigured) LinkId() uint64      { return sc.linkId }
-func (sc ShortConfigured) UserId() uint64      { return sc.userId }
-func (sc ShortConfigured) Alias() string       { return sc.alias }
-func (sc ShortConfigured) Destination() string { return sc.destination }
-func (sc ShortConfigured) IsOpen() bool        { return sc.isOpen }
+func (sc ShortConfigured) Id() uint64           { return sc.id }
+func (sc ShortConfigured) LinkId() uint64       { return sc.linkId }
+func (sc ShortConfigured) UserId() uint64       { return sc.userId }
+func (sc ShortConfigured) Alias() string        { return sc.alias }
+func (sc ShortConfigured) Destination() string  { return sc.destination }
+func (sc ShortConfigured) IsOpen() bool         { return sc.isOpen }
+func (sc ShortConfigured) WorkspaceId() *uint64 { return sc.workspaceId }
+func (sc ShortConfigured) SubscriberId() uint64 { return sc.subscriberId }
+func (sc ShortConfigured) EditorId() uint64     { return sc.editorId }
 
 func NewShortConfigured(
 	id uint64,
@@ -25,12 +34,18 @@ func NewShortConfigured(
 	shortened string,
 	destination string,
 	isOpen bool,
+	workspaceId *uint64,
+	subscriberId uint64,
+	editorId uint64,
 ) ShortConfigured {
 	return ShortConfigured{
-		id:          id,
-		userId:      userId,
-		linkId:      linkId,
-		alias:       shortened,
-		destination: destination,
-		isOpen:      isOpen}
+		id:           id,
+		userId:       userId,
+		linkId:       linkId,
+		alias:        shortened,
+		destination:  destination,
+		isOpen:       isOpen,
+		workspaceId:  workspaceId,
+		subscriberId: subscriberId,
+		editorId:     editorId}
 }
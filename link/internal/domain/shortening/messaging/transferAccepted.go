@@ -0,0 +1,21 @@
+package messaging
+
+const TransferAcceptedName = "link.transfer_accepted"
+
+// Transfer accepted by its recipient, whose subscription is yet to be checked
+type TransferAccepted struct {
+	id         uint64
+	userId     uint64 // The recipient
+	transferId uint64
+}
+
+func (ta TransferAccepted) Id() uint64         { return ta.id }
+func (ta TransferAccepted) UserId() uint64     { return ta.userId }
+func (ta TransferAccepted) TransferId() uint64 { return ta.transferId }
+
+func NewTransferAccepted(id, userId, transferId uint64) TransferAccepted {
+	return TransferAccepted{
+		id:         id,
+		userId:     userId,
+		transferId: transferId}
+}
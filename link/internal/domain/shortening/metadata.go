@@ -0,0 +1,76 @@
+package shortening
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	mETADATA_TEXT_MAX_LEN = 300  // Longer texts are cut, since they're only meant for display
+	mETADATA_URL_MAX_LEN  = 2048 // Longer URLs are dropped, since a cut URL is of no use
+)
+
+// What the destination page says about itself. Empty fields are the ones the
+// page didn't have
+type Metadata struct {
+	title         string // From `<title>`
+	ogTitle       string
+	ogDescription string
+	ogImage       string     // Absolute URL
+	favicon       string     // Absolute URL
+	fetchedAt     *time.Time // Nil means the destination is yet to be fetched
+}
+
+func (m Metadata) Title() string         { return m.title }
+func (m Metadata) OgTitle() string       { return m.ogTitle }
+func (m Metadata) OgDescription() string { return m.ogDescription }
+func (m Metadata) OgImage() string       { return m.ogImage }
+func (m Metadata) Favicon() string       { return m.favicon }
+func (m Metadata) FetchedAt() *time.Time { return m.fetchedAt }
+
+// Texts are trimmed and cut to fit, while URLs that are too long are dropped
+func NewMetadata(
+	title string,
+	ogTitle string,
+	ogDescription string,
+	ogImage string,
+	favicon string,
+	fetchedAt *time.Time,
+) Metadata {
+	return Metadata{
+		title:         fitMetadataText(title),
+		ogTitle:       fitMetadataText(ogTitle),
+		ogDescription: fitMetadataText(ogDescription),
+		ogImage:       fitMetadataUrl(ogImage),
+		favicon:       fitMetadataUrl(favicon),
+		fetchedAt:     fetchedAt}
+}
+
+func (l Link) Metadata() Metadata { return l.metadata }
+
+// Sets what was fetched from the current destination. See `Metadata`
+func (l *Link) SetMetadata(m Metadata) {
+	l.metadata = m
+}
+
+func fitMetadataText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= mETADATA_TEXT_MAX_LEN {
+		return s
+	}
+
+	s = s[:mETADATA_TEXT_MAX_LEN]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func fitMetadataUrl(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > mETADATA_URL_MAX_LEN {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,17 @@
+package shortening
+
+// A user as known by `link`. Kept from what `account` announces on
+// registration, so `link` never has to read the users of `account`
+type Owner struct {
+	id       uint64
+	username string
+}
+
+func (o Owner) Id() uint64       { return o.id }
+func (o Owner) Username() string { return o.username }
+
+func NewOwner(id uint64, username string) Owner {
+	return Owner{
+		id:       id,
+		username: username}
+}
@@ -0,0 +1,108 @@
+package shortening
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+const cURSOR_VERSION = "v1"
+
+// Position of a link within the listing ordered by `(updatedAt, id)`. The
+// listing resumes right after the position, or right before it when the
+// cursor goes backward
+type LinkCursor struct {
+	updatedAt time.Time
+	id        uint64
+	backward  bool
+}
+
+func (c LinkCursor) UpdatedAt() time.Time { return c.updatedAt }
+func (c LinkCursor) Id() uint64           { return c.id }
+func (c LinkCursor) Backward() bool       { return c.backward }
+
+// Encodes the cursor into an opaque token for the clients
+func (c LinkCursor) String() string {
+	direction := "n"
+	if c.backward {
+		direction = "p"
+	}
+	raw := fmt.Sprintf(
+		"%s:%d:%d:%s",
+		cURSOR_VERSION, c.updatedAt.UnixMicro(), c.id, direction)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func NewLinkCursor(l Link, backward bool) LinkCursor {
+	return LinkCursor{
+		updatedAt: l.updatedAt.UTC(),
+		id:        l.id,
+		backward:  backward}
+}
+
+// Decodes the token made by `LinkCursor.String`
+func ParseLinkCursor(token string) (LinkCursor, error) {
+	malformed := oops.BadRequest{
+		Err: errors.New("malformed cursor"),
+		Msg: "Cursor is malformed"}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return LinkCursor{}, fmt.Errorf("domain<ParseLinkCursor>: %w", malformed)
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || parts[0] != cURSOR_VERSION {
+		return LinkCursor{}, fmt.Errorf("domain<ParseLinkCursor>: %w", malformed)
+	}
+
+	updatedAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return LinkCursor{}, fmt.Errorf("domain<ParseLinkCursor>: %w", malformed)
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return LinkCursor{}, fmt.Errorf("domain<ParseLinkCursor>: %w", malformed)
+	}
+
+	var backward bool
+	switch parts[3] {
+	case "n":
+	case "p":
+		backward = true
+	default:
+		return LinkCursor{}, fmt.Errorf("domain<ParseLinkCursor>: %w", malformed)
+	}
+
+	c := LinkCursor{
+		updatedAt: time.UnixMicro(updatedAt).UTC(),
+		id:        id,
+		backward:  backward}
+	return c, nil
+}
+
+// A slice of the links listing. Nil cursor means there's nothing to be listed
+// in that direction
+type LinkPage struct {
+	links []Link
+	next  *LinkCursor
+	prev  *LinkCursor
+	total *uint // Number of links matching the filter. Nil when not asked
+}
+
+func (p LinkPage) Links() []Link     { return p.links }
+func (p LinkPage) Next() *LinkCursor { return p.next }
+func (p LinkPage) Prev() *LinkCursor { return p.prev }
+func (p LinkPage) Total() *uint      { return p.total }
+
+func NewLinkPage(links []Link, next, prev *LinkCursor, total *uint) LinkPage {
+	return LinkPage{
+		links: links,
+		next:  next,
+		prev:  prev,
+		total: total}
+}
@@ -0,0 +1,62 @@
+package shortening
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+// HTTP status code used for redirecting visitors of a link
+type RedirectType int
+
+const (
+	RedirectMovedPermanently  RedirectType = http.StatusMovedPermanently
+	RedirectFound             RedirectType = http.StatusFound
+	RedirectTemporaryRedirect RedirectType = http.StatusTemporaryRedirect
+	RedirectPermanentRedirect RedirectType = http.StatusPermanentRedirect
+
+	DefaultRedirectType = RedirectTemporaryRedirect
+)
+
+func NewRedirectType(code int) (RedirectType, error) {
+	switch rt := RedirectType(code); rt {
+	case RedirectMovedPermanently,
+		RedirectFound,
+		RedirectTemporaryRedirect,
+		RedirectPermanentRedirect:
+		return rt, nil
+	}
+
+	err := oops.BadValues{
+		Err: errors.New("unknown redirect type"),
+		Msg: fmt.Sprintf(
+			"Redirect type should be one of %d, %d, %d, or %d (get: %d)",
+			RedirectMovedPermanently,
+			RedirectFound,
+			RedirectTemporaryRedirect,
+			RedirectPermanentRedirect,
+			code)}
+	return 0, fmt.Errorf("domain<NewRedirectType>: %w", err)
+}
+
+// Owner-wide settings, used as the defaults of their new links
+type Preference struct {
+	userId       uint64
+	redirectType RedirectType
+}
+
+func (p Preference) UserId() uint64             { return p.userId }
+func (p Preference) RedirectType() RedirectType { return p.redirectType }
+
+func NewPreference(userId uint64, redirectType RedirectType) Preference {
+	return Preference{
+		userId:       userId,
+		redirectType: redirectType}
+}
+
+// Used for owners who had never set their preference
+func NewDefaultPreference(userId uint64) Preference {
+	return NewPreference(userId, DefaultRedirectType)
+}
@@ -0,0 +1,72 @@
+package shortening
+
+import "time"
+
+// Values of a link that are tracked across its revisions
+type Snapshot struct {
+	alias       string
+	destination string
+	isOpen      bool
+}
+
+func (s Snapshot) Alias() string       { return s.alias }
+func (s Snapshot) Destination() string { return s.destination }
+func (s Snapshot) IsOpen() bool        { return s.isOpen }
+
+func NewSnapshot(alias, destination string, isOpen bool) Snapshot {
+	return Snapshot{
+		alias:       alias,
+		destination: destination,
+		isOpen:      isOpen}
+}
+
+func (l Link) Snapshot() Snapshot {
+	return NewSnapshot(l.alias, l.destination, l.isOpen)
+}
+
+// A recorded change of a link. Revisions are numbered per link, starting from
+// the one recording its creation, which has no previous values
+type Revision struct {
+	id        uint64
+	linkId    uint64
+	number    uint
+	editorId  *uint64 // Who made the change? Nil means it was made by the system
+	before    *Snapshot
+	after     Snapshot
+	createdAt time.Time
+}
+
+func (r Revision) Id() uint64           { return r.id }
+func (r Revision) LinkId() uint64       { return r.linkId }
+func (r Revision) Number() uint         { return r.number }
+func (r Revision) EditorId() *uint64    { return r.editorId }
+func (r Revision) After() Snapshot      { return r.after }
+func (r Revision) CreatedAt() time.Time { return r.createdAt }
+
+// Values of the link before the change. False for the revision recording
+// the creation of the link
+func (r Revision) Before() (Snapshot, bool) {
+	if r.before == nil {
+		return Snapshot{}, false
+	}
+	return *r.before, true
+}
+
+func NewRevision(
+	id uint64,
+	linkId uint64,
+	number uint,
+	editorId *uint64,
+	before *Snapshot,
+	after Snapshot,
+	createdAt time.Time,
+) Revision {
+	return Revision{
+		id:        id,
+		linkId:    linkId,
+		number:    number,
+		editorId:  editorId,
+		before:    before,
+		after:     after,
+		createdAt: createdAt}
+}
@@ -0,0 +1,181 @@
+package shortening
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+const (
+	rULE_MAX_COUNT  = 20 // How many rules could a link have?
+	rULE_MAX_VALUES = 20 // How many values could a rule match against?
+)
+
+// What a routing rule looks at when matching a visit
+type RuleCondition string
+
+const (
+	RuleConditionPlatform RuleCondition = "platform" // Platform named by the user-agent
+	RuleConditionLanguage RuleCondition = "language" // Most preferred language of `Accept-Language`
+	RuleConditionCountry  RuleCondition = "country"  // Country the visit came from, by IP
+)
+
+// Platforms recognized from the user-agent
+var RulePlatforms = []string{"ios", "android", "windows", "macos", "linux"}
+
+// Sends the visitors matching the condition to another destination. Rules of
+// a link are evaluated by their position, and the first match wins
+type Rule struct {
+	id          uint64
+	linkId      uint64
+	position    uint
+	condition   RuleCondition
+	values      []string
+	destination string
+}
+
+func (r Rule) Id() uint64               { return r.id }
+func (r Rule) LinkId() uint64           { return r.linkId }
+func (r Rule) Position() uint           { return r.position }
+func (r Rule) Condition() RuleCondition { return r.condition }
+func (r Rule) Destination() string      { return r.destination }
+func (r Rule) Values() []string {
+	values := make([]string, len(r.values))
+	copy(values, r.values)
+	return values
+}
+
+// Moves the rule to another position. Rules sharing the same position keep
+// the order they were made in
+func (r *Rule) MoveTo(position uint) {
+	r.position = position
+}
+
+// Checks whether another rule could be added to a link already having `count`
+// of them
+func CanAddRule(count uint) error {
+	if count >= rULE_MAX_COUNT {
+		err := oops.Forbidden{
+			Err: errors.New("too many rules"),
+			Msg: fmt.Sprintf("A link could only have %d rules at maximum", rULE_MAX_COUNT)}
+		return fmt.Errorf("domain<CanAddRule>: %w", err)
+	}
+	return nil
+}
+
+// Values are normalized for their condition: platforms and languages are
+// lowercased, while countries are uppercased ISO 3166-1 alpha-2 codes
+func NewRule(
+	id *uint64,
+	linkId uint64,
+	position uint,
+	condition string,
+	values []string,
+	destination string,
+) (Rule, error) {
+	var actualId uint64 = 0
+	if id != nil {
+		actualId = *id
+	}
+
+	switch RuleCondition(condition) {
+	case RuleConditionPlatform, RuleConditionLanguage, RuleConditionCountry:
+	default:
+		err := oops.BadValues{
+			Err: errors.New("unknown rule condition"),
+			Msg: fmt.Sprintf(
+				"Rule condition should be one of %s, %s, %s (get: %q)",
+				RuleConditionPlatform, RuleConditionLanguage, RuleConditionCountry,
+				condition)}
+		return Rule{}, fmt.Errorf("domain<NewRule>: %w", err)
+	}
+
+	actualValues := []string{}
+	for _, v := range values {
+		value, err := normalizeRuleValue(RuleCondition(condition), v)
+		if err != nil {
+			return Rule{}, fmt.Errorf("domain<NewRule>: %w", err)
+		}
+		if !slices.Contains(actualValues, value) {
+			actualValues = append(actualValues, value)
+		}
+	}
+	switch {
+	case len(actualValues) == 0 || len(actualValues) > rULE_MAX_VALUES:
+		err := oops.BadValues{
+			Err: errors.New("rule values out of range"),
+			Msg: fmt.Sprintf("Rule should match against 1 - %d values", rULE_MAX_VALUES)}
+		return Rule{}, fmt.Errorf("domain<NewRule>: %w", err)
+	case destination == "":
+		err := oops.BadValues{
+			Err: errors.New("rule without destination"),
+			Msg: "Rule should have a destination"}
+		return Rule{}, fmt.Errorf("domain<NewRule>: %w", err)
+	case len(destination) > dESTINATION_MAX_LEN:
+		err := oops.BadValues{
+			Err: errors.New("rule destination too long"),
+			Msg: fmt.Sprintf(
+				"Destination could only be %d chars long at maximum",
+				dESTINATION_MAX_LEN)}
+		return Rule{}, fmt.Errorf("domain<NewRule>: %w", err)
+	}
+
+	r := Rule{
+		id:          actualId,
+		linkId:      linkId,
+		position:    position,
+		condition:   RuleCondition(condition),
+		values:      actualValues,
+		destination: destination}
+	return r, nil
+}
+
+func normalizeRuleValue(condition RuleCondition, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch condition {
+	case RuleConditionPlatform:
+		value = strings.ToLower(value)
+		if !slices.Contains(RulePlatforms, value) {
+			err := oops.BadValues{
+				Err: errors.New("unknown platform"),
+				Msg: fmt.Sprintf(
+					"Platform should be one of %s (get: %q)",
+					strings.Join(RulePlatforms, ", "), value)}
+			return "", fmt.Errorf("domain<normalizeRuleValue>: %w", err)
+		}
+	case RuleConditionLanguage:
+		// Either a primary language (`en`) or along with its region (`en-us`)
+		value = strings.ToLower(value)
+		primary, region, hasRegion := strings.Cut(value, "-")
+		if !isLetters(primary, 2, 3) || (hasRegion && !isLetters(region, 2, 3)) {
+			err := oops.BadValues{
+				Err: errors.New("malformed language"),
+				Msg: fmt.Sprintf("Language should look like `en` or `en-US` (get: %q)", value)}
+			return "", fmt.Errorf("domain<normalizeRuleValue>: %w", err)
+		}
+	case RuleConditionCountry:
+		value = strings.ToUpper(value)
+		if !isLetters(value, 2, 2) {
+			err := oops.BadValues{
+				Err: errors.New("malformed country"),
+				Msg: fmt.Sprintf("Country should be an ISO 3166-1 alpha-2 code (get: %q)", value)}
+			return "", fmt.Errorf("domain<normalizeRuleValue>: %w", err)
+		}
+	}
+	return value, nil
+}
+
+func isLetters(s string, minLen, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
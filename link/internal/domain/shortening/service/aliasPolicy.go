@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+// Decides which aliases could be used by the users
+type AliasPolicy struct {
+	charset   string              // Which characters could be used in an alias?
+	minLen    int                 // How short an alias could be?
+	maxLen    int                 // How long an alias could be?
+	reserved  map[string]struct{} // Which aliases clash with our own routes? Compared case-insensitively
+	profanity []string            // Which words shouldn't appear anywhere in an alias? Compared case-insensitively
+}
+
+func NewAliasPolicy(
+	charset string,
+	minLen int,
+	maxLen int,
+	reserved []string,
+	profanity []string,
+) AliasPolicy {
+	actualReserved := map[string]struct{}{}
+	for _, r := range reserved {
+		if r = strings.ToLower(strings.TrimSpace(r)); r != "" {
+			actualReserved[r] = struct{}{}
+		}
+	}
+
+	actualProfanity := []string{}
+	for _, p := range profanity {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			actualProfanity = append(actualProfanity, p)
+		}
+	}
+
+	return AliasPolicy{
+		charset:   charset,
+		minLen:    minLen,
+		maxLen:    maxLen,
+		reserved:  actualReserved,
+		profanity: actualProfanity}
+}
+
+// Checks the alias against every rule, reporting the first one it violates
+func (ap AliasPolicy) Check(alias string) error {
+	var err error
+	lowered := strings.ToLower(alias)
+	invalidIdx := strings.IndexFunc(alias, func(r rune) bool {
+		return !strings.ContainsRune(ap.charset, r)
+	})
+	switch length := len(alias); {
+	case length < ap.minLen || length > ap.maxLen:
+		err = oops.BadValues{
+			Err: errors.New("alias length out of range"),
+			Msg: fmt.Sprintf("Alias should be %d - %d chars long", ap.minLen, ap.maxLen)}
+	case invalidIdx >= 0:
+		invalid, _ := utf8.DecodeRuneInString(alias[invalidIdx:])
+		err = oops.BadValues{
+			Err: errors.New("alias contains disallowed char"),
+			Msg: fmt.Sprintf(
+				"Alias could only contain these characters: %s (found: %q)",
+				ap.charset, invalid)}
+	default:
+		if _, ok := ap.reserved[lowered]; ok {
+			err = oops.BadValues{
+				Err: errors.New("alias is reserved"),
+				Msg: fmt.Sprintf("Alias `%s` is reserved", alias)}
+			break
+		}
+		for _, p := range ap.profanity {
+			if strings.Contains(lowered, p) {
+				err = oops.BadValues{
+					Err: errors.New("alias contains profanity"),
+					Msg: "Alias shouldn't contain inappropriate words"}
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("domain<AliasPolicy.Check>: %w", err)
+	}
+	return nil
+}
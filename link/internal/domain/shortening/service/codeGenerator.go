@@ -0,0 +1,157 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+
+	"github.com/solsteace/kochira/link/internal/domain/shortening/store"
+)
+
+const (
+	cODE_CHARSET = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	cODE_MAX_LEN = 15 // Bound by the `shortened` column
+
+	// How many attempts are made on a length before moving on to a longer
+	// one? Repeated collisions are taken as a sign of a crowded keyspace
+	cODE_ATTEMPTS_PER_LEN = 2
+)
+
+// Makes the short codes of new links. `attempt` counts the collisions met so
+// far for the same link, starting from 0
+type CodeGenerator interface {
+	Generate(destination string, attempt uint) (string, error)
+}
+
+// Names of the available strategies, see `NewCodeGenerator`
+const (
+	CodeStrategyRandom   = "random"
+	CodeStrategySequence = "sequence"
+	CodeStrategyHash     = "hash"
+)
+
+// Makes the generator of the strategy. Codes are `minLen` chars long and
+// grow up to `maxLen` as collisions pile up
+func NewCodeGenerator(
+	strategy string,
+	minLen uint,
+	maxLen uint,
+	sequence store.CodeSequence,
+) (CodeGenerator, error) {
+	if minLen < 1 || minLen > maxLen || maxLen > cODE_MAX_LEN {
+		return nil, fmt.Errorf(
+			"domain<NewCodeGenerator>: code length should be within 1 - %d, with min <= max (get: %d - %d)",
+			cODE_MAX_LEN, minLen, maxLen)
+	}
+
+	length := codeLength{minLen, maxLen}
+	switch strategy {
+	case CodeStrategyRandom, "":
+		return randomCode{length}, nil
+	case CodeStrategySequence:
+		if sequence == nil {
+			return nil, errors.New("domain<NewCodeGenerator>: sequence strategy needs a sequence")
+		}
+		return sequenceCode{length, sequence}, nil
+	case CodeStrategyHash:
+		return hashCode{length}, nil
+	}
+	return nil, fmt.Errorf("domain<NewCodeGenerator>: unknown strategy `%s`", strategy)
+}
+
+type codeLength struct {
+	min uint
+	max uint
+}
+
+func (cl codeLength) at(attempt uint) uint {
+	return min(cl.min+attempt/cODE_ATTEMPTS_PER_LEN, cl.max)
+}
+
+// Draws every char at random
+type randomCode struct {
+	length codeLength
+}
+
+func (rc randomCode) Generate(_ string, attempt uint) (string, error) {
+	code := make([]byte, rc.length.at(attempt))
+	charsetLen := big.NewInt(int64(len(cODE_CHARSET)))
+	for i := range code {
+		idx, err := rand.Int(rand.Reader, charsetLen)
+		if err != nil {
+			return "", fmt.Errorf("domain<randomCode.Generate>: %w", err)
+		}
+		code[i] = cODE_CHARSET[idx.Int64()]
+	}
+	return string(code), nil
+}
+
+// Encodes the next number of a sequence. The number is permuted first, so
+// consecutive links don't get guessable codes
+type sequenceCode struct {
+	length   codeLength
+	sequence store.CodeSequence
+}
+
+// Multiplier of the permutation. Being coprime to 62 keeps the permutation
+// one-to-one within each code length
+const cODE_PERMUTATION_MULTIPLIER = 25214903917
+
+func (sc sequenceCode) Generate(_ string, attempt uint) (string, error) {
+	n, err := sc.sequence.NextCodeSequence()
+	if err != nil {
+		return "", fmt.Errorf("domain<sequenceCode.Generate>: %w", err)
+	}
+
+	// The shortest length that still fits the number, so the codes only grow
+	// once the numbers had used up the shorter ones
+	length := sc.length.at(attempt)
+	space := pow62(length)
+	for n >= space && length < sc.length.max {
+		length++
+		space = pow62(length)
+	}
+
+	hi, lo := bits.Mul64(n%space, cODE_PERMUTATION_MULTIPLIER)
+	return encode62(bits.Rem64(hi, lo, space), length), nil
+}
+
+// Derives the code from the destination, so the same destination gets the
+// same code unless it collides
+type hashCode struct {
+	length codeLength
+}
+
+func (hc hashCode) Generate(destination string, attempt uint) (string, error) {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", attempt, destination)))
+	length := hc.length.at(attempt)
+	n := binary.BigEndian.Uint64(sum[:8])
+	return encode62(n%pow62(length), length), nil
+}
+
+// 62^n, saturated at the largest uint64
+func pow62(n uint) uint64 {
+	result := uint64(1)
+	for range n {
+		hi, lo := bits.Mul64(result, uint64(len(cODE_CHARSET)))
+		if hi != 0 {
+			return ^uint64(0)
+		}
+		result = lo
+	}
+	return result
+}
+
+// Writes `n` in base 62, left-padded to `length` chars
+func encode62(n uint64, length uint) string {
+	code := make([]byte, length)
+	for i := int(length) - 1; i >= 0; i-- {
+		code[i] = cODE_CHARSET[n%uint64(len(cODE_CHARSET))]
+		n /= uint64(len(cODE_CHARSET))
+	}
+	return string(code)
+}
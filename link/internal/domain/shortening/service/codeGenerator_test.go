@@ -0,0 +1,94 @@
+package service
+
+import "testing"
+
+type counterSequence struct {
+	next uint64
+}
+
+func (cs *counterSequence) NextCodeSequence() (uint64, error) {
+	n := cs.next
+	cs.next++
+	return n, nil
+}
+
+func TestSequenceCodeIsOneToOnePerLength(t *testing.T) {
+	for _, length := range []uint{1, 2} {
+		gen, err := NewCodeGenerator(CodeStrategySequence, length, length, &counterSequence{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		seen := map[string]bool{}
+		for range pow62(length) {
+			code, err := gen.Generate("", 0)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			} else if uint(len(code)) != length {
+				t.Fatalf("expected code of length %d, got `%s`", length, code)
+			} else if seen[code] {
+				t.Fatalf("expected unique codes of length %d, got `%s` twice", length, code)
+			}
+			seen[code] = true
+		}
+	}
+}
+
+func TestSequenceCodeGrowsOnceLengthIsUsedUp(t *testing.T) {
+	sequence := &counterSequence{next: pow62(2) - 1}
+	gen, err := NewCodeGenerator(CodeStrategySequence, 2, 3, sequence)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, expected := range []int{2, 3, 3} {
+		code, err := gen.Generate("", 0)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		} else if len(code) != expected {
+			t.Fatalf("expected code of length %d, got `%s`", expected, code)
+		}
+	}
+}
+
+func TestPow62(t *testing.T) {
+	cases := []struct {
+		name     string
+		n        uint
+		expected uint64
+	}{
+		{"zero", 0, 1},
+		{"one", 1, 62},
+		{"two", 2, 3844},
+		{"largest fitting", 10, 839299365868340224},
+		{"first overflowing", 11, ^uint64(0)},
+		{"longest code", cODE_MAX_LEN, ^uint64(0)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := pow62(c.n); got != c.expected {
+				t.Fatalf("expected %d, got %d", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestCodeGrowsWithAttempts(t *testing.T) {
+	expected := []int{6, 6, 7, 7, 8, 8, 8}
+	for _, strategy := range []string{CodeStrategyRandom, CodeStrategySequence, CodeStrategyHash} {
+		t.Run(strategy, func(t *testing.T) {
+			gen, err := NewCodeGenerator(strategy, 6, 8, &counterSequence{})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			for attempt, length := range expected {
+				code, err := gen.Generate("https://example.com", uint(attempt))
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				} else if len(code) != length {
+					t.Fatalf("expected code of length %d on attempt %d, got `%s`", length, attempt, code)
+				}
+			}
+		})
+	}
+}
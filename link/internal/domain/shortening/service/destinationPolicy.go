@@ -0,0 +1,182 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+// Tells whether the host had been blocked
+type HostBlocklist interface {
+	Blocks(host string) bool
+}
+
+// Decides which destinations the links could point to
+type DestinationPolicy struct {
+	schemes   []string // Which URL schemes are allowed? Compared case-insensitively
+	blocklist HostBlocklist
+	ownHosts  []string // Where our links are served from. Pointing there may create redirect loops
+
+	// Resolves the IPs of the destination host. Hosts that couldn't be
+	// resolved are rejected, as there's no telling where they point to
+	lookupIp func(host string) ([]net.IP, error)
+	isPublic func(addr netip.Addr) bool // Shared with the clients reaching the destinations
+}
+
+func NewDestinationPolicy(
+	schemes []string,
+	blocklist HostBlocklist,
+	ownHosts []string,
+	lookupIp func(host string) ([]net.IP, error),
+	isPublic func(addr netip.Addr) bool,
+) DestinationPolicy {
+	actualSchemes := []string{}
+	for _, s := range schemes {
+		actualSchemes = append(actualSchemes, strings.ToLower(s))
+	}
+	actualOwnHosts := []string{}
+	for _, h := range ownHosts {
+		if h = strings.ToLower(h); h != "" {
+			actualOwnHosts = append(actualOwnHosts, h)
+		}
+	}
+
+	return DestinationPolicy{
+		schemes:   actualSchemes,
+		blocklist: blocklist,
+		ownHosts:  actualOwnHosts,
+		lookupIp:  lookupIp,
+		isPublic:  isPublic}
+}
+
+// Checks the destination against every rule, reporting the first one it
+// violates
+func (dp DestinationPolicy) Check(destination string) error {
+	destinationUrl, err := url.Parse(destination)
+	if err != nil {
+		err := oops.BadValues{
+			Err: err,
+			Msg: "Destination should be a valid URL"}
+		return fmt.Errorf("domain<DestinationPolicy.Check>: %w", err)
+	}
+
+	scheme := strings.ToLower(destinationUrl.Scheme)
+	host := strings.ToLower(destinationUrl.Hostname())
+	switch {
+	case !slices.Contains(dp.schemes, scheme):
+		err = oops.BadValues{
+			Err: errors.New("scheme not allowed"),
+			Msg: fmt.Sprintf(
+				"Destination should use one of these schemes: %s (get: %q)",
+				strings.Join(dp.schemes, ", "), destinationUrl.Scheme)}
+	case host == "":
+		err = oops.BadValues{
+			Err: errors.New("missing host"),
+			Msg: "Destination should contain a host"}
+	case slices.Contains(dp.ownHosts, host):
+		err = oops.BadValues{
+			Err: errors.New("destination loops back"),
+			Msg: "Destination couldn't point to another shortened link, as it may create a redirect loop"}
+	case dp.blocklist.Blocks(host):
+		err = oops.BadValues{
+			Err: errors.New("host is blocked"),
+			Msg: fmt.Sprintf("Destination host `%s` had been blocked", host)}
+	default:
+		err = dp.checkAddresses(host)
+	}
+
+	if err != nil {
+		return fmt.Errorf("domain<DestinationPolicy.Check>: %w", err)
+	}
+	return nil
+}
+
+// How many destinations are checked at once by `CheckMany`?
+const cHECK_MANY_WORKERS = 16
+
+// Checks many destinations at once, reporting the outcome of each in the same
+// order. Hosts shared by the destinations are only resolved once
+func (dp DestinationPolicy) CheckMany(destinations []string) []error {
+	batch := dp
+	batch.lookupIp = memoizeLookup(dp.lookupIp)
+
+	errs := make([]error, len(destinations))
+	slots := make(chan struct{}, cHECK_MANY_WORKERS)
+	var wg sync.WaitGroup
+	for idx, d := range destinations {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			errs[idx] = batch.Check(d)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+type lookupResult struct {
+	once sync.Once
+	ips  []net.IP
+	err  error
+}
+
+// Remembers the outcome of each host, while concurrent lookups of the same
+// host wait on the first one
+func memoizeLookup(lookup func(host string) ([]net.IP, error)) func(host string) ([]net.IP, error) {
+	var mu sync.Mutex
+	results := map[string]*lookupResult{}
+	return func(host string) ([]net.IP, error) {
+		mu.Lock()
+		r, ok := results[host]
+		if !ok {
+			r = &lookupResult{}
+			results[host] = r
+		}
+		mu.Unlock()
+
+		r.once.Do(func() { r.ips, r.err = lookup(host) })
+		return r.ips, r.err
+	}
+}
+
+// Requires every address the host resolves to to be reachable publicly
+func (dp DestinationPolicy) checkAddresses(host string) error {
+	errPrivate := oops.BadValues{
+		Err: errors.New("host is private"),
+		Msg: "Destination couldn't point to private or local network addresses"}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errPrivate
+	}
+
+	ips := []net.IP{}
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		resolved, err := dp.lookupIp(host)
+		if err == nil && len(resolved) == 0 {
+			err = errors.New("host has no address")
+		}
+		if err != nil {
+			return oops.BadValues{
+				Err: err,
+				Msg: fmt.Sprintf("Destination host `%s` couldn't be resolved", host)}
+		}
+		ips = resolved
+	}
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok || !dp.isPublic(addr) {
+			return errPrivate
+		}
+	}
+	return nil
+}
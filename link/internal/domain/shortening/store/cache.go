@@ -0,0 +1,5 @@
+package store
+
+type RedirectCache interface {
+	Invalidate(alias ...string) error // Forgets cached redirections of given aliases
+}
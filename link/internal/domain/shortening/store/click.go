@@ -0,0 +1,7 @@
+package store
+
+import "github.com/solsteace/kochira/link/internal/domain/shortening"
+
+type Click interface {
+	GetClickCounts(linkId uint64, w shortening.ClickWindow) ([]shortening.ClickCount, error) // Retrieves rolled up clicks within the window
+}
@@ -0,0 +1,23 @@
+package store
+
+import (
+	"time"
+
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+	"github.com/solsteace/kochira/link/internal/domain/shortening/messaging"
+)
+
+type Health interface {
+	// Queries ============
+
+	GetDueForCheck(checkedBefore time.Time, limit uint) ([]shortening.Link, error) // Retrieves open links whose destination wasn't checked since given time, from the least recent
+
+	// Commands ===========
+
+	SaveHealth(l shortening.Link, unhealthy bool) error // Saves the health of the link, unless its destination had changed. Emits `destinationUnhealthy` message when `unhealthy`
+
+	// Events ===========
+
+	GetDestinationUnhealthy(limit uint) ([]messaging.DestinationUnhealthy, error) // Retrieves pending `destinationUnhealthy` messages
+	ResolveDestinationUnhealthy(id []uint64) error                                // Resolves pending `destinationUnhealthy` messages
+}
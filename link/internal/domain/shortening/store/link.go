@@ -1,6 +1,8 @@
 package store
 
 import (
+	"time"
+
 	"github.com/solsteace/kochira/link/internal/domain/shortening"
 	"github.com/solsteace/kochira/link/internal/domain/shortening/messaging"
 )
@@ -8,18 +10,31 @@ import (
 type Link[queryParams any] interface {
 	// Queries ============
 
-	GetMany(q queryParams) ([]shortening.Link, error) // Retrieves many links
-	GetManyByUser(userId uint64, q queryParams) ([]shortening.Link, error)
+	GetMany(q queryParams) ([]shortening.Link, error)                        // Retrieves many links
+	GetManyByUser(userId uint64, q queryParams) (shortening.LinkPage, error) // Retrieves a page of personal links owned by user
+	GetManyByWorkspace(workspaceId uint64, q queryParams) (shortening.LinkPage, error)
 	GetById(id uint64) (shortening.Link, error)
-	CountByUserIdExcept(userId uint64, linkId uint64) (shortening.Stats, error) // Retrieves the number of links owned by user, excluding certain link
+	CountByUserIdExcept(userId uint64, linkId uint64) (shortening.Stats, error) // Retrieves the number of personal links owned by user, excluding certain link
 	GetOpenedFromOldestByUser(userId uint64) ([]shortening.Link, error)
+	GetOpenedFromOldestByWorkspace(workspaceId uint64) ([]shortening.Link, error)
+	ExistsByAliasExcept(alias string, linkId uint64) (bool, error)                    // Checks whether other link had used the alias
+	EachByUser(userId uint64, each func(l shortening.Link) error) error               // Walks every link owned by user outside workspaces from the oldest, stopping at the first error
+	GetTrashByUser(userId uint64, roles []shortening.Role) ([]shortening.Link, error) // Retrieves links in the trash, owned by user or placed in workspaces where user has one of `roles`, from the latest deleted
 
 	// Commands ===========
 
-	Create(l shortening.Link) error                 // Creates Link and emits `linkShortened` message
-	UpdateWithSubscription(l shortening.Link) error // Emits `shortConfigured` message
-	Update(l shortening.Link) error                 // Updates link
-	DeleteById(id uint64) error                     // Deletes link
+	Create(l shortening.Link) error                                  // Creates Link and emits `linkShortened` message
+	CreateMany(l []shortening.Link) ([]uint64, error)                // Creates links at once and emits a single `bulkShortened` message
+	DeleteManyById(id []uint64) error                                // Deletes links at once
+	UpdateWithSubscription(l shortening.Link, editorId uint64) error // Emits `shortConfigured` message, recorded as made by `editorId` once approved
+	Update(l shortening.Link, editorId *uint64) error                // Updates link. Nil `editorId` means the system made the change
+	DeleteById(id uint64) error                                      // Deletes link
+	SaveDeletion(l shortening.Link, editorId *uint64) error          // Moves link in or out of the trash, following its `deletedAt` and `isOpen`
+	PurgeDeletedBefore(deletedBefore time.Time, limit uint) error    // Deletes up to `limit` links that had been in the trash since before given time
+
+	// Updates links at once, once `check` accepts the rest of their quota. The
+	// quota is held meanwhile
+	UpdateWithinQuota(l []shortening.Link, editorId *uint64, check func(stats shortening.Stats) error) error
 
 	// Events ===========
 
@@ -31,5 +46,9 @@ type Link[queryParams any] interface {
 	GetShortConfiguredById(id uint64) (messaging.ShortConfigured, error)
 	ResolveShortConfigured(id []uint64) error // Resolves pending `shortConfigured` messages
 
+	GetBulkShortened(limit uint) ([]messaging.BulkShortened, error) // Retrieves pending `bulkShortened` messages
+	GetBulkShortenedById(id uint64) (messaging.BulkShortened, error)
+	ResolveBulkShortened(id []uint64) error // Resolves pending `bulkShortened` messages
+
 	ApplySubscriptionExpiration(deactivatedLinks []uint64) error
 }
@@ -0,0 +1,15 @@
+package store
+
+import (
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+)
+
+type Metadata interface {
+	// Queries ============
+
+	GetUnfetchedMetadata(limit uint) ([]shortening.Link, error) // Retrieves links whose destination is yet to be fetched, from the oldest
+
+	// Commands ===========
+
+	SaveMetadata(l shortening.Link) error // Saves the metadata of the link, unless its destination had changed
+}
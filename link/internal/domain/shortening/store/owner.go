@@ -0,0 +1,7 @@
+package store
+
+import "github.com/solsteace/kochira/link/internal/domain/shortening"
+
+type Owner interface {
+	SaveOwners(o []shortening.Owner) error // Inserts the owners, replacing the known ones
+}
@@ -0,0 +1,8 @@
+package store
+
+import "github.com/solsteace/kochira/link/internal/domain/shortening"
+
+type Preference interface {
+	GetPreferenceByUserId(userId uint64) (shortening.Preference, error)
+	SavePreference(p shortening.Preference) error // Inserts or replaces the preference of the user
+}
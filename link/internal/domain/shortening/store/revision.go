@@ -0,0 +1,10 @@
+package store
+
+import "github.com/solsteace/kochira/link/internal/domain/shortening"
+
+// Revisions are recorded by the commands of `Link`, within the same
+// transaction as the change they record
+type Revision interface {
+	GetRevisionsByLinkId(linkId uint64) ([]shortening.Revision, error) // Retrieves the revisions of the link, from the latest
+	GetRevision(linkId uint64, number uint) (shortening.Revision, error)
+}
@@ -0,0 +1,12 @@
+package store
+
+import "github.com/solsteace/kochira/link/internal/domain/shortening"
+
+type Rule interface {
+	GetRulesByLinkId(linkId uint64) ([]shortening.Rule, error) // Retrieves the rules of the link, by their priority
+	GetRuleById(id uint64) (shortening.Rule, error)
+	CountRulesByLinkId(linkId uint64) (uint, error)
+	CreateRule(r shortening.Rule) (uint64, error)
+	UpdateRule(r shortening.Rule) error
+	DeleteRuleById(id uint64) error
+}
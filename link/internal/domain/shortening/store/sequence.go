@@ -0,0 +1,7 @@
+package store
+
+// Hands out increasing numbers for `service.CodeGenerator`. Numbers are never
+// handed out twice, even when the link they're used for isn't created
+type CodeSequence interface {
+	NextCodeSequence() (uint64, error)
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+	"github.com/solsteace/kochira/link/internal/domain/shortening/messaging"
+)
+
+type Transfer interface {
+	// Queries ============
+
+	GetUserIdByUsername(username string) (uint64, error)
+	GetTransferById(id uint64) (shortening.Transfer, error)
+	GetTransfersByUser(userId uint64) ([]shortening.Transfer, error) // Retrieves the transfers from and to the user, from the latest
+	ExistsOpenTransferByLinkId(linkId uint64) (bool, error)          // Checks whether the link is being transferred
+
+	// Commands ===========
+
+	CreateTransfer(t shortening.Transfer) (uint64, error)
+	UpdateTransfer(t shortening.Transfer) error
+	AcceptTransfer(t shortening.Transfer) error // Updates transfer and emits `transferAccepted` message
+
+	// Updates transfer along with the link changing hands, once `check`
+	// accepts the recipient's quota. The quota is held meanwhile
+	CompleteTransfer(t shortening.Transfer, l shortening.Link, check func(stats shortening.Stats) error) error
+
+	// Events ===========
+
+	GetTransferAccepted(limit uint) ([]messaging.TransferAccepted, error) // Retrieves pending `transferAccepted` messages
+	GetTransferAcceptedById(id uint64) (messaging.TransferAccepted, error)
+	ResolveTransferAccepted(id []uint64) error // Resolves pending `transferAccepted` messages
+}
@@ -0,0 +1,20 @@
+package store
+
+import "github.com/solsteace/kochira/link/internal/domain/shortening"
+
+type Workspace interface {
+	// Queries ============
+
+	GetUserIdByUsername(username string) (uint64, error)
+	GetWorkspaceById(id uint64) (shortening.Workspace, error)
+	GetWorkspacesByUser(userId uint64) ([]shortening.Workspace, error)       // Retrieves the workspaces the user is a member of
+	GetWorkspacesBySubscriber(userId uint64) ([]shortening.Workspace, error) // Retrieves the workspaces running on the user's subscription
+	GetMembers(workspaceId uint64) ([]shortening.Member, error)
+	GetMember(workspaceId uint64, userId uint64) (shortening.Member, error)
+
+	// Commands ===========
+
+	CreateWorkspace(w shortening.Workspace) (uint64, error) // Creates workspace along with its subscriber as an owner
+	SaveMember(m shortening.Member) error                   // Inserts or replaces the role of the member
+	DeleteMember(workspaceId uint64, userId uint64) error
+}
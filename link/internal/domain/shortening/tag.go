@@ -0,0 +1,94 @@
+package shortening
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+const (
+	tAG_MAX_LEN    = 31
+	tAG_MAX_COUNT  = 10
+	tAG_CHARSET    = "abcdefghijklmnopqrstuvwxyz0123456789-_"
+	fOLDER_MAX_LEN = 63
+)
+
+// Tags are compared case-insensitively, hence kept in lowercase
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "" || len(tag) > tAG_MAX_LEN:
+		err := oops.BadValues{
+			Err: errors.New("tag length out of range"),
+			Msg: fmt.Sprintf("Tag should be 1 - %d chars long", tAG_MAX_LEN)}
+		return "", fmt.Errorf("domain<normalizeTag>: %w", err)
+	case strings.IndexFunc(tag, func(r rune) bool { return !strings.ContainsRune(tAG_CHARSET, r) }) >= 0:
+		err := oops.BadValues{
+			Err: errors.New("tag contains disallowed char"),
+			Msg: fmt.Sprintf("Tag could only contain these characters: %s (get: %q)", tAG_CHARSET, tag)}
+		return "", fmt.Errorf("domain<normalizeTag>: %w", err)
+	}
+	return tag, nil
+}
+
+// Empty folder means the link isn't put in any folder
+func normalizeFolder(folder string) (string, error) {
+	folder = strings.TrimSpace(folder)
+	switch {
+	case len(folder) > fOLDER_MAX_LEN:
+		err := oops.BadValues{
+			Err: errors.New("folder too long"),
+			Msg: fmt.Sprintf("Folder could only be %d chars long at maximum", fOLDER_MAX_LEN)}
+		return "", fmt.Errorf("domain<normalizeFolder>: %w", err)
+	case strings.IndexFunc(folder, unicode.IsControl) >= 0:
+		err := oops.BadValues{
+			Err: errors.New("folder contains control char"),
+			Msg: "Folder shouldn't contain control characters"}
+		return "", fmt.Errorf("domain<normalizeFolder>: %w", err)
+	}
+	return folder, nil
+}
+
+// Replaces the tags of the link. Duplicates are dropped
+func (l *Link) Tag(tags []string) error {
+	actualTags := []string{}
+	for _, t := range tags {
+		tag, err := normalizeTag(t)
+		if err != nil {
+			return fmt.Errorf("domain<Link.Tag>: %w", err)
+		}
+		if !slices.Contains(actualTags, tag) {
+			actualTags = append(actualTags, tag)
+		}
+	}
+	if len(actualTags) > tAG_MAX_COUNT {
+		err := oops.BadValues{
+			Err: errors.New("too many tags"),
+			Msg: fmt.Sprintf("A link could only have %d tags at maximum", tAG_MAX_COUNT)}
+		return fmt.Errorf("domain<Link.Tag>: %w", err)
+	}
+
+	slices.Sort(actualTags)
+	l.tags = actualTags
+	return nil
+}
+
+func (l *Link) PutInFolder(folder string) error {
+	actualFolder, err := normalizeFolder(folder)
+	if err != nil {
+		return fmt.Errorf("domain<Link.PutInFolder>: %w", err)
+	}
+	l.folder = actualFolder
+	return nil
+}
+
+func (l Link) Tags() []string {
+	tags := make([]string, len(l.tags))
+	copy(tags, l.tags)
+	return tags
+}
+func (l Link) Folder() string { return l.folder }
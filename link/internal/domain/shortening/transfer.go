@@ -0,0 +1,160 @@
+package shortening
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+// Where a transfer is at. Only pending and accepted transfers are still open
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "pending"   // Waiting for the recipient
+	TransferAccepted  TransferStatus = "accepted"  // Waiting for the recipient's subscription check
+	TransferCompleted TransferStatus = "completed" // The recipient owns the link now
+	TransferRejected  TransferStatus = "rejected"  // The recipient's subscription couldn't take the link
+	TransferDeclined  TransferStatus = "declined"  // The recipient refused the link
+	TransferCancelled TransferStatus = "cancelled" // The owner called the transfer off
+)
+
+// Hands a link over from its owner to another user, which takes effect once
+// the recipient accepts it and their subscription could take the link
+type Transfer struct {
+	id         uint64
+	linkId     uint64
+	fromUserId uint64
+	toUserId   uint64
+	status     TransferStatus
+	createdAt  time.Time
+	updatedAt  time.Time
+}
+
+func (t Transfer) Id() uint64             { return t.id }
+func (t Transfer) LinkId() uint64         { return t.linkId }
+func (t Transfer) FromUserId() uint64     { return t.fromUserId }
+func (t Transfer) ToUserId() uint64       { return t.toUserId }
+func (t Transfer) Status() TransferStatus { return t.status }
+func (t Transfer) CreatedAt() time.Time   { return t.createdAt }
+func (t Transfer) UpdatedAt() time.Time   { return t.updatedAt }
+
+func (t Transfer) IsOpen() bool {
+	return t.status == TransferPending || t.status == TransferAccepted
+}
+
+// Could the user see the transfer?
+func (t Transfer) InvolvedBy(userId uint64) bool {
+	return t.fromUserId == userId || t.toUserId == userId
+}
+
+func NewTransfer(
+	id *uint64,
+	linkId uint64,
+	fromUserId uint64,
+	toUserId uint64,
+	status TransferStatus,
+	createdAt time.Time,
+	updatedAt time.Time,
+) Transfer {
+	var actualId uint64 = 0
+	if id != nil {
+		actualId = *id
+	}
+	return Transfer{
+		id:         actualId,
+		linkId:     linkId,
+		fromUserId: fromUserId,
+		toUserId:   toUserId,
+		status:     status,
+		createdAt:  createdAt,
+		updatedAt:  updatedAt}
+}
+
+// Starts handing the link over to another user
+func (l Link) TransferTo(toUserId uint64, now time.Time) (Transfer, error) {
+	switch {
+	case l.workspaceId != nil:
+		err := oops.Forbidden{
+			Err: errors.New("transfer of workspace link"),
+			Msg: "Links of a workspace are shared through their workspace instead"}
+		return Transfer{}, fmt.Errorf("domain<Link.TransferTo>: %w", err)
+	case toUserId == l.userId:
+		err := oops.BadValues{
+			Err: errors.New("transfer to owner"),
+			Msg: "Link couldn't be transferred to its own owner"}
+		return Transfer{}, fmt.Errorf("domain<Link.TransferTo>: %w", err)
+	}
+	return NewTransfer(nil, l.id, l.userId, toUserId, TransferPending, now, now), nil
+}
+
+// Moves the link to another owner. See `Transfer`
+func (l *Link) HandOver(t Transfer) error {
+	if t.linkId != l.id || t.fromUserId != l.userId {
+		err := oops.Forbidden{
+			Err: errors.New("stale transfer"),
+			Msg: "Link had changed hands since the transfer was started"}
+		return fmt.Errorf("domain<Link.HandOver>: %w", err)
+	}
+	l.userId = t.toUserId
+	return nil
+}
+
+func (t *Transfer) Accept(userId uint64, now time.Time) error {
+	if err := t.moveFrom(TransferPending, t.toUserId, userId, "accept"); err != nil {
+		return fmt.Errorf("domain<Transfer.Accept>: %w", err)
+	}
+	t.status = TransferAccepted
+	t.updatedAt = now
+	return nil
+}
+
+func (t *Transfer) Decline(userId uint64, now time.Time) error {
+	if err := t.moveFrom(TransferPending, t.toUserId, userId, "decline"); err != nil {
+		return fmt.Errorf("domain<Transfer.Decline>: %w", err)
+	}
+	t.status = TransferDeclined
+	t.updatedAt = now
+	return nil
+}
+
+func (t *Transfer) Cancel(userId uint64, now time.Time) error {
+	if err := t.moveFrom(TransferPending, t.fromUserId, userId, "cancel"); err != nil {
+		return fmt.Errorf("domain<Transfer.Cancel>: %w", err)
+	}
+	t.status = TransferCancelled
+	t.updatedAt = now
+	return nil
+}
+
+// Settles the accepted transfer with the outcome of the subscription check
+func (t *Transfer) Settle(approved bool, now time.Time) error {
+	if t.status != TransferAccepted {
+		err := oops.Forbidden{
+			Err: errors.New("transfer not accepted"),
+			Msg: fmt.Sprintf("Transfer couldn't be settled while %s", t.status)}
+		return fmt.Errorf("domain<Transfer.Settle>: %w", err)
+	}
+
+	t.status = TransferRejected
+	if approved {
+		t.status = TransferCompleted
+	}
+	t.updatedAt = now
+	return nil
+}
+
+func (t Transfer) moveFrom(status TransferStatus, actorId, userId uint64, action string) error {
+	switch {
+	case actorId != userId:
+		return oops.Forbidden{
+			Err: errors.New("not the transfer actor"),
+			Msg: fmt.Sprintf("You couldn't %s this transfer", action)}
+	case t.status != status:
+		return oops.Forbidden{
+			Err: errors.New("transfer status mismatch"),
+			Msg: fmt.Sprintf("Transfer is no longer %s (status: %s)", status, t.status)}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package shortening
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+func (l Link) DeletedAt() *time.Time { return l.deletedAt }
+func (l Link) IsDeleted() bool       { return l.deletedAt != nil }
+
+// Restores the deletion as stored. Use `Trash` and `Restore` for new ones
+func (l *Link) SetDeletedAt(deletedAt *time.Time) {
+	l.deletedAt = deletedAt
+}
+
+// Moves the link into the trash, where it stops redirecting while keeping
+// its alias until purged
+func (l *Link) Trash(now time.Time) error {
+	if l.deletedAt != nil {
+		err := oops.BadValues{
+			Err: errors.New("link is already in the trash"),
+			Msg: "This link is already in the trash"}
+		return fmt.Errorf("domain<Link.Trash>: %w", err)
+	}
+	l.deletedAt = &now
+	return nil
+}
+
+// Takes the link out of the trash closed, since whatever allowed it to be
+// open, like the quota or the subscription, might have lapsed meanwhile
+func (l *Link) Restore() error {
+	if l.deletedAt == nil {
+		err := oops.BadValues{
+			Err: errors.New("link isn't in the trash"),
+			Msg: "This link isn't in the trash"}
+		return fmt.Errorf("domain<Link.Restore>: %w", err)
+	}
+	l.deletedAt = nil
+	l.isOpen = false
+	return nil
+}
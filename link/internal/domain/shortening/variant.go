@@ -0,0 +1,98 @@
+package shortening
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+const (
+	vARIANT_MIN_COUNT  = 2
+	vARIANT_MAX_COUNT  = 10
+	vARIANT_MAX_WEIGHT = 1000
+)
+
+// One of the destinations the traffic of a link is split across. Weights are
+// relative to each other, so 70/30 and 7/3 split the same way
+type Variant struct {
+	id          uint64
+	destination string
+	weight      uint
+	hits        uint64 // How many visits were sent to this variant?
+}
+
+func (v Variant) Id() uint64          { return v.id }
+func (v Variant) Destination() string { return v.destination }
+func (v Variant) Weight() uint        { return v.weight }
+func (v Variant) Hits() uint64        { return v.hits }
+
+func NewVariant(id *uint64, destination string, weight uint, hits uint64) (Variant, error) {
+	var actualId uint64 = 0
+	if id != nil {
+		actualId = *id
+	}
+
+	switch {
+	case destination == "":
+		err := oops.BadValues{
+			Err: errors.New("variant without destination"),
+			Msg: "Variant should have a destination"}
+		return Variant{}, fmt.Errorf("domain<NewVariant>: %w", err)
+	case len(destination) > dESTINATION_MAX_LEN:
+		err := oops.BadValues{
+			Err: errors.New("variant destination too long"),
+			Msg: fmt.Sprintf(
+				"Destination could only be %d chars long at maximum",
+				dESTINATION_MAX_LEN)}
+		return Variant{}, fmt.Errorf("domain<NewVariant>: %w", err)
+	case weight < 1 || weight > vARIANT_MAX_WEIGHT:
+		err := oops.BadValues{
+			Err: errors.New("variant weight out of range"),
+			Msg: fmt.Sprintf("Variant weight should be 1 - %d", vARIANT_MAX_WEIGHT)}
+		return Variant{}, fmt.Errorf("domain<NewVariant>: %w", err)
+	}
+
+	v := Variant{
+		id:          actualId,
+		destination: destination,
+		weight:      weight,
+		hits:        hits}
+	return v, nil
+}
+
+// Spreads the visits across the variants by their weight, in place of the
+// destination. No variants sends every visit to the destination again
+func (l *Link) SplitTraffic(variants []Variant) error {
+	if len(variants) > 0 && (len(variants) < vARIANT_MIN_COUNT || len(variants) > vARIANT_MAX_COUNT) {
+		err := oops.BadValues{
+			Err: errors.New("variant count out of range"),
+			Msg: fmt.Sprintf(
+				"Traffic should be split across %d - %d variants",
+				vARIANT_MIN_COUNT, vARIANT_MAX_COUNT)}
+		return fmt.Errorf("domain<Link.SplitTraffic>: %w", err)
+	}
+
+	// Variants are told apart by their destination, so their hits could be
+	// kept across updates
+	seen := map[string]struct{}{}
+	for _, v := range variants {
+		if _, ok := seen[v.destination]; ok {
+			err := oops.BadValues{
+				Err: errors.New("duplicate variant"),
+				Msg: fmt.Sprintf("Variant destination `%s` is listed more than once", v.destination)}
+			return fmt.Errorf("domain<Link.SplitTraffic>: %w", err)
+		}
+		seen[v.destination] = struct{}{}
+	}
+
+	l.variants = make([]Variant, len(variants))
+	copy(l.variants, variants)
+	return nil
+}
+
+func (l Link) Variants() []Variant {
+	variants := make([]Variant, len(l.variants))
+	copy(variants, l.variants)
+	return variants
+}
@@ -0,0 +1,138 @@
+package shortening
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/solsteace/go-lib/oops"
+)
+
+const wORKSPACE_NAME_MAX_LEN = 63
+
+// What a member could do with the links of a workspace
+type Role string
+
+const (
+	RoleOwner  Role = "owner"  // Everything, including managing the members
+	RoleEditor Role = "editor" // Creates and changes the links
+	RoleViewer Role = "viewer" // Only sees the links
+)
+
+func NewRole(role string) (Role, error) {
+	switch Role(role) {
+	case RoleOwner, RoleEditor, RoleViewer:
+		return Role(role), nil
+	}
+	err := oops.BadValues{
+		Err: errors.New("unknown role"),
+		Msg: fmt.Sprintf(
+			"Role should be one of %s, %s, %s (get: %q)",
+			RoleOwner, RoleEditor, RoleViewer, role)}
+	return "", fmt.Errorf("domain<NewRole>: %w", err)
+}
+
+// Acts done on links, checked against the role of the actor
+type Permission uint
+
+const (
+	PermissionView   Permission = iota // Sees the link along with its stats and history
+	PermissionEdit                     // Changes the link and its routing
+	PermissionManage                   // Deletes the link and manages the workspace
+)
+
+func (r Role) Can(p Permission) bool {
+	switch r {
+	case RoleOwner:
+		return true
+	case RoleEditor:
+		return p <= PermissionEdit
+	case RoleViewer:
+		return p == PermissionView
+	}
+	return false
+}
+
+// Roles whose members could do `p`
+func RolesAllowedTo(p Permission) []Role {
+	roles := []Role{}
+	for _, r := range []Role{RoleOwner, RoleEditor, RoleViewer} {
+		if r.Can(p) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// Group of users sharing their links. The workspace runs on the subscription
+// of its subscriber, who is the owner that made it
+type Workspace struct {
+	id           uint64
+	name         string
+	subscriberId uint64
+	createdAt    time.Time
+}
+
+func (w Workspace) Id() uint64           { return w.id }
+func (w Workspace) Name() string         { return w.name }
+func (w Workspace) SubscriberId() uint64 { return w.subscriberId }
+func (w Workspace) CreatedAt() time.Time { return w.createdAt }
+
+func NewWorkspace(
+	id *uint64,
+	name string,
+	subscriberId uint64,
+	createdAt time.Time,
+) (Workspace, error) {
+	var actualId uint64 = 0
+	if id != nil {
+		actualId = *id
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > wORKSPACE_NAME_MAX_LEN {
+		err := oops.BadValues{
+			Err: errors.New("workspace name out of range"),
+			Msg: fmt.Sprintf(
+				"Workspace name should be 1 - %d chars long", wORKSPACE_NAME_MAX_LEN)}
+		return Workspace{}, fmt.Errorf("domain<NewWorkspace>: %w", err)
+	}
+
+	w := Workspace{
+		id:           actualId,
+		name:         name,
+		subscriberId: subscriberId,
+		createdAt:    createdAt}
+	return w, nil
+}
+
+// Checks whether the member could be given another role or be removed, which
+// is nil. The subscriber always stays as an owner, so the workspace keeps
+// its subscription
+func (w Workspace) CanChange(m Member, role *Role) error {
+	if m.userId == w.subscriberId && (role == nil || *role != RoleOwner) {
+		err := oops.Forbidden{
+			Err: errors.New("subscriber demoted"),
+			Msg: "The workspace runs on this member's subscription, hence they should stay as an owner"}
+		return fmt.Errorf("domain<Workspace.CanChange>: %w", err)
+	}
+	return nil
+}
+
+type Member struct {
+	workspaceId uint64
+	userId      uint64
+	role        Role
+}
+
+func (m Member) WorkspaceId() uint64 { return m.workspaceId }
+func (m Member) UserId() uint64      { return m.userId }
+func (m Member) Role() Role          { return m.role }
+
+func NewMember(workspaceId, userId uint64, role Role) Member {
+	return Member{
+		workspaceId: workspaceId,
+		userId:      userId,
+		role:        role}
+}
@@ -9,9 +9,10 @@ import (
 )
 
 type checkSubscriptionData struct {
-	CtxId   uint64 `json:"contextId"`
-	UserId  uint64 `json:"userId"`
-	Usecase string `json:"usecase"`
+	CtxId        uint64 `json:"contextId"`
+	UserId       uint64 `json:"userId"`
+	SubscriberId uint64 `json:"subscriberId"` // Whose subscription to check? Differs from the user's on workspaces
+	Usecase      string `json:"usecase"`
 }
 
 // Handles integration event for commanding subscription check
@@ -31,9 +32,10 @@ func (csm CheckSubscriptionMessenger) FromLinkShortened(
 			Version:  csm.Version,
 			IssuedAt: time.Now()},
 		Data: checkSubscriptionData{
-			CtxId:   msg.Id(),
-			UserId:  msg.UserId(),
-			Usecase: shorteningMsg.LinkShortenedName}}
+			CtxId:        msg.Id(),
+			UserId:       msg.UserId(),
+			SubscriberId: msg.SubscriberId(),
+			Usecase:      shorteningMsg.LinkShortenedName}}
 
 	marshalledPayload, err := json.Marshal(payload)
 	if err != nil {
@@ -55,9 +57,10 @@ func (csm CheckSubscriptionMessenger) FromShortConfigured(
 			Version:  csm.Version,
 			IssuedAt: time.Now()},
 		Data: checkSubscriptionData{
-			CtxId:   msg.Id(),
-			UserId:  msg.UserId(),
-			Usecase: shorteningMsg.ShortConfiguredName}}
+			CtxId:        msg.Id(),
+			UserId:       msg.UserId(),
+			SubscriberId: msg.SubscriberId(),
+			Usecase:      shorteningMsg.ShortConfiguredName}}
 
 	marshalledPayload, err := json.Marshal(payload)
 	if err != nil {
@@ -66,3 +69,54 @@ func (csm CheckSubscriptionMessenger) FromShortConfigured(
 	}
 	return marshalledPayload, nil
 }
+
+// Transforms `bulkShortened` event. The whole batch is checked at once
+func (csm CheckSubscriptionMessenger) FromBulkShortened(
+	msg shorteningMsg.BulkShortened,
+) ([]byte, error) {
+	payload := struct {
+		Meta meta                  `json:"meta"`
+		Data checkSubscriptionData `json:"data"`
+	}{
+		Meta: meta{
+			Version:  csm.Version,
+			IssuedAt: time.Now()},
+		Data: checkSubscriptionData{
+			CtxId:        msg.Id(),
+			UserId:       msg.UserId(),
+			SubscriberId: msg.SubscriberId(),
+			Usecase:      shorteningMsg.BulkShortenedName}}
+
+	marshalledPayload, err := json.Marshal(payload)
+	if err != nil {
+		return []byte{}, fmt.Errorf(
+			"messaging<CheckSubscriptionMessenger.FromBulkShortened>: %w", err)
+	}
+	return marshalledPayload, nil
+}
+
+// Transforms `transferAccepted` event. The recipient's subscription is the
+// one being checked
+func (csm CheckSubscriptionMessenger) FromTransferAccepted(
+	msg shorteningMsg.TransferAccepted,
+) ([]byte, error) {
+	payload := struct {
+		Meta meta                  `json:"meta"`
+		Data checkSubscriptionData `json:"data"`
+	}{
+		Meta: meta{
+			Version:  csm.Version,
+			IssuedAt: time.Now()},
+		Data: checkSubscriptionData{
+			CtxId:        msg.Id(),
+			UserId:       msg.UserId(),
+			SubscriberId: msg.UserId(),
+			Usecase:      shorteningMsg.TransferAcceptedName}}
+
+	marshalledPayload, err := json.Marshal(payload)
+	if err != nil {
+		return []byte{}, fmt.Errorf(
+			"messaging<CheckSubscriptionMessenger.FromTransferAccepted>: %w", err)
+	}
+	return marshalledPayload, nil
+}
@@ -0,0 +1,56 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	shorteningMsg "github.com/solsteace/kochira/link/internal/domain/shortening/messaging"
+)
+
+type destinationUnhealthyData struct {
+	Id            uint64    `json:"id"`            // What is the id of this message?
+	Event         string    `json:"event"`         // What happened?
+	LinkId        uint64    `json:"linkId"`        // Which link is broken?
+	UserId        uint64    `json:"userId"`        // Who owns the link?
+	WorkspaceId   *uint64   `json:"workspaceId"`   // Which workspace shares the link, if any?
+	Destination   string    `json:"destination"`   // Where does the link point to?
+	Status        *int      `json:"status"`        // What did the destination answer with? Null when unreachable
+	FailureStreak uint      `json:"failureStreak"` // How many checks in a row had failed?
+	CheckedAt     time.Time `json:"checkedAt"`     // When was the latest check?
+}
+
+// Handles integration event for broken destinations
+type DestinationUnhealthyMessenger struct {
+	Version uint
+}
+
+// Transforms `destinationUnhealthy` event
+func (dum DestinationUnhealthyMessenger) FromDestinationUnhealthy(
+	msg shorteningMsg.DestinationUnhealthy,
+) ([]byte, error) {
+	payload := struct {
+		Meta meta                     `json:"meta"`
+		Data destinationUnhealthyData `json:"data"`
+	}{
+		Meta: meta{
+			Version:  dum.Version,
+			IssuedAt: time.Now()},
+		Data: destinationUnhealthyData{
+			Id:            msg.Id(),
+			Event:         shorteningMsg.DestinationUnhealthyName,
+			LinkId:        msg.LinkId(),
+			UserId:        msg.UserId(),
+			WorkspaceId:   msg.WorkspaceId(),
+			Destination:   msg.Destination(),
+			Status:        msg.Status(),
+			FailureStreak: msg.FailureStreak(),
+			CheckedAt:     msg.CheckedAt()}}
+
+	marshalledPayload, err := json.Marshal(payload)
+	if err != nil {
+		return []byte{}, fmt.Errorf(
+			"messaging<DestinationUnhealthyMessenger.FromDestinationUnhealthy>: %w", err)
+	}
+	return marshalledPayload, nil
+}
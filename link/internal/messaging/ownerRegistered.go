@@ -0,0 +1,33 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type ownerRegisteredOwner struct {
+	Id       uint64 `json:"id"`
+	Username string `json:"username"`
+}
+
+type ownerRegisteredData struct {
+	Owners []ownerRegisteredOwner `json:"owners"` // Who had just registered?
+}
+
+type ownerRegisteredPayload struct {
+	Meta meta                `json:"meta"`
+	Data ownerRegisteredData `json:"data"`
+}
+
+type OwnerRegisteredMessenger struct {
+	Version uint
+}
+
+func (orm OwnerRegisteredMessenger) FromMsg(msg []byte) (*ownerRegisteredPayload, error) {
+	payload := new(ownerRegisteredPayload)
+	if err := json.Unmarshal(msg, &payload); err != nil {
+		return nil, fmt.Errorf(
+			"messaging<OwnerRegisteredMessenger.FromMsg>: %w", err)
+	}
+	return payload, nil
+}
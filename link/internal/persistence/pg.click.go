@@ -0,0 +1,173 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/solsteace/kochira/link/internal/domain/redirect"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+)
+
+type pgClick struct {
+	LinkId    uint64    `db:"link_id"`
+	ClickedAt time.Time `db:"clicked_at"`
+	Referrer  string    `db:"referrer"`
+	UserAgent string    `db:"user_agent"`
+	IpHash    string    `db:"ip_hash"`
+}
+
+func newPgClick(c redirect.Click) pgClick {
+	return pgClick{
+		LinkId:    c.LinkId,
+		ClickedAt: c.At,
+		Referrer:  c.Referrer,
+		UserAgent: c.UserAgent,
+		IpHash:    c.IpHash}
+}
+
+type pgClickRollup struct {
+	LinkId      uint64    `db:"link_id"`
+	Granularity string    `db:"granularity"`
+	Bucket      time.Time `db:"bucket"`
+	Clicks      uint64    `db:"clicks"`
+}
+
+func (row pgClickRollup) toClickCount() shortening.ClickCount {
+	return shortening.NewClickCount(row.Bucket, row.Clicks)
+}
+
+// Rolls clicks up per link for every granularity. Clicks are aggregated here
+// first as a single upsert couldn't touch the same row twice
+func newPgClickRollups(clicks []redirect.Click) []pgClickRollup {
+	type key struct {
+		linkId      uint64
+		granularity shortening.Granularity
+		bucket      time.Time
+	}
+
+	counts := map[key]uint64{}
+	order := []key{}
+	for _, c := range clicks {
+		for _, g := range []shortening.Granularity{
+			shortening.GranularityHour,
+			shortening.GranularityDay,
+		} {
+			k := key{c.LinkId, g, g.Bucket(c.At)}
+			if _, ok := counts[k]; !ok {
+				order = append(order, k)
+			}
+			counts[k]++
+		}
+	}
+
+	rows := []pgClickRollup{}
+	for _, k := range order {
+		rows = append(rows, pgClickRollup{
+			LinkId:      k.linkId,
+			Granularity: string(k.granularity),
+			Bucket:      k.bucket,
+			Clicks:      counts[k]})
+	}
+	return rows
+}
+
+func (repo pg) AddClicks(clicks []redirect.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.AddClicks>: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows := []pgClick{}
+	for _, c := range clicks {
+		rows = append(rows, newPgClick(c))
+	}
+	query := `
+		INSERT INTO link_clicks(
+			link_id,
+			clicked_at,
+			referrer,
+			user_agent,
+			ip_hash)
+		VALUES (
+			:link_id,
+			:clicked_at,
+			:referrer,
+			:user_agent,
+			:ip_hash)`
+	if _, err := tx.NamedExec(query, rows); err != nil {
+		return fmt.Errorf("persistence<pg.AddClicks>: %w", err)
+	}
+
+	rollupQuery := `
+		INSERT INTO link_click_rollups(
+			link_id,
+			granularity,
+			bucket,
+			clicks)
+		VALUES (
+			:link_id,
+			:granularity,
+			:bucket,
+			:clicks)
+		ON CONFLICT (link_id, granularity, bucket) DO UPDATE
+		SET clicks = link_click_rollups.clicks + EXCLUDED.clicks`
+	if _, err := tx.NamedExec(rollupQuery, newPgClickRollups(clicks)); err != nil {
+		return fmt.Errorf("persistence<pg.AddClicks>: %w", err)
+	}
+
+	// Hits are counted per variant, so the owner could compare them
+	hits := map[uint64]uint64{}
+	for _, c := range clicks {
+		if c.Variant != 0 {
+			hits[c.Variant]++
+		}
+	}
+	hitQuery := `UPDATE link_variants SET hits = hits + $2 WHERE id = $1`
+	for variantId, count := range hits {
+		if _, err := tx.Exec(hitQuery, variantId, count); err != nil {
+			return fmt.Errorf("persistence<pg.AddClicks>: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("persistence<pg.AddClicks>: %w", err)
+	}
+	return nil
+}
+
+func (repo pg) GetClickCounts(
+	linkId uint64,
+	w shortening.ClickWindow,
+) ([]shortening.ClickCount, error) {
+	query := `
+		SELECT
+			link_id,
+			granularity,
+			bucket,
+			clicks
+		FROM link_click_rollups
+		WHERE
+			link_id = $1
+			AND granularity = $2
+			AND bucket >= $3
+			AND bucket < $4
+		ORDER BY bucket`
+	args := []any{linkId, string(w.Granularity()), w.From(), w.To()}
+	rows := new([]pgClickRollup)
+	if err := repo.db.Select(rows, query, args...); err != nil {
+		return []shortening.ClickCount{}, fmt.Errorf("persistence<pg.GetClickCounts>: %w", err)
+	}
+
+	counts := []shortening.ClickCount{}
+	for _, r := range *rows {
+		counts = append(counts, r.toClickCount())
+	}
+	return counts, nil
+}
@@ -1,6 +1,11 @@
 package persistence
 
-import "github.com/jmoiron/sqlx"
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+)
 
 type pg struct {
 	db *sqlx.DB
@@ -9,3 +14,14 @@ type pg struct {
 func NewPgLink(db *sqlx.DB) pg {
 	return pg{db}
 }
+
+// Tells whether the error came from a link reusing the shortened or alias of
+// another link
+func pgIsShortenedTaken(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" { // unique_violation
+		return false
+	}
+	return pgErr.ConstraintName == "links_shortened_key" ||
+		pgErr.ConstraintName == "links_alias_key"
+}
@@ -0,0 +1,159 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+	"github.com/solsteace/kochira/link/internal/domain/shortening/messaging"
+)
+
+func (repo pg) GetDueForCheck(checkedBefore time.Time, limit uint) ([]shortening.Link, error) {
+	query := pgLinkSelect + `
+		WHERE
+			l.is_open
+			AND l.deleted_at IS NULL
+			AND l.expired_at > CURRENT_TIMESTAMP
+			AND (l.health_checked_at IS NULL OR l.health_checked_at < $1)
+		ORDER BY l.health_checked_at NULLS FIRST, l.id
+		LIMIT $2`
+	args := []any{checkedBefore, limit}
+	rows := new([]pgLink)
+	if err := repo.db.Select(rows, query, args...); err != nil {
+		return []shortening.Link{}, fmt.Errorf("persistence<pg.GetDueForCheck>: %w", err)
+	}
+
+	links := []shortening.Link{}
+	for _, r := range *rows {
+		link, err := r.toShortening()
+		if err != nil {
+			return []shortening.Link{}, fmt.Errorf("persistence<pg.GetDueForCheck>: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+func (repo pg) SaveHealth(l shortening.Link, unhealthy bool) error {
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.SaveHealth>: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The check is outdated once the destination had changed in the meantime
+	h := l.Health()
+	query := `
+		UPDATE "links"
+		SET
+			health_status = $1,
+			health_latency_ms = $2,
+			health_failure_streak = $3,
+			health_checked_at = $4
+		WHERE id = $5 AND destination = $6`
+	args := []any{
+		h.Status(), h.Latency().Milliseconds(), h.FailureStreak(), h.CheckedAt(),
+		l.Id(), l.Destination()}
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.SaveHealth>: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("persistence<pg.SaveHealth>: %w", err)
+	} else if affected == 0 {
+		return nil
+	}
+
+	if unhealthy {
+		outboxQuery := `
+			INSERT INTO destination_unhealthy_outbox(
+				link_id,
+				user_id,
+				workspace_id,
+				destination,
+				status,
+				failure_streak,
+				checked_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		outboxArgs := []any{
+			l.Id(), l.UserId(), l.WorkspaceId(), l.Destination(),
+			h.Status(), h.FailureStreak(), h.CheckedAt()}
+		if _, err := tx.Exec(outboxQuery, outboxArgs...); err != nil {
+			return fmt.Errorf("persistence<pg.SaveHealth>: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("persistence<pg.SaveHealth>: %w", err)
+	}
+	return nil
+}
+
+type pgDestinationUnhealthy struct {
+	Id            uint64    `db:"id"`
+	LinkId        uint64    `db:"link_id"`
+	UserId        uint64    `db:"user_id"`
+	WorkspaceId   *uint64   `db:"workspace_id"`
+	Destination   string    `db:"destination"`
+	Status        *int      `db:"status"`
+	FailureStreak uint      `db:"failure_streak"`
+	CheckedAt     time.Time `db:"checked_at"`
+}
+
+func (row pgDestinationUnhealthy) toMessage() messaging.DestinationUnhealthy {
+	return messaging.NewDestinationUnhealthy(
+		row.Id,
+		row.LinkId,
+		row.UserId,
+		row.WorkspaceId,
+		row.Destination,
+		row.Status,
+		row.FailureStreak,
+		row.CheckedAt)
+}
+
+func (repo pg) GetDestinationUnhealthy(maxCount uint) ([]messaging.DestinationUnhealthy, error) {
+	query := `
+		SELECT
+			id,
+			link_id,
+			user_id,
+			workspace_id,
+			destination,
+			status,
+			failure_streak,
+			checked_at
+		FROM destination_unhealthy_outbox
+		WHERE is_done = false
+		ORDER BY id
+		LIMIT $1`
+	args := []any{maxCount}
+	rows := new([]pgDestinationUnhealthy)
+	if err := repo.db.Select(rows, query, args...); err != nil {
+		return []messaging.DestinationUnhealthy{}, fmt.Errorf("persistence<pg.GetDestinationUnhealthy>: %w", err)
+	}
+
+	messages := []messaging.DestinationUnhealthy{}
+	for _, row := range *rows {
+		messages = append(messages, row.toMessage())
+	}
+	return messages, nil
+}
+
+func (repo pg) ResolveDestinationUnhealthy(id []uint64) error {
+	query, args, err := sqlx.In(`
+		UPDATE destination_unhealthy_outbox
+		SET is_done = true
+		WHERE id IN (?)`, id)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.ResolveDestinationUnhealthy>: %w", err)
+	}
+
+	if _, err := repo.db.Exec(repo.db.Rebind(query), args...); err != nil {
+		return fmt.Errorf("persistence<pg.ResolveDestinationUnhealthy>: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package persistence
+
+import (
+	"fmt"
+
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+)
+
+func (repo pg) GetUnfetchedMetadata(limit uint) ([]shortening.Link, error) {
+	query := pgLinkSelect + `
+		WHERE l.meta_fetched_at IS NULL AND l.deleted_at IS NULL
+		ORDER BY l.id
+		LIMIT $1`
+	args := []any{limit}
+	rows := new([]pgLink)
+	if err := repo.db.Select(rows, query, args...); err != nil {
+		return []shortening.Link{}, fmt.Errorf("persistence<pg.GetUnfetchedMetadata>: %w", err)
+	}
+
+	links := []shortening.Link{}
+	for _, r := range *rows {
+		link, err := r.toShortening()
+		if err != nil {
+			return []shortening.Link{}, fmt.Errorf("persistence<pg.GetUnfetchedMetadata>: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+func (repo pg) SaveMetadata(l shortening.Link) error {
+	// The metadata is outdated once the destination had changed in the meantime
+	m := l.Metadata()
+	query := `
+		UPDATE "links"
+		SET
+			meta_title = $1,
+			meta_og_title = $2,
+			meta_og_description = $3,
+			meta_og_image = $4,
+			meta_favicon = $5,
+			meta_fetched_at = $6
+		WHERE id = $7 AND destination = $8`
+	args := []any{
+		m.Title(), m.OgTitle(), m.OgDescription(), m.OgImage(), m.Favicon(), m.FetchedAt(),
+		l.Id(), l.Destination()}
+	if _, err := repo.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("persistence<pg.SaveMetadata>: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package persistence
+
+import (
+	"fmt"
+
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+)
+
+func (repo pg) SaveOwners(owners []shortening.Owner) error {
+	if len(owners) == 0 {
+		return nil
+	}
+
+	ids := []int64{}
+	usernames := []string{}
+	for _, o := range owners {
+		ids = append(ids, int64(o.Id()))
+		usernames = append(usernames, o.Username())
+	}
+	query := `
+		INSERT INTO link_owners(id, username)
+		SELECT * FROM UNNEST($1::BIGINT[], $2::VARCHAR[])
+		ON CONFLICT (id) DO UPDATE SET username = EXCLUDED.username`
+	args := []any{ids, usernames}
+	if _, err := repo.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("persistence<pg.SaveOwners>: %w", err)
+	}
+	return nil
+}
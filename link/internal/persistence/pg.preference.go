@@ -0,0 +1,61 @@
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+)
+
+type pgPreference struct {
+	UserId       uint64 `db:"user_id"`
+	RedirectType int    `db:"redirect_type"`
+}
+
+func (row pgPreference) toShortening() (shortening.Preference, error) {
+	redirectType, err := shortening.NewRedirectType(row.RedirectType)
+	if err != nil {
+		return shortening.Preference{}, fmt.Errorf("persistence<pgPreference.toShortening>: %w", err)
+	}
+	return shortening.NewPreference(row.UserId, redirectType), nil
+}
+
+func (repo pg) GetPreferenceByUserId(userId uint64) (shortening.Preference, error) {
+	row := new(pgPreference)
+	query := `SELECT * FROM link_preferences WHERE user_id = $1 LIMIT 1`
+	args := []any{userId}
+	if err := repo.db.Get(row, query, args...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err2 := oops.NotFound{
+				Err: err,
+				Msg: fmt.Sprintf("link_preferences(user_id:%d) not found", userId)}
+			return shortening.Preference{}, fmt.Errorf("persistence<pg.GetPreferenceByUserId>: %w", err2)
+		default:
+			return shortening.Preference{}, fmt.Errorf("persistence<pg.GetPreferenceByUserId>: %w", err)
+		}
+	}
+
+	p, err := row.toShortening()
+	if err != nil {
+		return shortening.Preference{}, fmt.Errorf("persistence<pg.GetPreferenceByUserId>: %w", err)
+	}
+	return p, nil
+}
+
+func (repo pg) SavePreference(p shortening.Preference) error {
+	row := pgPreference{
+		UserId:       p.UserId(),
+		RedirectType: int(p.RedirectType())}
+	query := `
+		INSERT INTO link_preferences(user_id, redirect_type)
+		VALUES (:user_id, :redirect_type)
+		ON CONFLICT (user_id) DO UPDATE
+		SET redirect_type = EXCLUDED.redirect_type`
+	if _, err := repo.db.NamedExec(query, row); err != nil {
+		return fmt.Errorf("persistence<pg.SavePreference>: %w", err)
+	}
+	return nil
+}
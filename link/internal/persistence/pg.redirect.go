@@ -5,22 +5,33 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/solsteace/go-lib/oops"
 	"github.com/solsteace/kochira/link/internal/domain/redirect"
 )
 
 func (row pgLink) toRedirect() redirect.Link {
 	return redirect.Link{
-		Id:          row.Id,
-		Shortened:   row.Shortened,
-		Destination: row.Destination,
-		IsOpen:      row.IsOpen,
-		ExpiredAt:   row.ExpiredAt}
+		Id:              row.Id,
+		UserId:          row.UserId,
+		Shortened:       row.Shortened,
+		Destination:     row.Destination,
+		IsOpen:          row.IsOpen,
+		ExpiredAt:       row.ExpiredAt,
+		Password:        row.Password,
+		RemainingClicks: row.RemainingClicks,
+		RedirectType:    row.RedirectType,
+		PassQuery:       row.PassQuery,
+		PassPath:        row.PassPath,
+		Preview:         row.Preview,
+		ActiveFrom:      row.ActiveFrom,
+		ActiveUntil:     row.ActiveUntil,
+		CreatedAt:       row.CreatedAt}
 }
 
 func (repo pg) GetByAlias(alias string) (redirect.Link, error) {
 	row := new(pgLink)
-	query := `SELECT * FROM "links" WHERE alias = $1 LIMIT 1`
+	query := `SELECT * FROM "links" WHERE alias = $1 AND deleted_at IS NULL LIMIT 1`
 	args := []any{alias}
 	if err := repo.db.Get(row, query, args...); err != nil {
 		l := redirect.Link{}
@@ -36,5 +47,124 @@ func (repo pg) GetByAlias(alias string) (redirect.Link, error) {
 		}
 	}
 
-	return row.toRedirect(), nil
+	ruleRows := new([]pgRule)
+	if err := repo.db.Select(ruleRows, pgRuleSelectByLinkId, row.Id); err != nil {
+		return redirect.Link{}, fmt.Errorf("persistence<pgLink.GetByAlias>: %w", err)
+	}
+
+	variantRows := new([]pgVariant)
+	variantQuery := `SELECT * FROM link_variants WHERE link_id = $1 ORDER BY id`
+	if err := repo.db.Select(variantRows, variantQuery, row.Id); err != nil {
+		return redirect.Link{}, fmt.Errorf("persistence<pgLink.GetByAlias>: %w", err)
+	}
+
+	link := row.toRedirect()
+	for _, r := range *ruleRows {
+		link.Rules = append(link.Rules, r.toRedirect())
+	}
+	for _, v := range *variantRows {
+		link.Variants = append(link.Variants, redirect.Variant{
+			Id:          v.Id,
+			Destination: v.Destination,
+			Weight:      v.Weight})
+	}
+	return link, nil
+}
+
+// There's no replica to lag behind, hence reads are always fresh
+func (repo pg) GetFreshByAlias(alias string) (redirect.Link, error) {
+	link, err := repo.GetByAlias(alias)
+	if err != nil {
+		return redirect.Link{}, fmt.Errorf("persistence<pgLink.GetFreshByAlias>: %w", err)
+	}
+	return link, nil
+}
+
+func (repo pg) ConsumeClick(id uint64) (bool, error) {
+	query := `
+		UPDATE "links"
+		SET remaining_clicks = remaining_clicks - 1
+		WHERE id = $1 AND remaining_clicks > 0`
+	args := []any{id}
+	result, err := repo.db.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("persistence<pg.ConsumeClick>: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("persistence<pg.ConsumeClick>: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (repo pg) GetUsernameById(userId uint64) (string, error) {
+	query := `SELECT username FROM link_owners WHERE id = $1 LIMIT 1`
+	args := []any{userId}
+	var username string
+	if err := repo.db.Get(&username, query, args...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", fmt.Errorf(
+				"persistence<pg.GetUsernameById>: %w",
+				oops.NotFound{
+					Err: err,
+					Msg: fmt.Sprintf("user(id:%d) not found", userId)})
+		default:
+			return "", fmt.Errorf("persistence<pg.GetUsernameById>: %w", err)
+		}
+	}
+	return username, nil
+}
+
+// Serves the redirection off `replica`, while the writes it makes still go to
+// `primary`. Both could point at the same database
+type pgRedirect struct {
+	replica pg
+	primary pg
+}
+
+func NewPgRedirect(replica *sqlx.DB, primary *sqlx.DB) pgRedirect {
+	return pgRedirect{
+		replica: pg{replica},
+		primary: pg{primary}}
+}
+
+func (repo pgRedirect) GetByAlias(alias string) (redirect.Link, error) {
+	link, err := repo.replica.GetByAlias(alias)
+	if err != nil {
+		return redirect.Link{}, fmt.Errorf("persistence<pgRedirect.GetByAlias>: %w", err)
+	}
+	return link, nil
+}
+
+func (repo pgRedirect) GetFreshByAlias(alias string) (redirect.Link, error) {
+	link, err := repo.primary.GetByAlias(alias)
+	if err != nil {
+		return redirect.Link{}, fmt.Errorf("persistence<pgRedirect.GetFreshByAlias>: %w", err)
+	}
+	return link, nil
+}
+
+func (repo pgRedirect) ConsumeClick(id uint64) (bool, error) {
+	ok, err := repo.primary.ConsumeClick(id)
+	if err != nil {
+		return false, fmt.Errorf("persistence<pgRedirect.ConsumeClick>: %w", err)
+	}
+	return ok, nil
+}
+
+func (repo pgRedirect) GetUsernameById(userId uint64) (string, error) {
+	username, err := repo.replica.GetUsernameById(userId)
+	if err != nil {
+		return "", fmt.Errorf("persistence<pgRedirect.GetUsernameById>: %w", err)
+	}
+	return username, nil
+}
+
+func (repo pgRedirect) AddClicks(clicks []redirect.Click) error {
+	if err := repo.primary.AddClicks(clicks); err != nil {
+		return fmt.Errorf("persistence<pgRedirect.AddClicks>: %w", err)
+	}
+	return nil
 }
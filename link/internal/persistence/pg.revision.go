@@ -0,0 +1,140 @@
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+)
+
+type pgRevision struct {
+	Id             uint64    `db:"id"`
+	LinkId         uint64    `db:"link_id"`
+	Number         uint      `db:"number"`
+	EditorId       *uint64   `db:"editor_id"`
+	OldAlias       *string   `db:"old_alias"`
+	OldDestination *string   `db:"old_destination"`
+	OldIsOpen      *bool     `db:"old_is_open"`
+	NewAlias       string    `db:"new_alias"`
+	NewDestination string    `db:"new_destination"`
+	NewIsOpen      bool      `db:"new_is_open"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+func (row pgRevision) toShortening() shortening.Revision {
+	var before *shortening.Snapshot
+	if row.OldAlias != nil && row.OldDestination != nil && row.OldIsOpen != nil {
+		snapshot := shortening.NewSnapshot(*row.OldAlias, *row.OldDestination, *row.OldIsOpen)
+		before = &snapshot
+	}
+	return shortening.NewRevision(
+		row.Id,
+		row.LinkId,
+		row.Number,
+		row.EditorId,
+		before,
+		shortening.NewSnapshot(row.NewAlias, row.NewDestination, row.NewIsOpen),
+		row.CreatedAt)
+}
+
+func (repo pg) GetRevisionsByLinkId(linkId uint64) ([]shortening.Revision, error) {
+	rows := new([]pgRevision)
+	query := `
+		SELECT *
+		FROM link_revisions
+		WHERE link_id = $1
+		ORDER BY number DESC`
+	if err := repo.db.Select(rows, query, linkId); err != nil {
+		return []shortening.Revision{}, fmt.Errorf("persistence<pg.GetRevisionsByLinkId>: %w", err)
+	}
+
+	revisions := []shortening.Revision{}
+	for _, row := range *rows {
+		revisions = append(revisions, row.toShortening())
+	}
+	return revisions, nil
+}
+
+func (repo pg) GetRevision(linkId uint64, number uint) (shortening.Revision, error) {
+	row := new(pgRevision)
+	query := `SELECT * FROM link_revisions WHERE link_id = $1 AND number = $2 LIMIT 1`
+	args := []any{linkId, number}
+	if err := repo.db.Get(row, query, args...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err2 := oops.NotFound{
+				Err: err,
+				Msg: fmt.Sprintf("link_revisions(link_id:%d, number:%d) not found", linkId, number)}
+			return shortening.Revision{}, fmt.Errorf("persistence<pg.GetRevision>: %w", err2)
+		default:
+			return shortening.Revision{}, fmt.Errorf("persistence<pg.GetRevision>: %w", err)
+		}
+	}
+	return row.toShortening(), nil
+}
+
+// Retrieves the tracked values of the link as stored, locking the link until
+// the transaction ends so its revisions are numbered one after another
+func pgLockLinkSnapshot(tx *sqlx.Tx, linkId uint64) (shortening.Snapshot, error) {
+	row := new(struct {
+		Alias       string `db:"alias"`
+		Destination string `db:"destination"`
+		IsOpen      bool   `db:"is_open"`
+	})
+	query := `
+		SELECT alias, destination, COALESCE(is_open, false) AS is_open
+		FROM "links"
+		WHERE id = $1
+		FOR UPDATE`
+	if err := tx.Get(row, query, linkId); err != nil {
+		return shortening.Snapshot{}, fmt.Errorf("persistence<pgLockLinkSnapshot>: %w", err)
+	}
+	return shortening.NewSnapshot(row.Alias, row.Destination, row.IsOpen), nil
+}
+
+// Records the change of the link, unless none of the tracked values changed.
+// Nil `before` records the creation of the link
+func pgRecordRevision(
+	tx *sqlx.Tx,
+	linkId uint64,
+	editorId *uint64,
+	before *shortening.Snapshot,
+	after shortening.Snapshot,
+) error {
+	if before != nil && *before == after {
+		return nil
+	}
+
+	var oldAlias, oldDestination *string
+	var oldIsOpen *bool
+	if before != nil {
+		alias, destination, isOpen := before.Alias(), before.Destination(), before.IsOpen()
+		oldAlias, oldDestination, oldIsOpen = &alias, &destination, &isOpen
+	}
+	query := `
+		INSERT INTO link_revisions(
+			link_id,
+			number,
+			editor_id,
+			old_alias,
+			old_destination,
+			old_is_open,
+			new_alias,
+			new_destination,
+			new_is_open)
+		SELECT $1, COALESCE(MAX(number), 0) + 1, $2, $3, $4, $5, $6, $7, $8
+		FROM link_revisions
+		WHERE link_id = $1`
+	args := []any{
+		linkId, editorId,
+		oldAlias, oldDestination, oldIsOpen,
+		after.Alias(), after.Destination(), after.IsOpen()}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("persistence<pgRecordRevision>: %w", err)
+	}
+	return nil
+}
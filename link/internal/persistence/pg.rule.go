@@ -0,0 +1,158 @@
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/kochira/link/internal/domain/redirect"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+)
+
+type pgRule struct {
+	Id          uint64 `db:"id"`
+	LinkId      uint64 `db:"link_id"`
+	Position    uint   `db:"position"`
+	Condition   string `db:"condition"`
+	Values      string `db:"match_values"` // Comma-separated, see `shortening.NewRule`
+	Destination string `db:"destination"`
+}
+
+func (row pgRule) toShortening() (shortening.Rule, error) {
+	r, err := shortening.NewRule(
+		&row.Id,
+		row.LinkId,
+		row.Position,
+		row.Condition,
+		strings.Split(row.Values, ","),
+		row.Destination)
+	if err != nil {
+		return shortening.Rule{}, fmt.Errorf("persistence<pgRule.toShortening>: %w", err)
+	}
+	return r, nil
+}
+
+func (row pgRule) toRedirect() redirect.Rule {
+	return redirect.Rule{
+		Condition:   row.Condition,
+		Values:      strings.Split(row.Values, ","),
+		Destination: row.Destination}
+}
+
+func newPgRule(r shortening.Rule) pgRule {
+	return pgRule{
+		Id:          r.Id(),
+		LinkId:      r.LinkId(),
+		Position:    r.Position(),
+		Condition:   string(r.Condition()),
+		Values:      strings.Join(r.Values(), ","),
+		Destination: r.Destination()}
+}
+
+const pgRuleSelectByLinkId = `
+	SELECT *
+	FROM link_rules
+	WHERE link_id = $1
+	ORDER BY position, id`
+
+func (repo pg) GetRulesByLinkId(linkId uint64) ([]shortening.Rule, error) {
+	rows := new([]pgRule)
+	if err := repo.db.Select(rows, pgRuleSelectByLinkId, linkId); err != nil {
+		return []shortening.Rule{}, fmt.Errorf("persistence<pg.GetRulesByLinkId>: %w", err)
+	}
+
+	rules := []shortening.Rule{}
+	for _, row := range *rows {
+		r, err := row.toShortening()
+		if err != nil {
+			return []shortening.Rule{}, fmt.Errorf("persistence<pg.GetRulesByLinkId>: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (repo pg) GetRuleById(id uint64) (shortening.Rule, error) {
+	row := new(pgRule)
+	query := `SELECT * FROM link_rules WHERE id = $1 LIMIT 1`
+	args := []any{id}
+	if err := repo.db.Get(row, query, args...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err2 := oops.NotFound{
+				Err: err,
+				Msg: fmt.Sprintf("link_rules(id:%d) not found", id)}
+			return shortening.Rule{}, fmt.Errorf("persistence<pg.GetRuleById>: %w", err2)
+		default:
+			return shortening.Rule{}, fmt.Errorf("persistence<pg.GetRuleById>: %w", err)
+		}
+	}
+
+	r, err := row.toShortening()
+	if err != nil {
+		return shortening.Rule{}, fmt.Errorf("persistence<pg.GetRuleById>: %w", err)
+	}
+	return r, nil
+}
+
+func (repo pg) CountRulesByLinkId(linkId uint64) (uint, error) {
+	var count uint
+	query := `SELECT COUNT(*) FROM link_rules WHERE link_id = $1`
+	if err := repo.db.Get(&count, query, linkId); err != nil {
+		return 0, fmt.Errorf("persistence<pg.CountRulesByLinkId>: %w", err)
+	}
+	return count, nil
+}
+
+func (repo pg) CreateRule(r shortening.Rule) (uint64, error) {
+	query := `
+		INSERT INTO link_rules(
+			link_id,
+			position,
+			condition,
+			match_values,
+			destination)
+		VALUES (
+			:link_id,
+			:position,
+			:condition,
+			:match_values,
+			:destination)
+		RETURNING id`
+	stmt, err := repo.db.PrepareNamed(query)
+	if err != nil {
+		return 0, fmt.Errorf("persistence<pg.CreateRule>: %w", err)
+	}
+	defer stmt.Close()
+
+	var id uint64
+	if err := stmt.Get(&id, newPgRule(r)); err != nil {
+		return 0, fmt.Errorf("persistence<pg.CreateRule>: %w", err)
+	}
+	return id, nil
+}
+
+func (repo pg) UpdateRule(r shortening.Rule) error {
+	query := `
+		UPDATE link_rules
+		SET
+			position = :position,
+			condition = :condition,
+			match_values = :match_values,
+			destination = :destination
+		WHERE id = :id`
+	if _, err := repo.db.NamedExec(query, newPgRule(r)); err != nil {
+		return fmt.Errorf("persistence<pg.UpdateRule>: %w", err)
+	}
+	return nil
+}
+
+func (repo pg) DeleteRuleById(id uint64) error {
+	query := `DELETE FROM link_rules WHERE id = $1`
+	if _, err := repo.db.Exec(query, id); err != nil {
+		return fmt.Errorf("persistence<pg.DeleteRuleById>: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package persistence
+
+import "fmt"
+
+func (repo pg) NextCodeSequence() (uint64, error) {
+	var n uint64
+	if err := repo.db.Get(&n, `SELECT nextval('link_code_seq')`); err != nil {
+		return 0, fmt.Errorf("persistence<pg.NextCodeSequence>: %w", err)
+	}
+	return n, nil
+}
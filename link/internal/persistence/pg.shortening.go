@@ -3,8 +3,11 @@ package persistence
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -14,8 +17,11 @@ import (
 )
 
 type ShorteningQueryParams struct {
-	page  uint
-	limit uint
+	page      uint
+	limit     uint
+	cursor    *shortening.LinkCursor // Takes over `page` when set
+	withTotal bool                   // Should the matching links be counted?
+	filter    shortening.LinkFilter  // Only applied by the paged queries, `getPage`
 }
 
 func (param ShorteningQueryParams) Offset() uint {
@@ -25,7 +31,13 @@ func (param ShorteningQueryParams) Offset() uint {
 	return (param.page - 1) * param.limit
 }
 
-func NewShorteningQueryParams(page, limit *uint) ShorteningQueryParams {
+func NewShorteningQueryParams(
+	page *uint,
+	limit *uint,
+	cursor *shortening.LinkCursor,
+	withTotal bool,
+	filter shortening.LinkFilter,
+) ShorteningQueryParams {
 	var actualPage uint = 1
 	if page != nil && *page > 0 {
 		actualPage = *page
@@ -38,22 +50,139 @@ func NewShorteningQueryParams(page, limit *uint) ShorteningQueryParams {
 
 	return ShorteningQueryParams{
 		actualPage,
-		actualLimit}
+		actualLimit,
+		cursor,
+		withTotal,
+		filter}
+}
+
+// Turns the filter into SQL, with its arguments numbered after `args`
+func (param ShorteningQueryParams) where(args []any) (string, []any) {
+	f := param.filter
+	conditions := []string{}
+	if tag := f.Tag(); tag != nil {
+		args = append(args, *tag)
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM link_tags AS t WHERE t.link_id = l.id AND t.tag = $%d)",
+			len(args)))
+	}
+	if folder := f.Folder(); folder != nil {
+		args = append(args, *folder)
+		conditions = append(conditions, fmt.Sprintf("l.folder = $%d", len(args)))
+	}
+	if isOpen := f.IsOpen(); isOpen != nil {
+		args = append(args, *isOpen)
+		conditions = append(conditions, fmt.Sprintf("l.is_open = $%d", len(args)))
+	}
+	if expired := f.Expired(); expired != nil {
+		if *expired {
+			conditions = append(conditions, "l.expired_at <= CURRENT_TIMESTAMP")
+		} else {
+			conditions = append(conditions, "l.expired_at > CURRENT_TIMESTAMP")
+		}
+	}
+	if search := f.Search(); search != "" {
+		escaper := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+		args = append(args, "%"+escaper.Replace(search)+"%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(l.destination ILIKE $%d OR l.alias ILIKE $%d)",
+			len(args), len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " AND " + strings.Join(conditions, " AND "), args
+}
+
+// Could the listing be walked with `shortening.LinkCursor`?
+func (param ShorteningQueryParams) keyset() bool {
+	sort := param.filter.Sort()
+	return sort == "" || sort == shortening.LinkSortUpdatedAt
+}
+
+// Orders the listing, or the other way around when `reversed`. `l.id` breaks
+// the ties so the order stays stable between pages
+func (param ShorteningQueryParams) orderBy(reversed bool) string {
+	direction := "DESC"
+	if param.filter.Descending() == reversed {
+		direction = "ASC"
+	}
+
+	column := "l.updated_at"
+	switch param.filter.Sort() {
+	case shortening.LinkSortExpiredAt:
+		column = "l.expired_at"
+	case shortening.LinkSortClicks:
+		// Summed from the covering index of the rollups, without reading the table
+		column = `(
+			SELECT COALESCE(SUM(r.clicks), 0)
+			FROM link_click_rollups AS r
+			WHERE r.link_id = l.id AND r.granularity = 'day')`
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, l.id %s", column, direction, direction)
+}
+
+// Narrows the listing down to the links past the cursor, with its arguments
+// numbered after `args`
+func (param ShorteningQueryParams) after(args []any) (string, []any) {
+	comparison := ">"
+	if param.filter.Descending() != param.cursor.Backward() {
+		comparison = "<"
+	}
+	args = append(args, param.cursor.UpdatedAt(), param.cursor.Id())
+	condition := fmt.Sprintf(
+		" AND (l.updated_at, l.id) %s ($%d, $%d)",
+		comparison, len(args)-1, len(args))
+	return condition, args
 }
 
 type pgLink struct {
-	Id          uint64    `db:"id"`
-	UserId      uint64    `db:"user_id"`
-	Shortened   string    `db:"shortened"`
-	Alias       string    `db:"alias"`
-	Destination string    `db:"destination"`
-	IsOpen      bool      `db:"is_open"`
-	UpdatedAt   time.Time `db:"updated_at"`
-	ExpiredAt   time.Time `db:"expired_at"`
+	Id              uint64     `db:"id"`
+	UserId          uint64     `db:"user_id"`
+	Shortened       string     `db:"shortened"`
+	Alias           string     `db:"alias"`
+	Destination     string     `db:"destination"`
+	IsOpen          bool       `db:"is_open"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	ExpiredAt       time.Time  `db:"expired_at"`
+	Password        string     `db:"password"`
+	MaxClicks       *uint      `db:"max_clicks"`
+	RemainingClicks *uint      `db:"remaining_clicks"`
+	RedirectType    int        `db:"redirect_type"`
+	PassQuery       bool       `db:"pass_query"`
+	PassPath        bool       `db:"pass_path"`
+	Preview         bool       `db:"preview"`
+	CreatedAt       time.Time  `db:"created_at"` // Set by the database
+	Folder          string     `db:"folder"`
+	ActiveFrom      *time.Time `db:"active_from"`
+	ActiveUntil     *time.Time `db:"active_until"`
+	Tags            string     `db:"tags"` // Comma-separated, see `pgLinkSelect`
+	WorkspaceId     *uint64    `db:"workspace_id"`
+	HealthStatus    *int       `db:"health_status"`
+	HealthLatencyMs int64      `db:"health_latency_ms"`
+	HealthFailures  uint       `db:"health_failure_streak"`
+	HealthCheckedAt *time.Time `db:"health_checked_at"`
+	MetaTitle       string     `db:"meta_title"`
+	MetaOgTitle     string     `db:"meta_og_title"`
+	MetaOgDesc      string     `db:"meta_og_description"`
+	MetaOgImage     string     `db:"meta_og_image"`
+	MetaFavicon     string     `db:"meta_favicon"`
+	MetaFetchedAt   *time.Time `db:"meta_fetched_at"`
+	DeletedAt       *time.Time `db:"deleted_at"`
+	Variants        string     `db:"variants"` // JSON array of `pgVariant`, see `pgLinkSelect`
+}
+
+type pgVariant struct {
+	Id          uint64 `db:"id" json:"id"`
+	LinkId      uint64 `db:"link_id" json:"-"`
+	Destination string `db:"destination" json:"destination"`
+	Weight      uint   `db:"weight" json:"weight"`
+	Hits        uint64 `db:"hits" json:"hits"`
 }
 
 func (row pgLink) toShortening() (shortening.Link, error) {
-	return shortening.NewLink(
+	link, err := shortening.NewLink(
 		&row.Id,
 		row.UserId,
 		row.Shortened,
@@ -62,23 +191,271 @@ func (row pgLink) toShortening() (shortening.Link, error) {
 		row.IsOpen,
 		row.UpdatedAt,
 		row.ExpiredAt)
+	if err != nil {
+		return shortening.Link{}, fmt.Errorf("persistence<pgLink.toShortening>: %w", err)
+	}
+
+	redirectType, err := shortening.NewRedirectType(row.RedirectType)
+	if err != nil {
+		return shortening.Link{}, fmt.Errorf("persistence<pgLink.toShortening>: %w", err)
+	}
+	link.SetRedirectType(redirectType)
+	link.SetPassthrough(row.PassQuery, row.PassPath)
+	link.SetPreview(row.Preview)
+	if err := link.PutInFolder(row.Folder); err != nil {
+		return shortening.Link{}, fmt.Errorf("persistence<pgLink.toShortening>: %w", err)
+	}
+	if row.Tags != "" {
+		if err := link.Tag(strings.Split(row.Tags, ",")); err != nil {
+			return shortening.Link{}, fmt.Errorf("persistence<pgLink.toShortening>: %w", err)
+		}
+	}
+	if err := link.Schedule(row.ActiveFrom, row.ActiveUntil); err != nil {
+		return shortening.Link{}, fmt.Errorf("persistence<pgLink.toShortening>: %w", err)
+	}
+	if row.Variants != "" {
+		variantRows := []pgVariant{}
+		if err := json.Unmarshal([]byte(row.Variants), &variantRows); err != nil {
+			return shortening.Link{}, fmt.Errorf("persistence<pgLink.toShortening>: %w", err)
+		}
+
+		variants := []shortening.Variant{}
+		for _, v := range variantRows {
+			variant, err := shortening.NewVariant(&v.Id, v.Destination, v.Weight, v.Hits)
+			if err != nil {
+				return shortening.Link{}, fmt.Errorf("persistence<pgLink.toShortening>: %w", err)
+			}
+			variants = append(variants, variant)
+		}
+		if err := link.SplitTraffic(variants); err != nil {
+			return shortening.Link{}, fmt.Errorf("persistence<pgLink.toShortening>: %w", err)
+		}
+	}
+	if row.Password != "" {
+		link.Protect(row.Password)
+	}
+	if row.WorkspaceId != nil {
+		link.PlaceIn(*row.WorkspaceId)
+	}
+	link.SetHealth(shortening.NewHealth(
+		row.HealthStatus,
+		time.Duration(row.HealthLatencyMs)*time.Millisecond,
+		row.HealthFailures,
+		row.HealthCheckedAt))
+	link.SetMetadata(shortening.NewMetadata(
+		row.MetaTitle,
+		row.MetaOgTitle,
+		row.MetaOgDesc,
+		row.MetaOgImage,
+		row.MetaFavicon,
+		row.MetaFetchedAt))
+	link.SetDeletedAt(row.DeletedAt)
+	if row.MaxClicks != nil && row.RemainingClicks != nil {
+		err := link.LimitClicks(*row.MaxClicks, *row.RemainingClicks)
+		if err != nil {
+			return shortening.Link{}, fmt.Errorf("persistence<pgLink.toShortening>: %w", err)
+		}
+	}
+	return link, nil
 }
 
 func newPgLink(l shortening.Link) pgLink {
 	return pgLink{
-		Id:          l.Id(),
-		UserId:      l.UserId(),
-		Shortened:   l.Shortened(),
-		Alias:       l.Alias(),
-		Destination: l.Destination(),
-		IsOpen:      l.IsOpen(),
-		UpdatedAt:   l.UpdatedAt(),
-		ExpiredAt:   l.ExpiredAt()}
+		Id:              l.Id(),
+		UserId:          l.UserId(),
+		Shortened:       l.Shortened(),
+		Alias:           l.Alias(),
+		Destination:     l.Destination(),
+		IsOpen:          l.IsOpen(),
+		UpdatedAt:       l.UpdatedAt(),
+		ExpiredAt:       l.ExpiredAt(),
+		Password:        l.Password(),
+		MaxClicks:       l.MaxClicks(),
+		RemainingClicks: l.RemainingClicks(),
+		RedirectType:    int(l.RedirectType()),
+		PassQuery:       l.PassesQuery(),
+		PassPath:        l.PassesPath(),
+		Preview:         l.RequiresPreview(),
+		Folder:          l.Folder(),
+		ActiveFrom:      l.ActiveFrom(),
+		ActiveUntil:     l.ActiveUntil(),
+		WorkspaceId:     l.WorkspaceId(),
+		Tags:            strings.Join(l.Tags(), ",")}
+}
+
+// Selects links along with their tags and variants. Tags never contain
+// commas, see `shortening.Link.Tag`
+const pgLinkSelect = `
+	SELECT
+		l.*,
+		COALESCE((
+			SELECT string_agg(t.tag, ',' ORDER BY t.tag)
+			FROM link_tags AS t
+			WHERE t.link_id = l.id), '') AS tags,
+		COALESCE((
+			SELECT json_agg(json_build_object(
+				'id', v.id,
+				'destination', v.destination,
+				'weight', v.weight,
+				'hits', v.hits) ORDER BY v.id)
+			FROM link_variants AS v
+			WHERE v.link_id = l.id)::TEXT, '') AS variants
+	FROM "links" AS l`
+
+// Saves what's kept outside of the `links` table
+func pgSaveLinkRelations(tx *sqlx.Tx, linkId uint64, l shortening.Link) error {
+	if err := pgSaveLinkTags(tx, linkId, l.Tags()); err != nil {
+		return fmt.Errorf("persistence<pgSaveLinkRelations>: %w", err)
+	}
+	if err := pgSaveLinkVariants(tx, linkId, l.Variants()); err != nil {
+		return fmt.Errorf("persistence<pgSaveLinkRelations>: %w", err)
+	}
+	return nil
 }
 
+// Replaces the variants of the link. Variants keeping their destination keep
+// their hits too
+func pgSaveLinkVariants(tx *sqlx.Tx, linkId uint64, variants []shortening.Variant) error {
+	destinations := []string{}
+	for _, v := range variants {
+		destinations = append(destinations, v.Destination())
+	}
+	deleteQuery := `
+		DELETE FROM link_variants
+		WHERE link_id = $1 AND destination <> ALL($2::VARCHAR[])`
+	if _, err := tx.Exec(deleteQuery, linkId, destinations); err != nil {
+		return fmt.Errorf("persistence<pgSaveLinkVariants>: %w", err)
+	}
+
+	upsertQuery := `
+		INSERT INTO link_variants(link_id, destination, weight)
+		VALUES (:link_id, :destination, :weight)
+		ON CONFLICT (link_id, destination) DO UPDATE
+		SET weight = EXCLUDED.weight`
+	for _, v := range variants {
+		row := pgVariant{
+			LinkId:      linkId,
+			Destination: v.Destination(),
+			Weight:      v.Weight()}
+		if _, err := tx.NamedExec(upsertQuery, row); err != nil {
+			return fmt.Errorf("persistence<pgSaveLinkVariants>: %w", err)
+		}
+	}
+	return nil
+}
+
+// Replaces the tags of the link
+func pgSaveLinkTags(tx *sqlx.Tx, linkId uint64, tags []string) error {
+	deleteQuery := `DELETE FROM link_tags WHERE link_id = $1`
+	if _, err := tx.Exec(deleteQuery, linkId); err != nil {
+		return fmt.Errorf("persistence<pgSaveLinkTags>: %w", err)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	insertQuery := `
+		INSERT INTO link_tags(link_id, tag)
+		SELECT $1, UNNEST($2::VARCHAR[])`
+	if _, err := tx.Exec(insertQuery, linkId, tags); err != nil {
+		return fmt.Errorf("persistence<pgSaveLinkTags>: %w", err)
+	}
+	return nil
+}
+
+// Columns of "links" that could be changed without subscription check.
+//
+// The remaining clicks are only written when the click limit was changed, so
+// clicks consumed in the meantime by redirections wouldn't be given back
+const pgLinkSettingsSet = `
+	password = :password,
+	remaining_clicks = CASE
+		WHEN max_clicks IS DISTINCT FROM :max_clicks THEN :remaining_clicks
+		ELSE remaining_clicks
+	END,
+	max_clicks = :max_clicks,
+	redirect_type = :redirect_type,
+	pass_query = :pass_query,
+	pass_path = :pass_path,
+	preview = :preview,
+	folder = :folder,
+	active_from = :active_from,
+	active_until = :active_until`
+
+// Columns of "links" describing the health of the destination, which are
+// reset when the destination is changed. See `shortening.Health`
+const pgLinkHealthReset = `
+	health_status = CASE WHEN destination = :destination THEN health_status END,
+	health_latency_ms = CASE WHEN destination = :destination THEN health_latency_ms ELSE 0 END,
+	health_failure_streak = CASE WHEN destination = :destination THEN health_failure_streak ELSE 0 END,
+	health_checked_at = CASE WHEN destination = :destination THEN health_checked_at END`
+
+// Columns of "links" holding what the destination page says about itself,
+// which are reset when the destination is changed. See `shortening.Metadata`
+const pgLinkMetadataReset = `
+	meta_title = CASE WHEN destination = :destination THEN meta_title ELSE '' END,
+	meta_og_title = CASE WHEN destination = :destination THEN meta_og_title ELSE '' END,
+	meta_og_description = CASE WHEN destination = :destination THEN meta_og_description ELSE '' END,
+	meta_og_image = CASE WHEN destination = :destination THEN meta_og_image ELSE '' END,
+	meta_favicon = CASE WHEN destination = :destination THEN meta_favicon ELSE '' END,
+	meta_fetched_at = CASE WHEN destination = :destination THEN meta_fetched_at END`
+
+const pgLinkInsert = `
+	INSERT INTO "links"(
+		user_id,
+		shortened,
+		alias,
+		destination,
+		is_open,
+		updated_at,
+		expired_at,
+		max_clicks,
+		remaining_clicks,
+		redirect_type,
+		pass_query,
+		pass_path,
+		preview,
+		folder,
+		active_from,
+		active_until,
+		workspace_id)
+	VALUES (
+		:user_id, 
+		:shortened, 
+		:alias,
+		:destination, 
+		:is_open, 
+		:updated_at, 
+		:expired_at,
+		:max_clicks,
+		:remaining_clicks,
+		:redirect_type,
+		:pass_query,
+		:pass_path,
+		:preview,
+		:folder,
+		:active_from,
+		:active_until,
+		:workspace_id)
+	RETURNING id`
+
+const pgLinkUpdate = `
+	UPDATE "links"
+	SET 
+		alias = :alias,
+		destination = :destination,
+		is_open = :is_open,
+		updated_at = :updated_at,
+		expired_at = :expired_at,` + pgLinkSettingsSet + `,` + pgLinkHealthReset + `,` + pgLinkMetadataReset + `
+	WHERE
+		id = :id`
+
 func (repo pg) GetMany(q ShorteningQueryParams) ([]shortening.Link, error) {
 	rows := new([]pgLink)
-	query := `SELECT * FROM "links" LIMIT $1 OFFSET $2`
+	query := pgLinkSelect + `
+		WHERE l.deleted_at IS NULL
+		ORDER BY l.updated_at DESC, l.id DESC
+		LIMIT $1 OFFSET $2`
 	args := []any{q.limit, q.Offset()}
 	if err := repo.db.Select(rows, query, args...); err != nil {
 		return []shortening.Link{}, fmt.Errorf("persistence<pg.GetMany>: %w", err)
@@ -95,28 +472,93 @@ func (repo pg) GetMany(q ShorteningQueryParams) ([]shortening.Link, error) {
 	return links, nil
 }
 
-func (repo pg) GetManyByUser(userId uint64, q ShorteningQueryParams) ([]shortening.Link, error) {
+// Lists the personal links of the user, leaving out the ones of workspaces
+func (repo pg) GetManyByUser(userId uint64, q ShorteningQueryParams) (shortening.LinkPage, error) {
+	page, err := repo.getPage(`l.user_id = $1 AND l.workspace_id IS NULL`, userId, q)
+	if err != nil {
+		return shortening.LinkPage{}, fmt.Errorf("persistence<pg.GetManyByUser>: %w", err)
+	}
+	return page, nil
+}
+
+func (repo pg) GetManyByWorkspace(workspaceId uint64, q ShorteningQueryParams) (shortening.LinkPage, error) {
+	page, err := repo.getPage(`l.workspace_id = $1`, workspaceId, q)
+	if err != nil {
+		return shortening.LinkPage{}, fmt.Errorf("persistence<pg.GetManyByWorkspace>: %w", err)
+	}
+	return page, nil
+}
+
+// Lists a page of the links matching `scope`, which refers to `scopeArg` as $1.
+// Links in the trash are left out
+func (repo pg) getPage(scope string, scopeArg any, q ShorteningQueryParams) (shortening.LinkPage, error) {
+	where, args := q.where([]any{scopeArg})
+	where = ` WHERE l.deleted_at IS NULL AND ` + scope + where
+
+	var total *uint
+	if q.withTotal {
+		count := new(uint)
+		countQuery := `SELECT COUNT(*) FROM "links" AS l` + where
+		if err := repo.db.Get(count, countQuery, args...); err != nil {
+			return shortening.LinkPage{}, fmt.Errorf("persistence<pg.getPage>: %w", err)
+		}
+		total = count
+	}
+
+	// An extra row is fetched to tell whether the listing goes on past the page
+	query := pgLinkSelect + where
+	backward := q.cursor != nil && q.cursor.Backward()
+	if q.cursor != nil {
+		var after string
+		after, args = q.after(args)
+		args = append(args, q.limit+1)
+		query += after + q.orderBy(backward) + fmt.Sprintf(` LIMIT $%d`, len(args))
+	} else {
+		args = append(args, q.limit+1, q.Offset())
+		query += q.orderBy(false) +
+			fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+	}
+
 	rows := new([]pgLink)
-	query := `SELECT * FROM "links" WHERE user_id = $1 LIMIT $2 OFFSET $3`
-	args := []any{userId, q.limit, q.Offset()}
 	if err := repo.db.Select(rows, query, args...); err != nil {
-		return []shortening.Link{}, fmt.Errorf("persistence<pg.GetManyByUser>: %w", err)
+		return shortening.LinkPage{}, fmt.Errorf("persistence<pg.getPage>: %w", err)
 	}
 
 	links := []shortening.Link{}
 	for _, r := range *rows {
 		link, err := r.toShortening()
 		if err != nil {
-			return []shortening.Link{}, fmt.Errorf("persistence<pg.GetManyByUser>: %w", err)
+			return shortening.LinkPage{}, fmt.Errorf("persistence<pg.getPage>: %w", err)
 		}
 		links = append(links, link)
 	}
-	return links, nil
+
+	hasMore := uint(len(links)) > q.limit
+	if hasMore {
+		links = links[:q.limit]
+	}
+	if backward {
+		slices.Reverse(links)
+	}
+
+	// Offset pages hand out cursors too, so the clients could switch over
+	var next, prev *shortening.LinkCursor
+	if q.keyset() && len(links) > 0 {
+		if hasMore || backward {
+			c := shortening.NewLinkCursor(links[len(links)-1], false)
+			next = &c
+		}
+		if (backward && hasMore) || (!backward && (q.cursor != nil || q.Offset() > 0)) {
+			c := shortening.NewLinkCursor(links[0], true)
+			prev = &c
+		}
+	}
+	return shortening.NewLinkPage(links, next, prev, total), nil
 }
 
 func (repo pg) GetById(id uint64) (shortening.Link, error) {
 	row := new(pgLink)
-	query := `SELECT * FROM links WHERE id = $1 LIMIT 1`
+	query := pgLinkSelect + ` WHERE l.id = $1 LIMIT 1`
 	args := []any{id}
 	if err := repo.db.Get(row, query, args...); err != nil {
 		switch {
@@ -146,36 +588,29 @@ func (repo pg) Create(l shortening.Link) error {
 	defer tx.Rollback()
 
 	row := newPgLink(l)
-	stmt, err := tx.PrepareNamed(`
-		INSERT INTO "links"(
-			user_id,
-			shortened,
-			alias,
-			destination,
-			is_open,
-			updated_at,
-			expired_at)
-		VALUES (
-			:user_id, 
-			:shortened, 
-			:alias,
-			:destination, 
-			:is_open, 
-			:updated_at, 
-			:expired_at)
-		RETURNING id`)
+	stmt, err := tx.PrepareNamed(pgLinkInsert)
 	if err != nil {
 		return fmt.Errorf("persistence<pg.Create>: %w", err)
 	}
 	var linkId uint64
 	if err := stmt.Get(&linkId, row); err != nil {
+		if pgIsShortenedTaken(err) {
+			err = shortening.ShortenedTaken{Index: 0}
+		}
+		return fmt.Errorf("persistence<pg.Create>: %w", err)
+	}
+	if err := pgSaveLinkRelations(tx, linkId, l); err != nil {
+		return fmt.Errorf("persistence<pg.Create>: %w", err)
+	}
+	editorId := l.UserId()
+	if err := pgRecordRevision(tx, linkId, &editorId, nil, l.Snapshot()); err != nil {
 		return fmt.Errorf("persistence<pg.Create>: %w", err)
 	}
 
 	outboxQuery := `
-		INSERT INTO link_shortened_outbox(user_id, link_id) 
-		VALUES ($1, $2)`
-	outboxArgs := []any{row.UserId, linkId}
+		INSERT INTO link_shortened_outbox(user_id, link_id, workspace_id) 
+		VALUES ($1, $2, $3)`
+	outboxArgs := []any{row.UserId, linkId, row.WorkspaceId}
 	if _, err := tx.Exec(outboxQuery, outboxArgs...); err != nil {
 		return fmt.Errorf("persistence<pg.Create>: %w", err)
 	}
@@ -186,41 +621,324 @@ func (repo pg) Create(l shortening.Link) error {
 	return nil
 }
 
-func (repo pg) UpdateWithSubscription(l shortening.Link) error {
+// Applies the settings right away and emits `shortConfigured` message for the
+// rest
+func (repo pg) UpdateWithSubscription(l shortening.Link, editorId uint64) error {
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.UpdateWithSubscription>: %w", err)
+	}
+	defer tx.Rollback()
+
 	row := newPgLink(l)
-	query := `
+	settingsQuery := `UPDATE "links" SET ` + pgLinkSettingsSet + ` WHERE id = :id`
+	if _, err := tx.NamedExec(settingsQuery, row); err != nil {
+		return fmt.Errorf("persistence<pg.UpdateWithSubscription>: %w", err)
+	}
+	if err := pgSaveLinkRelations(tx, l.Id(), l); err != nil {
+		return fmt.Errorf("persistence<pg.UpdateWithSubscription>: %w", err)
+	}
+
+	outboxQuery := `
 		INSERT INTO short_configured_outbox(
 			link_id, 
 			user_id, 
 			destination, 
 			alias,
-			is_open) 
-		VALUES (
-			:id,
-			:user_id, 
-			:destination,
-			:alias,
-			:is_open)`
-	if _, err := repo.db.NamedExec(query, row); err != nil {
+			is_open,
+			workspace_id,
+			editor_id) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	outboxArgs := []any{
+		row.Id, row.UserId, row.Destination, row.Alias, row.IsOpen, row.WorkspaceId,
+		editorId}
+	if _, err := tx.Exec(outboxQuery, outboxArgs...); err != nil {
+		return fmt.Errorf("persistence<pg.UpdateWithSubscription>: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("persistence<pg.UpdateWithSubscription>: %w", err)
 	}
 	return nil
 }
 
-func (repo pg) Update(l shortening.Link) error {
+// Updates the link, recording a revision when its alias, destination, or
+// open state changed
+func (repo pg) Update(l shortening.Link, editorId *uint64) error {
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.Update>: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := pgLockLinkSnapshot(tx, l.Id())
+	if err != nil {
+		return fmt.Errorf("persistence<pg.Update>: %w", err)
+	}
 	row := newPgLink(l)
+	if _, err := tx.NamedExec(pgLinkUpdate, row); err != nil {
+		return fmt.Errorf("persistence<pg.Update>: %w", err)
+	}
+	if err := pgSaveLinkRelations(tx, l.Id(), l); err != nil {
+		return fmt.Errorf("persistence<pg.Update>: %w", err)
+	}
+	if err := pgRecordRevision(tx, l.Id(), editorId, &before, l.Snapshot()); err != nil {
+		return fmt.Errorf("persistence<pg.Update>: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("persistence<pg.Update>: %w", err)
+	}
+	return nil
+}
+
+// Creates the links within a single transaction, along with one
+// `bulkShortened` message covering all of them
+func (repo pg) CreateMany(l []shortening.Link) ([]uint64, error) {
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return []uint64{}, fmt.Errorf("persistence<pg.CreateMany>: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareNamed(pgLinkInsert)
+	if err != nil {
+		return []uint64{}, fmt.Errorf("persistence<pg.CreateMany>: %w", err)
+	}
+	linkIds := []uint64{}
+	for idx, link := range l {
+		var linkId uint64
+		if err := stmt.Get(&linkId, newPgLink(link)); err != nil {
+			if pgIsShortenedTaken(err) {
+				err = shortening.ShortenedTaken{Index: idx}
+			}
+			return []uint64{}, fmt.Errorf("persistence<pg.CreateMany>: %w", err)
+		}
+		if err := pgSaveLinkRelations(tx, linkId, link); err != nil {
+			return []uint64{}, fmt.Errorf("persistence<pg.CreateMany>: %w", err)
+		}
+		editorId := link.UserId()
+		if err := pgRecordRevision(tx, linkId, &editorId, nil, link.Snapshot()); err != nil {
+			return []uint64{}, fmt.Errorf("persistence<pg.CreateMany>: %w", err)
+		}
+		linkIds = append(linkIds, linkId)
+	}
+	if len(linkIds) == 0 {
+		return linkIds, nil
+	}
+
+	var outboxId uint64
+	outboxQuery := `
+		INSERT INTO bulk_shortened_outbox(user_id, workspace_id)
+		VALUES ($1, $2)
+		RETURNING id`
+	outboxArgs := []any{l[0].UserId(), l[0].WorkspaceId()}
+	if err := tx.Get(&outboxId, outboxQuery, outboxArgs...); err != nil {
+		return []uint64{}, fmt.Errorf("persistence<pg.CreateMany>: %w", err)
+	}
+
+	itemsQuery := `
+		INSERT INTO bulk_shortened_links(outbox_id, link_id)
+		SELECT $1, UNNEST($2::INTEGER[])`
+	itemsArgs := []any{outboxId, linkIds}
+	if _, err := tx.Exec(itemsQuery, itemsArgs...); err != nil {
+		return []uint64{}, fmt.Errorf("persistence<pg.CreateMany>: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []uint64{}, fmt.Errorf("persistence<pg.CreateMany>: %w", err)
+	}
+	return linkIds, nil
+}
+
+// Updates the links at once, after `check` accepted the open links counted
+// against their quota. Counting and updating are serialized per quota, so
+// concurrent approvals couldn't both take the last of it. The links share
+// their owner and workspace, and aren't counted themselves
+func (repo pg) UpdateWithinQuota(
+	l []shortening.Link,
+	editorId *uint64,
+	check func(stats shortening.Stats) error,
+) error {
+	if len(l) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.UpdateWithinQuota>: %w", err)
+	}
+	defer tx.Rollback()
+
+	linkIds := []uint64{}
+	for _, link := range l {
+		linkIds = append(linkIds, link.Id())
+	}
+	stats, err := pgLockQuota(tx, l[0].UserId(), l[0].WorkspaceId(), linkIds)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.UpdateWithinQuota>: %w", err)
+	}
+	if err := check(stats); err != nil {
+		return fmt.Errorf("persistence<pg.UpdateWithinQuota>: %w", err)
+	}
+
+	for _, link := range l {
+		before, err := pgLockLinkSnapshot(tx, link.Id())
+		if err != nil {
+			return fmt.Errorf("persistence<pg.UpdateWithinQuota>: %w", err)
+		}
+		if _, err := tx.NamedExec(pgLinkUpdate, newPgLink(link)); err != nil {
+			return fmt.Errorf("persistence<pg.UpdateWithinQuota>: %w", err)
+		}
+		if err := pgSaveLinkRelations(tx, link.Id(), link); err != nil {
+			return fmt.Errorf("persistence<pg.UpdateWithinQuota>: %w", err)
+		}
+		if err := pgRecordRevision(tx, link.Id(), editorId, &before, link.Snapshot()); err != nil {
+			return fmt.Errorf("persistence<pg.UpdateWithinQuota>: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("persistence<pg.UpdateWithinQuota>: %w", err)
+	}
+	return nil
+}
+
+// Holds the quota of the workspace, or of the user's personal links when
+// there's no workspace, until the transaction ends. Then counts the open links
+// taking the quota, excluding `exceptIds`
+func pgLockQuota(
+	tx *sqlx.Tx,
+	userId uint64,
+	workspaceId *uint64,
+	exceptIds []uint64,
+) (shortening.Stats, error) {
+	key := fmt.Sprintf("links.quota:user:%d", userId)
+	if workspaceId != nil {
+		key = fmt.Sprintf("links.quota:workspace:%d", *workspaceId)
+	}
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, key); err != nil {
+		return shortening.Stats{}, fmt.Errorf("persistence<pgLockQuota>: %w", err)
+	}
+
 	query := `
-		UPDATE "links"
-		SET 
-			alias = :alias,
-			destination = :destination,
-			is_open = :is_open,
-			updated_at = :updated_at,
-			expired_at = :expired_at
+		SELECT COUNT(*) AS n_links
+		FROM links
 		WHERE
-			id = :id`
-	if _, err := repo.db.NamedExec(query, row); err != nil {
-		return fmt.Errorf("persistence<pg.Update>: %w", err)
+			user_id = $1
+			AND workspace_id IS NULL
+			AND id <> ALL($2::INTEGER[])
+			AND is_open
+			AND deleted_at IS NULL`
+	args := []any{userId, exceptIds}
+	if workspaceId != nil {
+		query = `
+			SELECT COUNT(*) AS n_links
+			FROM links
+			WHERE
+				workspace_id = $1
+				AND id <> ALL($2::INTEGER[])
+				AND is_open
+				AND deleted_at IS NULL`
+		args = []any{*workspaceId, exceptIds}
+	}
+	var count uint
+	if err := tx.Get(&count, query, args...); err != nil {
+		return shortening.Stats{}, fmt.Errorf("persistence<pgLockQuota>: %w", err)
+	}
+	return shortening.NewStats(count), nil
+}
+
+func (repo pg) DeleteManyById(id []uint64) error {
+	if len(id) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(`DELETE FROM "links" WHERE id IN (?)`, id)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.DeleteManyById>: %w", err)
+	}
+	if _, err := repo.db.Exec(repo.db.Rebind(query), args...); err != nil {
+		return fmt.Errorf("persistence<pg.DeleteManyById>: %w", err)
+	}
+	return nil
+}
+
+// Links in the trash keep their alias, hence only the purge frees it
+func (repo pg) GetTrashByUser(userId uint64, roles []shortening.Role) ([]shortening.Link, error) {
+	query, args, err := sqlx.In(pgLinkSelect+`
+		WHERE 
+			l.deleted_at IS NOT NULL
+			AND (
+				(l.workspace_id IS NULL AND l.user_id = ?)
+				OR l.workspace_id IN (
+					SELECT workspace_id
+					FROM workspace_members
+					WHERE user_id = ? AND role IN (?)))
+		ORDER BY l.deleted_at DESC, l.id DESC`, userId, userId, roles)
+	if err != nil {
+		return []shortening.Link{}, fmt.Errorf("persistence<pg.GetTrashByUser>: %w", err)
+	}
+	rows := new([]pgLink)
+	if err := repo.db.Select(rows, repo.db.Rebind(query), args...); err != nil {
+		return []shortening.Link{}, fmt.Errorf("persistence<pg.GetTrashByUser>: %w", err)
+	}
+
+	links := []shortening.Link{}
+	for _, r := range *rows {
+		link, err := r.toShortening()
+		if err != nil {
+			return []shortening.Link{}, fmt.Errorf("persistence<pg.GetTrashByUser>: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// Records a revision as well, since restoring the link closes it
+func (repo pg) SaveDeletion(l shortening.Link, editorId *uint64) error {
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.SaveDeletion>: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := pgLockLinkSnapshot(tx, l.Id())
+	if err != nil {
+		return fmt.Errorf("persistence<pg.SaveDeletion>: %w", err)
+	}
+	query := `UPDATE "links" SET deleted_at = $1, is_open = $2 WHERE id = $3`
+	args := []any{l.DeletedAt(), l.IsOpen(), l.Id()}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("persistence<pg.SaveDeletion>: %w", err)
+	}
+	if err := pgRecordRevision(tx, l.Id(), editorId, &before, l.Snapshot()); err != nil {
+		return fmt.Errorf("persistence<pg.SaveDeletion>: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("persistence<pg.SaveDeletion>: %w", err)
+	}
+	return nil
+}
+
+func (repo pg) PurgeDeletedBefore(deletedBefore time.Time, limit uint) error {
+	query := `
+		DELETE FROM "links"
+		WHERE id IN (
+			SELECT id
+			FROM "links"
+			WHERE deleted_at < $1
+			ORDER BY deleted_at
+			LIMIT $2)`
+	args := []any{deletedBefore, limit}
+	if _, err := repo.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("persistence<pg.PurgeDeletedBefore>: %w", err)
 	}
 	return nil
 }
@@ -235,14 +953,18 @@ func (pg pg) DeleteById(id uint64) error {
 	return nil
 }
 
+// Counts the open personal links of the user, which the user's own
+// subscription accounts for
 func (repo pg) CountByUserIdExcept(userId uint64, linkId uint64) (shortening.Stats, error) {
 	query := `
 		SELECT COUNT(*) AS n_links 
 		FROM links 
 		WHERE 
 			user_id = $1
+			AND workspace_id IS NULL
 			AND id <> $2
-			AND is_open`
+			AND is_open
+			AND deleted_at IS NULL`
 	args := []any{userId, linkId}
 	result := repo.db.QueryRow(query, args...)
 	if result.Err() != nil {
@@ -256,12 +978,58 @@ func (repo pg) CountByUserIdExcept(userId uint64, linkId uint64) (shortening.Sta
 	return shortening.NewStats(count), nil
 }
 
-func (repo pg) GetOpenedFromOldestByUser(userId uint64) ([]shortening.Link, error) {
+// Short codes count as taken as well, as they're resolved the same way as
+// aliases
+func (repo pg) ExistsByAliasExcept(alias string, linkId uint64) (bool, error) {
 	query := `
-		SELECT *
-		FROM links
-		WHERE user_id = $1 AND is_open
-		ORDER BY updated_at`
+		SELECT EXISTS(
+			SELECT 1
+			FROM links
+			WHERE (alias = $1 OR shortened = $1) AND id <> $2)`
+	args := []any{alias, linkId}
+	var exists bool
+	if err := repo.db.Get(&exists, query, args...); err != nil {
+		return false, fmt.Errorf("persistence<pg.ExistsByAliasExcept>: %w", err)
+	}
+	return exists, nil
+}
+
+// Rows are read one by one, so every link of the user isn't held at once.
+// Links in the trash or in workspaces are left out
+func (repo pg) EachByUser(userId uint64, each func(l shortening.Link) error) error {
+	query := pgLinkSelect + `
+		WHERE l.user_id = $1 AND l.workspace_id IS NULL AND l.deleted_at IS NULL
+		ORDER BY l.id`
+	args := []any{userId}
+	rows, err := repo.db.Queryx(query, args...)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.EachByUser>: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := pgLink{}
+		if err := rows.StructScan(&row); err != nil {
+			return fmt.Errorf("persistence<pg.EachByUser>: %w", err)
+		}
+		link, err := row.toShortening()
+		if err != nil {
+			return fmt.Errorf("persistence<pg.EachByUser>: %w", err)
+		}
+		if err := each(link); err != nil {
+			return fmt.Errorf("persistence<pg.EachByUser>: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("persistence<pg.EachByUser>: %w", err)
+	}
+	return nil
+}
+
+func (repo pg) GetOpenedFromOldestByUser(userId uint64) ([]shortening.Link, error) {
+	query := pgLinkSelect + `
+		WHERE l.user_id = $1 AND l.workspace_id IS NULL AND l.is_open AND l.deleted_at IS NULL
+		ORDER BY l.updated_at`
 	args := []any{userId}
 	rows := new([]pgLink)
 	if err := repo.db.Select(rows, query, args...); err != nil {
@@ -279,11 +1047,51 @@ func (repo pg) GetOpenedFromOldestByUser(userId uint64) ([]shortening.Link, erro
 	return links, nil
 }
 
+func (repo pg) GetOpenedFromOldestByWorkspace(workspaceId uint64) ([]shortening.Link, error) {
+	query := pgLinkSelect + `
+		WHERE l.workspace_id = $1 AND l.is_open AND l.deleted_at IS NULL
+		ORDER BY l.updated_at`
+	args := []any{workspaceId}
+	rows := new([]pgLink)
+	if err := repo.db.Select(rows, query, args...); err != nil {
+		return []shortening.Link{}, fmt.Errorf("persistence<pg.GetOpenedFromOldestByWorkspace>: %w", err)
+	}
+
+	links := []shortening.Link{}
+	for _, r := range *rows {
+		link, err := r.toShortening()
+		if err != nil {
+			return []shortening.Link{}, fmt.Errorf("persistence<pg.GetOpenedFromOldestByWorkspace>: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
 // =================
 // event-related
 // =================
 
+// Closes the links, recording the closing as revisions made by the system
 func (repo pg) ApplySubscriptionExpiration(deactivatedLinks []uint64) error {
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.ApplySubscriptionExpiration>: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range deactivatedLinks {
+		before, err := pgLockLinkSnapshot(tx, id)
+		if err != nil {
+			return fmt.Errorf("persistence<pg.ApplySubscriptionExpiration>: %w", err)
+		}
+		after := shortening.NewSnapshot(before.Alias(), before.Destination(), false)
+		if err := pgRecordRevision(tx, id, nil, &before, after); err != nil {
+			return fmt.Errorf("persistence<pg.ApplySubscriptionExpiration>: %w", err)
+		}
+	}
+
 	query, args, err := sqlx.In(`
 		UPDATE links
 		SET 
@@ -293,34 +1101,44 @@ func (repo pg) ApplySubscriptionExpiration(deactivatedLinks []uint64) error {
 	if err != nil {
 		return fmt.Errorf("persistence<pg.ApplySubscriptionExpiration>: %w", err)
 	}
-	fmt.Println(query)
-	if _, err := repo.db.Exec(repo.db.Rebind(query), args...); err != nil {
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("persistence<pg.ApplySubscriptionExpiration>: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("persistence<pg.ApplySubscriptionExpiration>: %w", err)
 	}
 	return nil
 }
 
 type pgLinkShortened struct {
-	Id     uint64 `db:"id"`
-	UserId uint64 `db:"user_id"`
-	LinkId uint64 `db:"link_id"`
+	Id           uint64  `db:"id"`
+	UserId       uint64  `db:"user_id"`
+	LinkId       uint64  `db:"link_id"`
+	WorkspaceId  *uint64 `db:"workspace_id"`
+	SubscriberId uint64  `db:"subscriber_id"`
 }
 
 func (row pgLinkShortened) toMessage() messaging.LinkShortened {
 	return messaging.NewLinkShortened(
 		row.Id,
 		row.UserId,
-		row.LinkId)
+		row.LinkId,
+		row.WorkspaceId,
+		row.SubscriberId)
 }
 
 func (repo pg) GetLinkShortened(maxCount uint) ([]messaging.LinkShortened, error) {
 	query := `
 		SELECT 
-			id,
-			user_id,
-			link_id
+			o.id,
+			o.user_id,
+			o.link_id,
+			o.workspace_id,
+			COALESCE(w.subscriber_id, o.user_id) AS subscriber_id
 		FROM link_shortened_outbox AS o 
-		WHERE is_done = false 
+		LEFT JOIN workspaces AS w ON w.id = o.workspace_id
+		WHERE o.is_done = false 
 		LIMIT $1`
 	args := []any{maxCount}
 	rows := new([]pgLinkShortened)
@@ -336,7 +1154,16 @@ func (repo pg) GetLinkShortened(maxCount uint) ([]messaging.LinkShortened, error
 }
 
 func (repo pg) GetLinkShortenedById(id uint64) (messaging.LinkShortened, error) {
-	query := `SELECT id, user_id, link_id FROM link_shortened_outbox WHERE id = $1`
+	query := `
+		SELECT
+			o.id,
+			o.user_id,
+			o.link_id,
+			o.workspace_id,
+			COALESCE(w.subscriber_id, o.user_id) AS subscriber_id
+		FROM link_shortened_outbox AS o
+		LEFT JOIN workspaces AS w ON w.id = o.workspace_id
+		WHERE o.id = $1`
 	args := []any{id}
 	row := new(pgLinkShortened)
 	if err := repo.db.Get(row, query, args...); err != nil {
@@ -369,12 +1196,15 @@ func (repo pg) ResolveLinkShortened(id []uint64) error {
 }
 
 type pgShortConfigured struct {
-	Id          uint64 `db:"id"`
-	UserId      uint64 `db:"user_id"`
-	LinkId      uint64 `db:"link_id"`
-	Alias       string `db:"alias"`
-	Destination string `db:"destination"`
-	IsOpen      bool   `db:"is_open"`
+	Id           uint64  `db:"id"`
+	UserId       uint64  `db:"user_id"`
+	LinkId       uint64  `db:"link_id"`
+	Alias        string  `db:"alias"`
+	Destination  string  `db:"destination"`
+	IsOpen       bool    `db:"is_open"`
+	WorkspaceId  *uint64 `db:"workspace_id"`
+	SubscriberId uint64  `db:"subscriber_id"`
+	EditorId     uint64  `db:"editor_id"`
 }
 
 func (row pgShortConfigured) toMessage() messaging.ShortConfigured {
@@ -384,20 +1214,27 @@ func (row pgShortConfigured) toMessage() messaging.ShortConfigured {
 		row.UserId,
 		row.Alias,
 		row.Destination,
-		row.IsOpen)
+		row.IsOpen,
+		row.WorkspaceId,
+		row.SubscriberId,
+		row.EditorId)
 }
 
 func (repo pg) GetShortConfigured(maxCount uint) ([]messaging.ShortConfigured, error) {
 	query := `
 		SELECT
-			id,
-			user_id,
-			link_id,
-			destination,
-			alias,
-			is_open
-		FROM short_configured_outbox 
-		WHERE is_done = false 
+			o.id,
+			o.user_id,
+			o.link_id,
+			o.destination,
+			o.alias,
+			o.is_open,
+			o.workspace_id,
+			COALESCE(w.subscriber_id, o.user_id) AS subscriber_id,
+			COALESCE(o.editor_id, o.user_id) AS editor_id
+		FROM short_configured_outbox AS o
+		LEFT JOIN workspaces AS w ON w.id = o.workspace_id
+		WHERE o.is_done = false 
 		LIMIT $1`
 	args := []any{maxCount}
 	rows := new([]pgShortConfigured)
@@ -415,14 +1252,18 @@ func (repo pg) GetShortConfigured(maxCount uint) ([]messaging.ShortConfigured, e
 func (repo pg) GetShortConfiguredById(id uint64) (messaging.ShortConfigured, error) {
 	query := ` 
 		SELECT
-			id,
-			user_id,
-			link_id,
-			destination,
-			alias,
-			is_open
-		FROM short_configured_outbox 
-		WHERE id =  $1`
+			o.id,
+			o.user_id,
+			o.link_id,
+			o.destination,
+			o.alias,
+			o.is_open,
+			o.workspace_id,
+			COALESCE(w.subscriber_id, o.user_id) AS subscriber_id,
+			COALESCE(o.editor_id, o.user_id) AS editor_id
+		FROM short_configured_outbox AS o
+		LEFT JOIN workspaces AS w ON w.id = o.workspace_id
+		WHERE o.id = $1`
 	args := []any{id}
 	row := new(pgShortConfigured)
 	if err := repo.db.Get(row, query, args...); err != nil {
@@ -453,3 +1294,105 @@ func (repo pg) ResolveShortConfigured(id []uint64) error {
 	}
 	return nil
 }
+
+type pgBulkShortened struct {
+	Id           uint64  `db:"id"`
+	UserId       uint64  `db:"user_id"`
+	WorkspaceId  *uint64 `db:"workspace_id"`
+	SubscriberId uint64  `db:"subscriber_id"`
+	LinkId       uint64  `db:"link_id"`
+}
+
+// Groups the rows, which come one per link, into messages
+func newBulkShortenedMessages(rows []pgBulkShortened) []messaging.BulkShortened {
+	order := []uint64{}
+	users := map[uint64]uint64{}
+	workspaces := map[uint64]*uint64{}
+	subscribers := map[uint64]uint64{}
+	links := map[uint64][]uint64{}
+	for _, row := range rows {
+		if _, ok := links[row.Id]; !ok {
+			order = append(order, row.Id)
+			users[row.Id] = row.UserId
+			workspaces[row.Id] = row.WorkspaceId
+			subscribers[row.Id] = row.SubscriberId
+		}
+		links[row.Id] = append(links[row.Id], row.LinkId)
+	}
+
+	messages := []messaging.BulkShortened{}
+	for _, id := range order {
+		messages = append(messages, messaging.NewBulkShortened(
+			id, users[id], links[id], workspaces[id], subscribers[id]))
+	}
+	return messages
+}
+
+func (repo pg) GetBulkShortened(maxCount uint) ([]messaging.BulkShortened, error) {
+	query := `
+		SELECT
+			o.id,
+			o.user_id,
+			o.workspace_id,
+			COALESCE(w.subscriber_id, o.user_id) AS subscriber_id,
+			l.link_id
+		FROM bulk_shortened_outbox AS o
+		JOIN bulk_shortened_links AS l ON l.outbox_id = o.id
+		LEFT JOIN workspaces AS w ON w.id = o.workspace_id
+		WHERE o.id IN (
+			SELECT id
+			FROM bulk_shortened_outbox
+			WHERE is_done = false
+			ORDER BY id
+			LIMIT $1)
+		ORDER BY o.id`
+	args := []any{maxCount}
+	rows := new([]pgBulkShortened)
+	if err := repo.db.Select(rows, query, args...); err != nil {
+		return []messaging.BulkShortened{}, fmt.Errorf("persistence<pg.GetBulkShortened>: %w", err)
+	}
+	return newBulkShortenedMessages(*rows), nil
+}
+
+func (repo pg) GetBulkShortenedById(id uint64) (messaging.BulkShortened, error) {
+	query := `
+		SELECT
+			o.id,
+			o.user_id,
+			o.workspace_id,
+			COALESCE(w.subscriber_id, o.user_id) AS subscriber_id,
+			l.link_id
+		FROM bulk_shortened_outbox AS o
+		JOIN bulk_shortened_links AS l ON l.outbox_id = o.id
+		LEFT JOIN workspaces AS w ON w.id = o.workspace_id
+		WHERE o.id = $1`
+	args := []any{id}
+	rows := new([]pgBulkShortened)
+	if err := repo.db.Select(rows, query, args...); err != nil {
+		return messaging.BulkShortened{}, fmt.Errorf("persistence<pg.GetBulkShortenedById>: %w", err)
+	}
+
+	messages := newBulkShortenedMessages(*rows)
+	if len(messages) == 0 {
+		err := oops.NotFound{
+			Err: sql.ErrNoRows,
+			Msg: fmt.Sprintf("bulk_shortened_outbox(id:%d) not found", id)}
+		return messaging.BulkShortened{}, fmt.Errorf("persistence<pg.GetBulkShortenedById>: %w", err)
+	}
+	return messages[0], nil
+}
+
+func (repo pg) ResolveBulkShortened(id []uint64) error {
+	query, args, err := sqlx.In(`
+		UPDATE bulk_shortened_outbox
+		SET is_done = true
+		WHERE id IN (?)`, id)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.ResolveBulkShortened>: %w", err)
+	}
+
+	if _, err := repo.db.Exec(repo.db.Rebind(query), args...); err != nil {
+		return fmt.Errorf("persistence<pg.ResolveBulkShortened>: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+	"github.com/solsteace/kochira/link/internal/domain/shortening/messaging"
+)
+
+type pgTransfer struct {
+	Id         uint64    `db:"id"`
+	LinkId     uint64    `db:"link_id"`
+	FromUserId uint64    `db:"from_user_id"`
+	ToUserId   uint64    `db:"to_user_id"`
+	Status     string    `db:"status"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+func (row pgTransfer) toShortening() shortening.Transfer {
+	return shortening.NewTransfer(
+		&row.Id,
+		row.LinkId,
+		row.FromUserId,
+		row.ToUserId,
+		shortening.TransferStatus(row.Status),
+		row.CreatedAt,
+		row.UpdatedAt)
+}
+
+func newPgTransfer(t shortening.Transfer) pgTransfer {
+	return pgTransfer{
+		Id:         t.Id(),
+		LinkId:     t.LinkId(),
+		FromUserId: t.FromUserId(),
+		ToUserId:   t.ToUserId(),
+		Status:     string(t.Status()),
+		CreatedAt:  t.CreatedAt(),
+		UpdatedAt:  t.UpdatedAt()}
+}
+
+const pgTransferUpdate = `
+	UPDATE link_transfers
+	SET
+		status = :status,
+		updated_at = :updated_at
+	WHERE id = :id`
+
+func (repo pg) GetUserIdByUsername(username string) (uint64, error) {
+	query := `SELECT id FROM link_owners WHERE username = $1 LIMIT 1`
+	args := []any{username}
+	var userId uint64
+	if err := repo.db.Get(&userId, query, args...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, fmt.Errorf(
+				"persistence<pg.GetUserIdByUsername>: %w",
+				oops.NotFound{
+					Err: err,
+					Msg: fmt.Sprintf("User `%s` not found", username)})
+		default:
+			return 0, fmt.Errorf("persistence<pg.GetUserIdByUsername>: %w", err)
+		}
+	}
+	return userId, nil
+}
+
+func (repo pg) GetTransferById(id uint64) (shortening.Transfer, error) {
+	row := new(pgTransfer)
+	query := `SELECT * FROM link_transfers WHERE id = $1 LIMIT 1`
+	args := []any{id}
+	if err := repo.db.Get(row, query, args...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err2 := oops.NotFound{
+				Err: err,
+				Msg: fmt.Sprintf("link_transfers(id:%d) not found", id)}
+			return shortening.Transfer{}, fmt.Errorf("persistence<pg.GetTransferById>: %w", err2)
+		default:
+			return shortening.Transfer{}, fmt.Errorf("persistence<pg.GetTransferById>: %w", err)
+		}
+	}
+	return row.toShortening(), nil
+}
+
+func (repo pg) GetTransfersByUser(userId uint64) ([]shortening.Transfer, error) {
+	rows := new([]pgTransfer)
+	query := `
+		SELECT *
+		FROM link_transfers
+		WHERE from_user_id = $1 OR to_user_id = $1
+		ORDER BY created_at DESC, id DESC`
+	if err := repo.db.Select(rows, query, userId); err != nil {
+		return []shortening.Transfer{}, fmt.Errorf("persistence<pg.GetTransfersByUser>: %w", err)
+	}
+
+	transfers := []shortening.Transfer{}
+	for _, row := range *rows {
+		transfers = append(transfers, row.toShortening())
+	}
+	return transfers, nil
+}
+
+func (repo pg) ExistsOpenTransferByLinkId(linkId uint64) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM link_transfers
+			WHERE link_id = $1 AND status IN ($2, $3))`
+	args := []any{linkId, string(shortening.TransferPending), string(shortening.TransferAccepted)}
+	var exists bool
+	if err := repo.db.Get(&exists, query, args...); err != nil {
+		return false, fmt.Errorf("persistence<pg.ExistsOpenTransferByLinkId>: %w", err)
+	}
+	return exists, nil
+}
+
+func (repo pg) CreateTransfer(t shortening.Transfer) (uint64, error) {
+	query := `
+		INSERT INTO link_transfers(
+			link_id,
+			from_user_id,
+			to_user_id,
+			status,
+			created_at,
+			updated_at)
+		VALUES (
+			:link_id,
+			:from_user_id,
+			:to_user_id,
+			:status,
+			:created_at,
+			:updated_at)
+		RETURNING id`
+	stmt, err := repo.db.PrepareNamed(query)
+	if err != nil {
+		return 0, fmt.Errorf("persistence<pg.CreateTransfer>: %w", err)
+	}
+	defer stmt.Close()
+
+	var id uint64
+	if err := stmt.Get(&id, newPgTransfer(t)); err != nil {
+		return 0, fmt.Errorf("persistence<pg.CreateTransfer>: %w", err)
+	}
+	return id, nil
+}
+
+func (repo pg) UpdateTransfer(t shortening.Transfer) error {
+	if _, err := repo.db.NamedExec(pgTransferUpdate, newPgTransfer(t)); err != nil {
+		return fmt.Errorf("persistence<pg.UpdateTransfer>: %w", err)
+	}
+	return nil
+}
+
+func (repo pg) AcceptTransfer(t shortening.Transfer) error {
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.AcceptTransfer>: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExec(pgTransferUpdate, newPgTransfer(t)); err != nil {
+		return fmt.Errorf("persistence<pg.AcceptTransfer>: %w", err)
+	}
+
+	outboxQuery := `
+		INSERT INTO transfer_accepted_outbox(user_id, transfer_id)
+		VALUES ($1, $2)`
+	outboxArgs := []any{t.ToUserId(), t.Id()}
+	if _, err := tx.Exec(outboxQuery, outboxArgs...); err != nil {
+		return fmt.Errorf("persistence<pg.AcceptTransfer>: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("persistence<pg.AcceptTransfer>: %w", err)
+	}
+	return nil
+}
+
+// The quota the link moves into is held the same way as in
+// `UpdateWithinQuota`, with `check` deciding whether it could take the link
+func (repo pg) CompleteTransfer(
+	t shortening.Transfer,
+	l shortening.Link,
+	check func(stats shortening.Stats) error,
+) error {
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.CompleteTransfer>: %w", err)
+	}
+	defer tx.Rollback()
+
+	stats, err := pgLockQuota(tx, l.UserId(), l.WorkspaceId(), []uint64{l.Id()})
+	if err != nil {
+		return fmt.Errorf("persistence<pg.CompleteTransfer>: %w", err)
+	}
+	if err := check(stats); err != nil {
+		return fmt.Errorf("persistence<pg.CompleteTransfer>: %w", err)
+	}
+
+	if _, err := tx.NamedExec(pgTransferUpdate, newPgTransfer(t)); err != nil {
+		return fmt.Errorf("persistence<pg.CompleteTransfer>: %w", err)
+	}
+
+	// Only when the link is still where the transfer found it
+	linkQuery := `UPDATE "links" SET user_id = $1 WHERE id = $2 AND user_id = $3`
+	linkArgs := []any{l.UserId(), l.Id(), t.FromUserId()}
+	result, err := tx.Exec(linkQuery, linkArgs...)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.CompleteTransfer>: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("persistence<pg.CompleteTransfer>: %w", err)
+	} else if affected == 0 {
+		err := oops.Forbidden{
+			Err: errors.New("stale transfer"),
+			Msg: "Link had changed hands since the transfer was started"}
+		return fmt.Errorf("persistence<pg.CompleteTransfer>: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("persistence<pg.CompleteTransfer>: %w", err)
+	}
+	return nil
+}
+
+type pgTransferAccepted struct {
+	Id         uint64 `db:"id"`
+	UserId     uint64 `db:"user_id"`
+	TransferId uint64 `db:"transfer_id"`
+}
+
+func (row pgTransferAccepted) toMessage() messaging.TransferAccepted {
+	return messaging.NewTransferAccepted(
+		row.Id,
+		row.UserId,
+		row.TransferId)
+}
+
+func (repo pg) GetTransferAccepted(maxCount uint) ([]messaging.TransferAccepted, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			transfer_id
+		FROM transfer_accepted_outbox
+		WHERE is_done = false
+		ORDER BY id
+		LIMIT $1`
+	args := []any{maxCount}
+	rows := new([]pgTransferAccepted)
+	if err := repo.db.Select(rows, query, args...); err != nil {
+		return []messaging.TransferAccepted{}, fmt.Errorf("persistence<pg.GetTransferAccepted>: %w", err)
+	}
+
+	messages := []messaging.TransferAccepted{}
+	for _, row := range *rows {
+		messages = append(messages, row.toMessage())
+	}
+	return messages, nil
+}
+
+func (repo pg) GetTransferAcceptedById(id uint64) (messaging.TransferAccepted, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			transfer_id
+		FROM transfer_accepted_outbox
+		WHERE id = $1
+		LIMIT 1`
+	args := []any{id}
+	row := new(pgTransferAccepted)
+	if err := repo.db.Get(row, query, args...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err2 := oops.NotFound{
+				Err: err,
+				Msg: fmt.Sprintf("transfer_accepted_outbox(id:%d) not found", id)}
+			return messaging.TransferAccepted{}, fmt.Errorf("persistence<pg.GetTransferAcceptedById>: %w", err2)
+		default:
+			return messaging.TransferAccepted{}, fmt.Errorf("persistence<pg.GetTransferAcceptedById>: %w", err)
+		}
+	}
+	return row.toMessage(), nil
+}
+
+func (repo pg) ResolveTransferAccepted(id []uint64) error {
+	query, args, err := sqlx.In(`
+		UPDATE transfer_accepted_outbox
+		SET is_done = true
+		WHERE id IN (?)`, id)
+	if err != nil {
+		return fmt.Errorf("persistence<pg.ResolveTransferAccepted>: %w", err)
+	}
+
+	if _, err := repo.db.Exec(repo.db.Rebind(query), args...); err != nil {
+		return fmt.Errorf("persistence<pg.ResolveTransferAccepted>: %w", err)
+	}
+	return nil
+}
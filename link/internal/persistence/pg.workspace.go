@@ -0,0 +1,194 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+)
+
+type pgWorkspace struct {
+	Id           uint64    `db:"id"`
+	Name         string    `db:"name"`
+	SubscriberId uint64    `db:"subscriber_id"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+func (row pgWorkspace) toShortening() (shortening.Workspace, error) {
+	w, err := shortening.NewWorkspace(&row.Id, row.Name, row.SubscriberId, row.CreatedAt)
+	if err != nil {
+		return shortening.Workspace{}, fmt.Errorf("persistence<pgWorkspace.toShortening>: %w", err)
+	}
+	return w, nil
+}
+
+type pgMember struct {
+	WorkspaceId uint64 `db:"workspace_id"`
+	UserId      uint64 `db:"user_id"`
+	Role        string `db:"role"`
+}
+
+func (row pgMember) toShortening() shortening.Member {
+	return shortening.NewMember(row.WorkspaceId, row.UserId, shortening.Role(row.Role))
+}
+
+func (repo pg) GetWorkspaceById(id uint64) (shortening.Workspace, error) {
+	row := new(pgWorkspace)
+	query := `SELECT * FROM workspaces WHERE id = $1 LIMIT 1`
+	args := []any{id}
+	if err := repo.db.Get(row, query, args...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err2 := oops.NotFound{
+				Err: err,
+				Msg: fmt.Sprintf("workspace(id:%d) not found", id)}
+			return shortening.Workspace{}, fmt.Errorf("persistence<pg.GetWorkspaceById>: %w", err2)
+		default:
+			return shortening.Workspace{}, fmt.Errorf("persistence<pg.GetWorkspaceById>: %w", err)
+		}
+	}
+
+	w, err := row.toShortening()
+	if err != nil {
+		return shortening.Workspace{}, fmt.Errorf("persistence<pg.GetWorkspaceById>: %w", err)
+	}
+	return w, nil
+}
+
+func (repo pg) GetWorkspacesByUser(userId uint64) ([]shortening.Workspace, error) {
+	query := `
+		SELECT w.*
+		FROM workspaces AS w
+		JOIN workspace_members AS m ON m.workspace_id = w.id
+		WHERE m.user_id = $1
+		ORDER BY w.created_at, w.id`
+	workspaces, err := repo.getWorkspaces(query, userId)
+	if err != nil {
+		return []shortening.Workspace{}, fmt.Errorf("persistence<pg.GetWorkspacesByUser>: %w", err)
+	}
+	return workspaces, nil
+}
+
+func (repo pg) GetWorkspacesBySubscriber(userId uint64) ([]shortening.Workspace, error) {
+	query := `SELECT * FROM workspaces WHERE subscriber_id = $1 ORDER BY id`
+	workspaces, err := repo.getWorkspaces(query, userId)
+	if err != nil {
+		return []shortening.Workspace{}, fmt.Errorf("persistence<pg.GetWorkspacesBySubscriber>: %w", err)
+	}
+	return workspaces, nil
+}
+
+func (repo pg) getWorkspaces(query string, args ...any) ([]shortening.Workspace, error) {
+	rows := new([]pgWorkspace)
+	if err := repo.db.Select(rows, query, args...); err != nil {
+		return []shortening.Workspace{}, fmt.Errorf("persistence<pg.getWorkspaces>: %w", err)
+	}
+
+	workspaces := []shortening.Workspace{}
+	for _, row := range *rows {
+		w, err := row.toShortening()
+		if err != nil {
+			return []shortening.Workspace{}, fmt.Errorf("persistence<pg.getWorkspaces>: %w", err)
+		}
+		workspaces = append(workspaces, w)
+	}
+	return workspaces, nil
+}
+
+func (repo pg) GetMembers(workspaceId uint64) ([]shortening.Member, error) {
+	rows := new([]pgMember)
+	query := `
+		SELECT *
+		FROM workspace_members
+		WHERE workspace_id = $1
+		ORDER BY user_id`
+	if err := repo.db.Select(rows, query, workspaceId); err != nil {
+		return []shortening.Member{}, fmt.Errorf("persistence<pg.GetMembers>: %w", err)
+	}
+
+	members := []shortening.Member{}
+	for _, row := range *rows {
+		members = append(members, row.toShortening())
+	}
+	return members, nil
+}
+
+func (repo pg) GetMember(workspaceId uint64, userId uint64) (shortening.Member, error) {
+	row := new(pgMember)
+	query := `
+		SELECT *
+		FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2
+		LIMIT 1`
+	args := []any{workspaceId, userId}
+	if err := repo.db.Get(row, query, args...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err2 := oops.NotFound{
+				Err: err,
+				Msg: fmt.Sprintf("workspace_members(workspace_id:%d, user_id:%d) not found", workspaceId, userId)}
+			return shortening.Member{}, fmt.Errorf("persistence<pg.GetMember>: %w", err2)
+		default:
+			return shortening.Member{}, fmt.Errorf("persistence<pg.GetMember>: %w", err)
+		}
+	}
+	return row.toShortening(), nil
+}
+
+func (repo pg) CreateWorkspace(w shortening.Workspace) (uint64, error) {
+	ctx := context.Background()
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("persistence<pg.CreateWorkspace>: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uint64
+	query := `
+		INSERT INTO workspaces(name, subscriber_id, created_at)
+		VALUES ($1, $2, $3)
+		RETURNING id`
+	args := []any{w.Name(), w.SubscriberId(), w.CreatedAt()}
+	if err := tx.Get(&id, query, args...); err != nil {
+		return 0, fmt.Errorf("persistence<pg.CreateWorkspace>: %w", err)
+	}
+
+	memberQuery := `
+		INSERT INTO workspace_members(workspace_id, user_id, role)
+		VALUES ($1, $2, $3)`
+	memberArgs := []any{id, w.SubscriberId(), string(shortening.RoleOwner)}
+	if _, err := tx.Exec(memberQuery, memberArgs...); err != nil {
+		return 0, fmt.Errorf("persistence<pg.CreateWorkspace>: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("persistence<pg.CreateWorkspace>: %w", err)
+	}
+	return id, nil
+}
+
+func (repo pg) SaveMember(m shortening.Member) error {
+	query := `
+		INSERT INTO workspace_members(workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (workspace_id, user_id) DO UPDATE
+		SET role = EXCLUDED.role`
+	args := []any{m.WorkspaceId(), m.UserId(), string(m.Role())}
+	if _, err := repo.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("persistence<pg.SaveMember>: %w", err)
+	}
+	return nil
+}
+
+func (repo pg) DeleteMember(workspaceId uint64, userId uint64) error {
+	query := `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`
+	args := []any{workspaceId, userId}
+	if _, err := repo.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("persistence<pg.DeleteMember>: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package persistence
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/kochira/link/internal/domain/redirect"
+	"github.com/solsteace/kochira/link/internal/domain/redirect/store"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/valkeycompat"
+)
+
+// Read-through cache of `redirect.Link`, backed by `origin`.
+//
+// Invalidated links are marked stale rather than forgotten. Lookups on a
+// stale link read the origin's fresh side, as its replica might still serve
+// the link as it was before the change, which would be cached all over again.
+// Lookups that found nothing cached only fill the cache when it's still empty,
+// so a replica read racing the invalidation couldn't overwrite the mark
+type valkeyRedirect struct {
+	client         valkey.Client
+	origin         valkeyRedirectOrigin
+	retention      time.Duration // How long a found link would be remembered?
+	missRetention  time.Duration // How long a missing link would be remembered?
+	staleRetention time.Duration // How long an invalidated link is read fresh? Should outlast the replica lag
+}
+
+type valkeyRedirectOrigin interface {
+	store.Shortening
+	GetFreshByAlias(alias string) (redirect.Link, error) // Reads where the writes go, skipping the replica
+}
+
+func NewValkeyRedirect(
+	client valkey.Client,
+	origin valkeyRedirectOrigin,
+	retention time.Duration,
+	missRetention time.Duration,
+	staleRetention time.Duration,
+) valkeyRedirect {
+	return valkeyRedirect{
+		client:         client,
+		origin:         origin,
+		retention:      retention,
+		missRetention:  missRetention,
+		staleRetention: staleRetention}
+}
+
+// Replaces the cached link, but only when the key is still as it was seen.
+// KEYS[1]: the key. ARGV[1]: the stale mark that was seen, empty when nothing
+// was. ARGV[2]: retention, in milliseconds. The rest: fields of the hash
+var valkeyRedirectRemember = valkey.NewLuaScript(`
+local seen = ARGV[1]
+if seen == "" then
+	if redis.call("EXISTS", KEYS[1]) == 1 then
+		return 0
+	end
+elseif redis.call("HGET", KEYS[1], "stale") ~= seen then
+	return 0
+end
+redis.call("DEL", KEYS[1])
+redis.call("HSET", KEYS[1], unpack(ARGV, 3))
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1`)
+
+func valkeyRedirectKey(alias string) string {
+	return fmt.Sprintf("link:alias:%s:redirect", alias)
+}
+
+func (vr valkeyRedirect) GetByAlias(alias string) (redirect.Link, error) {
+	ctx := context.Background()
+	adapter := valkeycompat.NewAdapter(vr.client)
+	key := valkeyRedirectKey(alias)
+
+	// Any failure on the cache side falls back to the origin, as the cache
+	// shouldn't be the reason a redirect fails
+	stale := ""
+	if res := adapter.HGetAll(ctx, key); res.Err() == nil && len(res.Val()) > 0 {
+		stale = res.Val()["stale"]
+		row, err := valkeyRedirectLink{}.fromHash(res.Val())
+		switch {
+		case err != nil, stale != "":
+		case row.Missing:
+			return redirect.Link{}, fmt.Errorf(
+				"persistence<valkeyRedirect.GetByAlias>: %w",
+				oops.NotFound{Msg: fmt.Sprintf("link(shortened:%s) not found", alias)})
+		default:
+			return row.toRedirect(), nil
+		}
+	}
+
+	var link redirect.Link
+	var err error
+	if stale != "" {
+		link, err = vr.origin.GetFreshByAlias(alias)
+	} else {
+		link, err = vr.origin.GetByAlias(alias)
+	}
+	switch {
+	case errors.As(err, &oops.NotFound{}):
+		vr.remember(ctx, key, stale, valkeyRedirectLink{Missing: true}, vr.missRetention)
+		return redirect.Link{}, fmt.Errorf("persistence<valkeyRedirect.GetByAlias>: %w", err)
+	case err != nil:
+		return redirect.Link{}, fmt.Errorf("persistence<valkeyRedirect.GetByAlias>: %w", err)
+	}
+
+	vr.remember(ctx, key, stale, newValkeyRedirectLink(link), vr.retention)
+	return link, nil
+}
+
+// Clicks are always counted by the origin, as they have to be consumed
+// atomically
+func (vr valkeyRedirect) ConsumeClick(id uint64) (bool, error) {
+	ok, err := vr.origin.ConsumeClick(id)
+	if err != nil {
+		return false, fmt.Errorf("persistence<valkeyRedirect.ConsumeClick>: %w", err)
+	}
+	return ok, nil
+}
+
+// Stores the row on a best-effort basis, unless the key had changed since
+// it was seen with the `stale` mark. Empty `stale` means nothing was seen
+func (vr valkeyRedirect) remember(
+	ctx context.Context,
+	key string,
+	stale string,
+	row valkeyRedirectLink,
+	retention time.Duration,
+) {
+	args := []string{stale, strconv.FormatInt(retention.Milliseconds(), 10)}
+	for field, value := range row.toHash() {
+		args = append(args, field, value)
+	}
+	valkeyRedirectRemember.Exec(ctx, vr.client, []string{key}, args)
+}
+
+// Marks cached links stale, so the lookups for a while would read the origin's
+// fresh side. See `valkeyRedirect`
+func (vr valkeyRedirect) Invalidate(alias ...string) error {
+	if len(alias) == 0 {
+		return nil
+	}
+
+	// Marks are unique, so lookups that saw an older one couldn't fill the
+	// cache past a newer invalidation
+	mark := make([]byte, 8)
+	if _, err := rand.Read(mark); err != nil {
+		return fmt.Errorf("persistence<valkeyRedirect.Invalidate>: %w", err)
+	}
+
+	ctx := context.Background()
+	tx := valkeycompat.NewAdapter(vr.client).TxPipeline()
+	for _, a := range alias {
+		key := valkeyRedirectKey(a)
+		tx.Del(ctx, key)
+		tx.HSet(ctx, key, "stale", hex.EncodeToString(mark))
+		tx.Expire(ctx, key, vr.staleRetention)
+	}
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("persistence<valkeyRedirect.Invalidate>: %w", err)
+	}
+	return nil
+}
+
+type valkeyRedirectLink struct {
+	Missing         bool
+	Id              uint64
+	UserId          uint64
+	Shortened       string
+	Destination     string
+	IsOpen          bool
+	ExpiredAt       time.Time
+	Password        string
+	RemainingClicks *uint
+	RedirectType    int
+	PassQuery       bool
+	PassPath        bool
+	Preview         bool
+	ActiveFrom      *time.Time
+	ActiveUntil     *time.Time
+	Rules           []redirect.Rule
+	Variants        []redirect.Variant
+	CreatedAt       time.Time
+}
+
+func newValkeyRedirectLink(l redirect.Link) valkeyRedirectLink {
+	return valkeyRedirectLink{
+		Id:              l.Id,
+		UserId:          l.UserId,
+		Shortened:       l.Shortened,
+		Destination:     l.Destination,
+		IsOpen:          l.IsOpen,
+		ExpiredAt:       l.ExpiredAt,
+		Password:        l.Password,
+		RemainingClicks: l.RemainingClicks,
+		RedirectType:    l.RedirectType,
+		PassQuery:       l.PassQuery,
+		PassPath:        l.PassPath,
+		Preview:         l.Preview,
+		ActiveFrom:      l.ActiveFrom,
+		ActiveUntil:     l.ActiveUntil,
+		Rules:           l.Rules,
+		Variants:        l.Variants,
+		CreatedAt:       l.CreatedAt}
+}
+
+func (row valkeyRedirectLink) toRedirect() redirect.Link {
+	return redirect.Link{
+		Id:              row.Id,
+		UserId:          row.UserId,
+		Shortened:       row.Shortened,
+		Destination:     row.Destination,
+		IsOpen:          row.IsOpen,
+		ExpiredAt:       row.ExpiredAt,
+		Password:        row.Password,
+		RemainingClicks: row.RemainingClicks,
+		RedirectType:    row.RedirectType,
+		PassQuery:       row.PassQuery,
+		PassPath:        row.PassPath,
+		Preview:         row.Preview,
+		ActiveFrom:      row.ActiveFrom,
+		ActiveUntil:     row.ActiveUntil,
+		Rules:           row.Rules,
+		Variants:        row.Variants,
+		CreatedAt:       row.CreatedAt}
+}
+
+func (row valkeyRedirectLink) toHash() map[string]string {
+	if row.Missing {
+		return map[string]string{"missing": "true"}
+	}
+
+	hash := map[string]string{
+		"id":            fmt.Sprintf("%d", row.Id),
+		"user_id":       fmt.Sprintf("%d", row.UserId),
+		"shortened":     row.Shortened,
+		"destination":   row.Destination,
+		"is_open":       fmt.Sprintf("%t", row.IsOpen),
+		"expired_at":    fmt.Sprintf("%d", row.ExpiredAt.UnixMilli()),
+		"password":      row.Password,
+		"redirect_type": fmt.Sprintf("%d", row.RedirectType),
+		"pass_query":    fmt.Sprintf("%t", row.PassQuery),
+		"pass_path":     fmt.Sprintf("%t", row.PassPath),
+		"preview":       fmt.Sprintf("%t", row.Preview),
+		"created_at":    fmt.Sprintf("%d", row.CreatedAt.UnixMilli())}
+	if row.RemainingClicks != nil {
+		hash["remaining_clicks"] = fmt.Sprintf("%d", *row.RemainingClicks)
+	}
+	if row.ActiveFrom != nil {
+		hash["active_from"] = fmt.Sprintf("%d", row.ActiveFrom.UnixMilli())
+	}
+	if row.ActiveUntil != nil {
+		hash["active_until"] = fmt.Sprintf("%d", row.ActiveUntil.UnixMilli())
+	}
+	if len(row.Rules) > 0 {
+		// Marshalling plain values never fails
+		rules, _ := json.Marshal(row.Rules)
+		hash["rules"] = string(rules)
+	}
+	if len(row.Variants) > 0 {
+		variants, _ := json.Marshal(row.Variants)
+		hash["variants"] = string(variants)
+	}
+	return hash
+}
+
+func (_ valkeyRedirectLink) fromHash(hash map[string]string) (valkeyRedirectLink, error) {
+	if hash["missing"] == "true" {
+		return valkeyRedirectLink{Missing: true}, nil
+	}
+
+	hashId, err := strconv.ParseUint(hash["id"], 10, 64)
+	if err != nil {
+		return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+	}
+	hashUserId, err := strconv.ParseUint(hash["user_id"], 10, 64)
+	if err != nil {
+		return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+	}
+	hashIsOpen, err := strconv.ParseBool(hash["is_open"])
+	if err != nil {
+		return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+	}
+	hashExpiredAt, err := strconv.ParseInt(hash["expired_at"], 10, 64)
+	if err != nil {
+		return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+	}
+	hashRedirectType, err := strconv.Atoi(hash["redirect_type"])
+	if err != nil {
+		return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+	}
+	hashPassQuery, err := strconv.ParseBool(hash["pass_query"])
+	if err != nil {
+		return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+	}
+	hashPassPath, err := strconv.ParseBool(hash["pass_path"])
+	if err != nil {
+		return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+	}
+	hashPreview, err := strconv.ParseBool(hash["preview"])
+	if err != nil {
+		return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+	}
+	hashCreatedAt, err := strconv.ParseInt(hash["created_at"], 10, 64)
+	if err != nil {
+		return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+	}
+
+	row := valkeyRedirectLink{
+		Id:           hashId,
+		UserId:       hashUserId,
+		Shortened:    hash["shortened"],
+		Destination:  hash["destination"],
+		IsOpen:       hashIsOpen,
+		ExpiredAt:    time.UnixMilli(hashExpiredAt),
+		Password:     hash["password"],
+		RedirectType: hashRedirectType,
+		PassQuery:    hashPassQuery,
+		PassPath:     hashPassPath,
+		Preview:      hashPreview,
+		CreatedAt:    time.UnixMilli(hashCreatedAt)}
+	if hashRemainingClicks, ok := hash["remaining_clicks"]; ok {
+		remaining, err := strconv.ParseUint(hashRemainingClicks, 10, 0)
+		if err != nil {
+			return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+		}
+		actualRemaining := uint(remaining)
+		row.RemainingClicks = &actualRemaining
+	}
+	if hashActiveFrom, ok := hash["active_from"]; ok {
+		from, err := strconv.ParseInt(hashActiveFrom, 10, 64)
+		if err != nil {
+			return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+		}
+		actualFrom := time.UnixMilli(from)
+		row.ActiveFrom = &actualFrom
+	}
+	if hashActiveUntil, ok := hash["active_until"]; ok {
+		until, err := strconv.ParseInt(hashActiveUntil, 10, 64)
+		if err != nil {
+			return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+		}
+		actualUntil := time.UnixMilli(until)
+		row.ActiveUntil = &actualUntil
+	}
+	if hashRules, ok := hash["rules"]; ok {
+		if err := json.Unmarshal([]byte(hashRules), &row.Rules); err != nil {
+			return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+		}
+	}
+	if hashVariants, ok := hash["variants"]; ok {
+		if err := json.Unmarshal([]byte(hashVariants), &row.Variants); err != nil {
+			return valkeyRedirectLink{}, fmt.Errorf("persistence<valkeyRedirectLink.fromHash>: %w", err)
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,100 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/valkeycompat"
+)
+
+// Counts failed unlock attempts in fixed windows, shared by every instance
+// serving the redirects
+type valkeyUnlockAttempt struct {
+	client valkey.Client
+}
+
+func NewValkeyUnlockAttempt(client valkey.Client) valkeyUnlockAttempt {
+	return valkeyUnlockAttempt{client: client}
+}
+
+func valkeyUnlockAttemptKey(key string) string {
+	return fmt.Sprintf("link:unlock:%s:failures", key)
+}
+
+func (vu valkeyUnlockAttempt) CountFailures(key string) (uint, error) {
+	ctx := context.Background()
+	adapter := valkeycompat.NewAdapter(vu.client)
+	count, err := adapter.Get(ctx, valkeyUnlockAttemptKey(key)).Uint64()
+	switch {
+	case errors.Is(err, valkeycompat.Nil):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("persistence<valkeyUnlockAttempt.CountFailures>: %w", err)
+	}
+	return uint(count), nil
+}
+
+func (vu valkeyUnlockAttempt) AddFailure(key string, window time.Duration) error {
+	ctx := context.Background()
+	tx := valkeycompat.NewAdapter(vu.client).TxPipeline()
+	tx.Incr(ctx, valkeyUnlockAttemptKey(key))
+	tx.ExpireNX(ctx, valkeyUnlockAttemptKey(key), window)
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("persistence<valkeyUnlockAttempt.AddFailure>: %w", err)
+	}
+	return nil
+}
+
+// Counts the attempt only while the window has room for it. The window starts
+// on the first one
+var valkeyUnlockAttemptTake = valkey.NewLuaScript(`
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+if count >= tonumber(ARGV[1]) then
+	return {count, 0}
+end
+count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return {count, 1}`)
+
+// Only uncounts within the window the attempt was counted in, so an expired
+// counter isn't recreated without its expiry
+var valkeyUnlockAttemptReturn = valkey.NewLuaScript(`
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+if count > 0 then
+	redis.call("DECR", KEYS[1])
+end
+return 0`)
+
+func (vu valkeyUnlockAttempt) TakeAttempt(key string, limit uint, window time.Duration) (uint, bool, error) {
+	ctx := context.Background()
+	result, err := valkeyUnlockAttemptTake.Exec(
+		ctx,
+		vu.client,
+		[]string{valkeyUnlockAttemptKey(key)},
+		[]string{
+			strconv.FormatUint(uint64(limit), 10),
+			strconv.FormatInt(window.Milliseconds(), 10)}).AsIntSlice()
+	if err != nil {
+		return 0, false, fmt.Errorf("persistence<valkeyUnlockAttempt.TakeAttempt>: %w", err)
+	}
+	return uint(result[0]), result[1] == 1, nil
+}
+
+func (vu valkeyUnlockAttempt) ReturnAttempt(key string) error {
+	ctx := context.Background()
+	err := valkeyUnlockAttemptReturn.Exec(
+		ctx,
+		vu.client,
+		[]string{valkeyUnlockAttemptKey(key)},
+		[]string{}).Error()
+	if err != nil {
+		return fmt.Errorf("persistence<valkeyUnlockAttempt.ReturnAttempt>: %w", err)
+	}
+	return nil
+}
@@ -17,8 +17,30 @@ func (s shortening) Use(parent *chi.Mux) {
 	shortening.Group(func(r chi.Router) {
 		r.Use(s.userContext.Handle)
 		r.Get("/my", reqres.HttpHandlerWithError(s.controller.GetSelf))
+		r.Get("/my/export", reqres.HttpHandlerWithError(s.controller.Export))
+		r.Post("/my/import", reqres.HttpHandlerWithError(s.controller.Import))
+		r.Get("/my/trash", reqres.HttpHandlerWithError(s.controller.GetTrash))
 		r.Get("/my/{id}", reqres.HttpHandlerWithError(s.controller.GetById))
+		r.Get("/my/{id}/stats", reqres.HttpHandlerWithError(s.controller.GetStats))
+		r.Get("/my/{id}/qr", reqres.HttpHandlerWithError(s.controller.GetQr))
+		r.Post("/my/{id}/restore", reqres.HttpHandlerWithError(s.controller.Restore))
+		r.Get("/my/{id}/revisions", reqres.HttpHandlerWithError(s.controller.GetRevisions))
+		r.Post("/my/{id}/revisions/{rev}/restore", reqres.HttpHandlerWithError(s.controller.RestoreRevision))
+		r.Post("/my/{id}/transfer", reqres.HttpHandlerWithError(s.controller.CreateTransfer))
+		r.Get("/my/{id}/rules", reqres.HttpHandlerWithError(s.controller.GetRules))
+		r.Post("/my/{id}/rules", reqres.HttpHandlerWithError(s.controller.CreateRule))
+		r.Put("/my/{id}/rules/{ruleId}", reqres.HttpHandlerWithError(s.controller.UpdateRuleById))
+		r.Delete("/my/{id}/rules/{ruleId}", reqres.HttpHandlerWithError(s.controller.DeleteRuleById))
+		r.Get("/workspace/{workspaceId}", reqres.HttpHandlerWithError(s.controller.GetByWorkspace))
+		r.Get("/transfers", reqres.HttpHandlerWithError(s.controller.GetTransfers))
+		r.Post("/transfers/{transferId}/accept", reqres.HttpHandlerWithError(s.controller.AcceptTransfer))
+		r.Post("/transfers/{transferId}/decline", reqres.HttpHandlerWithError(s.controller.DeclineTransfer))
+		r.Post("/transfers/{transferId}/cancel", reqres.HttpHandlerWithError(s.controller.CancelTransfer))
+		r.Get("/preference", reqres.HttpHandlerWithError(s.controller.GetPreference))
+		r.Put("/preference", reqres.HttpHandlerWithError(s.controller.UpdatePreference))
+		r.Get("/alias/{alias}/availability", reqres.HttpHandlerWithError(s.controller.GetAliasAvailability))
 		r.Post("/", reqres.HttpHandlerWithError(s.controller.Create))
+		r.Post("/bulk", reqres.HttpHandlerWithError(s.controller.CreateMany))
 		r.Put("/{id}", reqres.HttpHandlerWithError(s.controller.UpdateById))
 		r.Delete("/{id}", reqres.HttpHandlerWithError(s.controller.DeleteById))
 	})
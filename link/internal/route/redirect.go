@@ -12,6 +12,13 @@ type redirect struct {
 
 func (r redirect) Use(parent *chi.Mux) {
 	parent.Get("/{shortened}", reqres.HttpHandlerWithError(r.controller.Go))
+	parent.Head("/{shortened}", reqres.HttpHandlerWithError(r.controller.Head))
+	parent.Post("/{shortened}", reqres.HttpHandlerWithError(r.controller.Unlock))
+
+	// Path suffix passthrough, see `redirect.Link.PassPath`
+	parent.Get("/{shortened}/*", reqres.HttpHandlerWithError(r.controller.Go))
+	parent.Head("/{shortened}/*", reqres.HttpHandlerWithError(r.controller.Head))
+	parent.Post("/{shortened}/*", reqres.HttpHandlerWithError(r.controller.Unlock))
 }
 
 func NewRedirect(controller controller.Redirect) redirect {
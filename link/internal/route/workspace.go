@@ -0,0 +1,31 @@
+package route
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/solsteace/go-lib/reqres"
+	"github.com/solsteace/kochira/link/internal/controller"
+	"github.com/solsteace/kochira/link/internal/middleware"
+)
+
+type workspace struct {
+	controller  controller.Workspace
+	userContext middleware.UserContext
+}
+
+func (ws workspace) Use(parent *chi.Mux) {
+	workspace := chi.NewRouter()
+	workspace.Group(func(r chi.Router) {
+		r.Use(ws.userContext.Handle)
+		r.Get("/", reqres.HttpHandlerWithError(ws.controller.GetSelf))
+		r.Post("/", reqres.HttpHandlerWithError(ws.controller.Create))
+		r.Get("/{id}/members", reqres.HttpHandlerWithError(ws.controller.GetMembers))
+		r.Post("/{id}/members", reqres.HttpHandlerWithError(ws.controller.AddMember))
+		r.Put("/{id}/members/{userId}", reqres.HttpHandlerWithError(ws.controller.UpdateMember))
+		r.Delete("/{id}/members/{userId}", reqres.HttpHandlerWithError(ws.controller.RemoveMember))
+	})
+	parent.Mount("/workspace", workspace)
+}
+
+func NewWorkspace(controller controller.Workspace, userContext middleware.UserContext) workspace {
+	return workspace{controller, userContext}
+}
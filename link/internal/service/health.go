@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	shorteningMessaging "github.com/solsteace/kochira/link/internal/domain/shortening/messaging"
+	"github.com/solsteace/kochira/link/internal/domain/shortening/store"
+	"github.com/solsteace/kochira/link/internal/utility"
+	"github.com/solsteace/kochira/link/internal/utility/probe"
+)
+
+const DestinationUnhealthyExchange = "link.destinations"
+
+// How many destinations are probed at once? Requests to the same host are
+// still spaced out by the prober
+const hEALTH_WORKERS = 8
+
+// Watches the destinations of the open links
+type Health struct {
+	store     store.Health
+	prober    probe.Prober
+	interval  time.Duration // How long a checked destination is left alone?
+	threshold uint          // How many failed checks in a row make a destination unhealthy?
+	messenger *utility.Amqp // interface later
+}
+
+func NewHealth(
+	store store.Health,
+	prober probe.Prober,
+	interval time.Duration,
+	threshold uint,
+	messenger *utility.Amqp,
+) Health {
+	return Health{
+		store:     store,
+		prober:    prober,
+		interval:  interval,
+		threshold: threshold,
+		messenger: messenger}
+}
+
+// Checks the destinations of up to `limit` links that are due for a check,
+// emitting `destinationUnhealthy` message for the ones that just reached the
+// threshold
+func (hs Health) CheckDue(limit uint) error {
+	links, err := hs.store.GetDueForCheck(time.Now().Add(-hs.interval), limit)
+	if err != nil {
+		return fmt.Errorf("service<Health.CheckDue>: %w", err)
+	}
+
+	type outcome struct {
+		status  *int
+		latency time.Duration
+	}
+	outcomes := make([]outcome, len(links))
+	slots := make(chan struct{}, hEALTH_WORKERS)
+	var wg sync.WaitGroup
+	for idx, l := range links {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			// Unreachable destinations are recorded as failures, hence the
+			// error itself is of no use
+			status, latency, _ := hs.prober.Probe(context.Background(), l.Destination())
+			outcomes[idx] = outcome{status, latency}
+		}()
+	}
+	wg.Wait()
+
+	for idx := range links {
+		o := outcomes[idx]
+		unhealthy := links[idx].RecordCheck(o.status, o.latency, time.Now(), hs.threshold)
+		if err := hs.store.SaveHealth(links[idx], unhealthy); err != nil {
+			return fmt.Errorf("service<Health.CheckDue>: %w", err)
+		}
+	}
+	return nil
+}
+
+func (hs Health) PublishDestinationUnhealthy(
+	maxMsg uint,
+	serialize func(msg shorteningMessaging.DestinationUnhealthy) ([]byte, error),
+) error {
+	msg, err := hs.store.GetDestinationUnhealthy(maxMsg)
+	if err != nil {
+		return fmt.Errorf("service<Health.PublishDestinationUnhealthy>: %w", err)
+	} else if len(msg) == 0 {
+		return nil
+	}
+
+	resolved := []uint64{}
+	for _, m := range msg {
+		payload, err := serialize(m)
+		if err != nil {
+			return fmt.Errorf("service<Health.PublishDestinationUnhealthy>: %w", err)
+		}
+
+		opts := utility.NewDefaultAmqpPublishOpts(DestinationUnhealthyExchange, "", "application/json")
+		if err = hs.messenger.Publish("default", payload, opts); err != nil {
+			return fmt.Errorf("service<Health.PublishDestinationUnhealthy>: %w", err)
+		}
+		resolved = append(resolved, m.Id())
+	}
+
+	if err := hs.store.ResolveDestinationUnhealthy(resolved); err != nil {
+		return fmt.Errorf("service<Health.PublishDestinationUnhealthy>: %w", err)
+	}
+	return nil
+}
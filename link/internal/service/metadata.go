@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+	"github.com/solsteace/kochira/link/internal/domain/shortening/store"
+	"github.com/solsteace/kochira/link/internal/utility/unfurl"
+)
+
+// How many destinations are fetched at once?
+const mETADATA_WORKERS = 8
+
+// Keeps what the destination pages say about themselves, off the requests that
+// create or reconfigure the links
+type Metadata struct {
+	store   store.Metadata
+	fetcher unfurl.Fetcher
+}
+
+func NewMetadata(store store.Metadata, fetcher unfurl.Fetcher) Metadata {
+	return Metadata{
+		store:   store,
+		fetcher: fetcher}
+}
+
+// Fetches the destinations of up to `limit` links whose destination is yet to
+// be fetched. Destinations that couldn't be fetched are recorded as having
+// nothing, so they won't be retried until the destination changes
+func (ms Metadata) FetchUnfetched(limit uint) error {
+	links, err := ms.store.GetUnfetchedMetadata(limit)
+	if err != nil {
+		return fmt.Errorf("service<Metadata.FetchUnfetched>: %w", err)
+	}
+
+	pages := make([]unfurl.Page, len(links))
+	slots := make(chan struct{}, mETADATA_WORKERS)
+	var wg sync.WaitGroup
+	for idx, l := range links {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			// The page is left empty on failure, which is all there is to store
+			page, _ := ms.fetcher.Fetch(context.Background(), l.Destination())
+			pages[idx] = page
+		}()
+	}
+	wg.Wait()
+
+	for idx := range links {
+		p := pages[idx]
+		now := time.Now()
+		links[idx].SetMetadata(shortening.NewMetadata(
+			p.Title, p.OgTitle, p.OgDescription, p.OgImage, p.Favicon, &now))
+		if err := ms.store.SaveMetadata(links[idx]); err != nil {
+			return fmt.Errorf("service<Metadata.FetchUnfetched>: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+	"github.com/solsteace/kochira/link/internal/domain/shortening/store"
+)
+
+const OwnerRegisteredQueue = "link.owner_registrar"
+
+// Keeps the users announced by `account`, which are looked up by their
+// username or shown as the owner of their links
+type Owner struct {
+	store store.Owner
+}
+
+func NewOwner(store store.Owner) Owner {
+	return Owner{store: store}
+}
+
+// Owners could be announced more than once, as `account` retries the whole
+// announcement when it partly failed
+func (ow Owner) HandleOwnerRegistered(owners []shortening.Owner) error {
+	if err := ow.store.SaveOwners(owners); err != nil {
+		return fmt.Errorf("service<Owner.HandleOwnerRegistered>: %w", err)
+	}
+	return nil
+}
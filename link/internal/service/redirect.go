@@ -1,29 +1,243 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"time"
 
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/kochira/link/internal/domain/redirect"
 	"github.com/solsteace/kochira/link/internal/domain/redirect/store"
+	"github.com/solsteace/kochira/link/internal/utility/geoip"
+	"github.com/solsteace/kochira/link/internal/utility/hash"
+)
+
+const (
+	uNLOCK_FAILURE_WINDOW        = time.Minute * 15
+	uNLOCK_MAX_FAILURES_PER_IP   = 10  // Within the window, across every link
+	uNLOCK_MAX_FAILURES_PER_LINK = 100 // Within the window, across every visitor that had failed already
 )
 
 type Redirect struct {
-	store store.Shortening
+	store      store.Shortening
+	clickStore store.Click
+	ownerStore store.Owner
+	ipHasher   hash.Digester
+	hasher     hash.Handler
+	attempts   store.UnlockAttempt
+	geoip      geoip.Lookup
+	clicks     chan redirect.Click // Buffered clicks, waiting to be written by `FlushClicks`
 }
 
 // Redirects the user to the destination based on given shortened URI
-func (rs Redirect) Go(shortened string) (string, error) {
+func (rs Redirect) Go(shortened string, visit redirect.Visit) (redirect.Target, error) {
+	link, err := rs.store.GetByAlias(shortened)
+	if err != nil {
+		return redirect.Target{}, fmt.Errorf("service<Redirect.Go>: %w", err)
+	}
+	visit = rs.locate(link, visit)
+
+	target, err := link.Access(visit, false)
+	if err != nil {
+		return redirect.Target{}, fmt.Errorf("service<Redirect.Go>: %w", err)
+	}
+	if err := rs.consume(link); err != nil {
+		return redirect.Target{}, fmt.Errorf("service<Redirect.Go>: %w", err)
+	}
+
+	rs.record(redirect.NewClick(link.Id, target.Variant, visit, rs.ipHasher.Digest(visit.Ip)))
+	return target, nil
+}
+
+// Same as `Go`, but without counting the visit. Meant for `HEAD` requests
+func (rs Redirect) Peek(shortened string, visit redirect.Visit) (redirect.Target, error) {
+	link, err := rs.store.GetByAlias(shortened)
+	if err != nil {
+		return redirect.Target{}, fmt.Errorf("service<Redirect.Peek>: %w", err)
+	}
+	visit = rs.locate(link, visit)
+
+	target, err := link.Access(visit, false)
+	if err != nil {
+		return redirect.Target{}, fmt.Errorf("service<Redirect.Peek>: %w", err)
+	}
+	return target, nil
+}
+
+// Describes the link without redirecting the visitor nor counting the visit
+func (rs Redirect) Preview(shortened string, visit redirect.Visit) (redirect.Preview, error) {
+	link, err := rs.store.GetByAlias(shortened)
+	if err != nil {
+		return redirect.Preview{}, fmt.Errorf("service<Redirect.Preview>: %w", err)
+	}
+	visit = rs.locate(link, visit)
+
+	owner, err := rs.ownerStore.GetUsernameById(link.UserId)
+	if err != nil {
+		return redirect.Preview{}, fmt.Errorf("service<Redirect.Preview>: %w", err)
+	}
+	preview, err := link.Describe(visit, owner)
+	if err != nil {
+		return redirect.Preview{}, fmt.Errorf("service<Redirect.Preview>: %w", err)
+	}
+	return preview, nil
+}
+
+// Same as `Go`, but for visitors coming from the unlock form or the preview
+// page
+func (rs Redirect) Unlock(
+	shortened string,
+	password string,
+	visit redirect.Visit,
+) (redirect.Target, error) {
 	link, err := rs.store.GetByAlias(shortened)
 	if err != nil {
-		return "", fmt.Errorf("service<Redirect.Go>: %w", err)
+		return redirect.Target{}, fmt.Errorf("service<Redirect.Unlock>: %w", err)
 	}
+	visit = rs.locate(link, visit)
 
-	destination, err := link.Access()
+	if link.IsProtected() {
+		if err := rs.checkPassword(link, password, visit); err != nil {
+			return redirect.Target{}, fmt.Errorf("service<Redirect.Unlock>: %w", err)
+		}
+	}
+	target, err := link.Access(visit, true)
 	if err != nil {
-		return "", fmt.Errorf("service<Redirect.Go>: %w", err)
+		return redirect.Target{}, fmt.Errorf("service<Redirect.Unlock>: %w", err)
+	}
+	if err := rs.consume(link); err != nil {
+		return redirect.Target{}, fmt.Errorf("service<Redirect.Unlock>: %w", err)
+	}
+
+	rs.record(redirect.NewClick(link.Id, target.Variant, visit, rs.ipHasher.Digest(visit.Ip)))
+	return target, nil
+}
+
+// Compares the password, unless the visitor had failed too many times lately.
+// The attempt is counted before comparing, so concurrent ones couldn't slip
+// past the limit. Failures are limited per link as well, so guessing couldn't
+// be spread across visitors. Yet only the visitors who had failed already are
+// turned away by it, so a link under attack still lets the others in
+func (rs Redirect) checkPassword(link redirect.Link, password string, visit redirect.Visit) error {
+	visitorKey := "ip:" + rs.ipHasher.Digest(visit.Ip)
+	linkKey := "link:" + strconv.FormatUint(link.Id, 10)
+	tooMany := oops.TooManyRequests{Msg: "Too many wrong passwords, please try again later"}
+
+	attempts, ok, err := rs.attempts.TakeAttempt(visitorKey, uNLOCK_MAX_FAILURES_PER_IP, uNLOCK_FAILURE_WINDOW)
+	if err != nil {
+		return fmt.Errorf("service<Redirect.checkPassword>: %w", err)
+	} else if !ok {
+		return fmt.Errorf("service<Redirect.checkPassword>: %w", tooMany)
+	}
+	if attempts > 1 {
+		failures, err := rs.attempts.CountFailures(linkKey)
+		if err != nil {
+			return fmt.Errorf("service<Redirect.checkPassword>: %w", err)
+		} else if failures >= uNLOCK_MAX_FAILURES_PER_LINK {
+			if err := rs.attempts.ReturnAttempt(visitorKey); err != nil {
+				return fmt.Errorf("service<Redirect.checkPassword>: %w", err)
+			}
+			return fmt.Errorf("service<Redirect.checkPassword>: %w", tooMany)
+		}
+	}
+
+	compareErr := rs.hasher.Compare(link.Password, password)
+	if !errors.As(compareErr, &oops.Unauthorized{}) {
+		if err := rs.attempts.ReturnAttempt(visitorKey); err != nil {
+			return fmt.Errorf("service<Redirect.checkPassword>: %w", err)
+		}
+		if compareErr != nil {
+			return fmt.Errorf("service<Redirect.checkPassword>: %w", compareErr)
+		}
+		return nil
+	}
+	if err := rs.attempts.AddFailure(linkKey, uNLOCK_FAILURE_WINDOW); err != nil {
+		return fmt.Errorf("service<Redirect.checkPassword>: %w", err)
+	}
+	return fmt.Errorf("service<Redirect.checkPassword>: %w", compareErr)
+}
+
+// Looks up where the visit came from, only when the rules of the link need it
+func (rs Redirect) locate(link redirect.Link, v redirect.Visit) redirect.Visit {
+	if v.Country == "" && link.RoutesByCountry() {
+		v.Country = rs.geoip.Country(v.Ip)
+	}
+	return v
+}
+
+// Takes one of the remaining clicks of the link, if it had any limit. This
+// should be the last check before redirecting, as a consumed click is never
+// given back
+func (rs Redirect) consume(link redirect.Link) error {
+	if !link.HasClickLimit() {
+		return nil
+	}
+
+	ok, err := rs.store.ConsumeClick(link.Id)
+	if err != nil {
+		return fmt.Errorf("service<Redirect.consume>: %w", err)
+	} else if !ok {
+		return fmt.Errorf("service<Redirect.consume>: %w", redirect.ErrClickLimitReached)
+	}
+	return nil
+}
+
+// Queues the click without waiting. When the buffer is full, the click is
+// dropped so the redirection would never be held back by analytics
+func (rs Redirect) record(c redirect.Click) {
+	select {
+	case rs.clicks <- c:
+	default:
+	}
+}
+
+// Writes buffered clicks in batches of `maxCount` until the buffer is drained.
+// Batches that couldn't be written are logged and dropped rather than retried,
+// as clicks are only kept for analytics
+func (rs Redirect) FlushClicks(maxCount uint) error {
+	for {
+		batch := []redirect.Click{}
+	collect:
+		for uint(len(batch)) < maxCount {
+			select {
+			case c := <-rs.clicks:
+				batch = append(batch, c)
+			default:
+				break collect
+			}
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := rs.clickStore.AddClicks(batch); err != nil {
+			log.Printf("service<Redirect.FlushClicks>: dropped %d clicks: %v\n", len(batch), err)
+		}
+		if uint(len(batch)) < maxCount {
+			return nil
+		}
 	}
-	return destination, nil
 }
 
-func NewRedirect(store store.Shortening) Redirect {
-	return Redirect{store}
+func NewRedirect(
+	store store.Shortening,
+	clickStore store.Click,
+	ownerStore store.Owner,
+	ipHasher hash.Digester,
+	hasher hash.Handler,
+	attempts store.UnlockAttempt,
+	geoip geoip.Lookup,
+	clickBufferSize uint,
+) Redirect {
+	return Redirect{
+		store:      store,
+		clickStore: clickStore,
+		ownerStore: ownerStore,
+		ipHasher:   ipHasher,
+		hasher:     hasher,
+		attempts:   attempts,
+		geoip:      geoip,
+		clicks:     make(chan redirect.Click, clickBufferSize)}
 }
@@ -1,15 +1,18 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/solsteace/go-lib/oops"
 	"github.com/solsteace/kochira/link/internal/domain/shortening"
 	shorteningMessaging "github.com/solsteace/kochira/link/internal/domain/shortening/messaging"
+	shorteningService "github.com/solsteace/kochira/link/internal/domain/shortening/service"
 	"github.com/solsteace/kochira/link/internal/domain/shortening/store"
 	"github.com/solsteace/kochira/link/internal/persistence"
 	"github.com/solsteace/kochira/link/internal/utility"
+	"github.com/solsteace/kochira/link/internal/utility/hash"
 )
 
 const (
@@ -20,120 +23,1118 @@ const (
 )
 
 type Shortening struct {
-	store     store.Link[persistence.ShorteningQueryParams]
-	messenger *utility.Amqp // interface later
+	store             store.Link[persistence.ShorteningQueryParams]
+	clickStore        store.Click
+	preferenceStore   store.Preference
+	ruleStore         store.Rule
+	revisionStore     store.Revision
+	transferStore     store.Transfer
+	workspaceStore    store.Workspace
+	redirectCache     store.RedirectCache
+	hasher            hash.Handler
+	aliasPolicy       shorteningService.AliasPolicy
+	destinationPolicy shorteningService.DestinationPolicy
+	codeGenerator     shorteningService.CodeGenerator
+	messenger         *utility.Amqp // interface later
 }
 
 func NewShortening(
 	store store.Link[persistence.ShorteningQueryParams],
+	clickStore store.Click,
+	preferenceStore store.Preference,
+	ruleStore store.Rule,
+	revisionStore store.Revision,
+	transferStore store.Transfer,
+	workspaceStore store.Workspace,
+	redirectCache store.RedirectCache,
+	hasher hash.Handler,
+	aliasPolicy shorteningService.AliasPolicy,
+	destinationPolicy shorteningService.DestinationPolicy,
+	codeGenerator shorteningService.CodeGenerator,
 	messenger *utility.Amqp,
 ) Shortening {
-	return Shortening{store, messenger}
+	return Shortening{
+		store:             store,
+		clickStore:        clickStore,
+		preferenceStore:   preferenceStore,
+		ruleStore:         ruleStore,
+		revisionStore:     revisionStore,
+		transferStore:     transferStore,
+		workspaceStore:    workspaceStore,
+		redirectCache:     redirectCache,
+		hasher:            hasher,
+		aliasPolicy:       aliasPolicy,
+		destinationPolicy: destinationPolicy,
+		codeGenerator:     codeGenerator,
+		messenger:         messenger}
 }
 
-func (s Shortening) GetSelf(userId uint64, page, limit *uint) ([]shortening.Link, error) {
-	qParams := persistence.NewShorteningQueryParams(page, limit)
-	links, err := s.store.GetManyByUser(userId, qParams)
+// Tells whether the alias could be used for the link, returning the reason
+// when it couldn't. Use 0 as `linkId` when there's no link to be excluded
+func (s Shortening) CheckAlias(alias string, linkId uint64) error {
+	if err := s.aliasPolicy.Check(alias); err != nil {
+		return fmt.Errorf("service<Shortening.CheckAlias>: %w", err)
+	}
+
+	taken, err := s.store.ExistsByAliasExcept(alias, linkId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.CheckAlias>: %w", err)
+	} else if taken {
+		err := oops.BadValues{
+			Err: errors.New("alias is taken"),
+			Msg: fmt.Sprintf("Alias `%s` had already been taken", alias)}
+		return fmt.Errorf("service<Shortening.CheckAlias>: %w", err)
+	}
+	return nil
+}
+
+// Lists the links of the user. The listing is walked with `cursor` when set,
+// otherwise with `page`
+func (s Shortening) GetSelf(
+	userId uint64,
+	page *uint,
+	limit *uint,
+	cursor *shortening.LinkCursor,
+	withTotal bool,
+	filter shortening.LinkFilter,
+) (shortening.LinkPage, error) {
+	if cursor != nil && filter.Sort() != shortening.LinkSortUpdatedAt {
+		err := oops.BadValues{
+			Err: errors.New("cursor used on unsupported sort"),
+			Msg: fmt.Sprintf(
+				"Cursor could only be used when sorting by `%s`",
+				shortening.LinkSortUpdatedAt)}
+		return shortening.LinkPage{}, fmt.Errorf("service<Shortening.GetSelf>: %w", err)
+	}
+
+	qParams := persistence.NewShorteningQueryParams(page, limit, cursor, withTotal, filter)
+	result, err := s.store.GetManyByUser(userId, qParams)
+	if err != nil {
+		return shortening.LinkPage{}, fmt.Errorf("service<Shortening.GetSelf>: %w", err)
+	}
+	return result, nil
+}
+
+// Lists the links of the workspace, just like `GetSelf` does for the personal
+// links of the user
+func (s Shortening) GetByWorkspace(
+	userId uint64,
+	workspaceId uint64,
+	page *uint,
+	limit *uint,
+	cursor *shortening.LinkCursor,
+	withTotal bool,
+	filter shortening.LinkFilter,
+) (shortening.LinkPage, error) {
+	if cursor != nil && filter.Sort() != shortening.LinkSortUpdatedAt {
+		err := oops.BadValues{
+			Err: errors.New("cursor used on unsupported sort"),
+			Msg: fmt.Sprintf(
+				"Cursor could only be used when sorting by `%s`",
+				shortening.LinkSortUpdatedAt)}
+		return shortening.LinkPage{}, fmt.Errorf("service<Shortening.GetByWorkspace>: %w", err)
+	}
+	if err := s.authorizeWorkspace(userId, workspaceId, shortening.PermissionView); err != nil {
+		return shortening.LinkPage{}, fmt.Errorf("service<Shortening.GetByWorkspace>: %w", err)
+	}
+
+	qParams := persistence.NewShorteningQueryParams(page, limit, cursor, withTotal, filter)
+	result, err := s.store.GetManyByWorkspace(workspaceId, qParams)
+	if err != nil {
+		return shortening.LinkPage{}, fmt.Errorf("service<Shortening.GetByWorkspace>: %w", err)
+	}
+	return result, nil
+}
+
+func (s Shortening) GetById(userId, id uint64) (shortening.Link, error) {
+	link, err := s.getLink(userId, id, shortening.PermissionView)
+	if err != nil {
+		return shortening.Link{}, fmt.Errorf("service<Shortening.GetById>: %w", err)
+	}
+	return link, nil
+}
+
+// Retrieves the link, making sure the user is permitted to act on it. Links
+// in the trash are treated as missing
+func (s Shortening) getLink(userId, id uint64, p shortening.Permission) (shortening.Link, error) {
+	link, err := s.getAnyLink(userId, id, p)
+	if err != nil {
+		return shortening.Link{}, fmt.Errorf("service<Shortening.getLink>: %w", err)
+	} else if link.IsDeleted() {
+		err := oops.NotFound{
+			Err: errors.New("link is in the trash"),
+			Msg: fmt.Sprintf("link(id:%d) not found", id)}
+		return shortening.Link{}, fmt.Errorf("service<Shortening.getLink>: %w", err)
+	}
+	return link, nil
+}
+
+// Same as `getLink`, including the links in the trash
+func (s Shortening) getAnyLink(userId, id uint64, p shortening.Permission) (shortening.Link, error) {
+	link, err := s.store.GetById(id)
+	if err != nil {
+		return shortening.Link{}, fmt.Errorf("service<Shortening.getAnyLink>: %w", err)
+	}
+
+	var role *shortening.Role
+	if workspaceId := link.WorkspaceId(); workspaceId != nil {
+		role, err = s.roleOf(userId, *workspaceId)
+		if err != nil {
+			return shortening.Link{}, fmt.Errorf("service<Shortening.getAnyLink>: %w", err)
+		}
+	}
+	if !link.Permits(userId, role, p) {
+		return shortening.Link{}, fmt.Errorf(
+			"service<Shortening.getAnyLink>: %w",
+			oops.Forbidden{Msg: "You don't have access to this link"})
+	}
+	return link, nil
+}
+
+// Retrieves the role of the user within the workspace. Nil means the user
+// isn't a member of it
+func (s Shortening) roleOf(userId, workspaceId uint64) (*shortening.Role, error) {
+	member, err := s.workspaceStore.GetMember(workspaceId, userId)
+	switch {
+	case errors.As(err, &oops.NotFound{}):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("service<Shortening.roleOf>: %w", err)
+	}
+	role := member.Role()
+	return &role, nil
+}
+
+func (s Shortening) authorizeWorkspace(userId, workspaceId uint64, p shortening.Permission) error {
+	role, err := s.roleOf(userId, workspaceId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.authorizeWorkspace>: %w", err)
+	} else if role == nil || !role.Can(p) {
+		return fmt.Errorf(
+			"service<Shortening.authorizeWorkspace>: %w",
+			oops.Forbidden{Msg: "You don't have access to this workspace"})
+	}
+	return nil
+}
+
+func (s Shortening) GetClickCounts(
+	userId uint64,
+	id uint64,
+	granularity string,
+	from *time.Time,
+	to *time.Time,
+) ([]shortening.ClickCount, shortening.ClickWindow, error) {
+	if _, err := s.GetById(userId, id); err != nil {
+		return []shortening.ClickCount{}, shortening.ClickWindow{}, fmt.Errorf(
+			"service<Shortening.GetClickCounts>: %w", err)
+	}
+
+	g, err := shortening.NewGranularity(granularity)
+	if err != nil {
+		return []shortening.ClickCount{}, shortening.ClickWindow{}, fmt.Errorf(
+			"service<Shortening.GetClickCounts>: %w", err)
+	}
+	window, err := shortening.NewClickWindow(g, from, to)
+	if err != nil {
+		return []shortening.ClickCount{}, shortening.ClickWindow{}, fmt.Errorf(
+			"service<Shortening.GetClickCounts>: %w", err)
+	}
+
+	counts, err := s.clickStore.GetClickCounts(id, window)
+	if err != nil {
+		return []shortening.ClickCount{}, shortening.ClickWindow{}, fmt.Errorf(
+			"service<Shortening.GetClickCounts>: %w", err)
+	}
+	return counts, window, nil
+}
+
+// Returns the preference of the user, or the defaults when they had never set
+// one
+func (s Shortening) GetPreference(userId uint64) (shortening.Preference, error) {
+	p, err := s.preferenceStore.GetPreferenceByUserId(userId)
+	switch {
+	case errors.As(err, &oops.NotFound{}):
+		return shortening.NewDefaultPreference(userId), nil
+	case err != nil:
+		return shortening.Preference{}, fmt.Errorf("service<Shortening.GetPreference>: %w", err)
+	}
+	return p, nil
+}
+
+func (s Shortening) UpdatePreference(userId uint64, redirectType int) error {
+	rt, err := shortening.NewRedirectType(redirectType)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.UpdatePreference>: %w", err)
+	}
+
+	p := shortening.NewPreference(userId, rt)
+	if err := s.preferenceStore.SavePreference(p); err != nil {
+		return fmt.Errorf("service<Shortening.UpdatePreference>: %w", err)
+	}
+	return nil
+}
+
+// Creates a link. `maxClicks` limits how many times the link could be visited
+// when given. The owner's preferred redirect type is used when `redirectType`
+// is nil. The link is placed in the workspace when `workspaceId` is given
+func (s Shortening) Create(
+	userId uint64,
+	workspaceId *uint64,
+	destination string,
+	maxClicks *uint,
+	redirectType *int,
+	activeFrom *time.Time,
+	activeUntil *time.Time,
+) error {
+	if workspaceId != nil {
+		if err := s.authorizeWorkspace(userId, *workspaceId, shortening.PermissionEdit); err != nil {
+			return fmt.Errorf("service<Shortening.Create>: %w", err)
+		}
+	}
+	p, err := s.GetPreference(userId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.Create>: %w", err)
+	}
+
+	if err := s.destinationPolicy.Check(destination); err != nil {
+		return fmt.Errorf("service<Shortening.Create>: %w", err)
+	}
+	newLink, err := s.newLink(userId, workspaceId, destination, maxClicks, redirectType, p)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.Create>: %w", err)
+	}
+	if err := newLink.Schedule(activeFrom, activeUntil); err != nil {
+		return fmt.Errorf("service<Shortening.Create>: %w", err)
+	}
+
+	// Collisions are retried with a fresh code, which gets longer as they
+	// keep coming
+	for attempt := uint(1); ; attempt++ {
+		err := s.store.Create(newLink)
+		if !errors.As(err, &shortening.ShortenedTaken{}) || attempt >= sHORTEN_MAX_ATTEMPTS {
+			if err != nil {
+				return fmt.Errorf("service<Shortening.Create>: %w", err)
+			}
+			return nil
+		}
+		if err := s.shorten(&newLink, attempt); err != nil {
+			return fmt.Errorf("service<Shortening.Create>: %w", err)
+		}
+	}
+}
+
+// How many times a new link is tried to be stored before its collisions are
+// reported as is?
+const sHORTEN_MAX_ATTEMPTS = 8
+
+// Gives the link a code from the generator. `attempt` counts the collisions
+// the link had met so far
+func (s Shortening) shorten(l *shortening.Link, attempt uint) error {
+	code, err := s.codeGenerator.Generate(l.Destination(), attempt)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.shorten>: %w", err)
+	}
+	if err := l.Shorten(code); err != nil {
+		return fmt.Errorf("service<Shortening.shorten>: %w", err)
+	}
+	return nil
+}
+
+// Builds a shortened link that is yet to be approved by subscription check.
+// The destination should had been checked against the policy beforehand
+func (s Shortening) newLink(
+	userId uint64,
+	workspaceId *uint64,
+	destination string,
+	maxClicks *uint,
+	redirectType *int,
+	p shortening.Preference,
+) (shortening.Link, error) {
+	now := time.Now()
+	newLink, err := shortening.NewLink(
+		nil,
+		userId,
+		"",
+		"",
+		destination,
+		false,
+		now,
+		now)
+	if err != nil {
+		return shortening.Link{}, fmt.Errorf("service<Shortening.newLink>: %w", err)
+	}
+
+	if workspaceId != nil {
+		newLink.PlaceIn(*workspaceId)
+	}
+	if maxClicks != nil {
+		if err := newLink.LimitClicks(*maxClicks, *maxClicks); err != nil {
+			return shortening.Link{}, fmt.Errorf("service<Shortening.newLink>: %w", err)
+		}
+	}
+
+	newLink.SetRedirectType(p.RedirectType())
+	if redirectType != nil {
+		rt, err := shortening.NewRedirectType(*redirectType)
+		if err != nil {
+			return shortening.Link{}, fmt.Errorf("service<Shortening.newLink>: %w", err)
+		}
+		newLink.SetRedirectType(rt)
+	}
+
+	if err := s.shorten(&newLink, 0); err != nil {
+		return shortening.Link{}, fmt.Errorf("service<Shortening.newLink>: %w", err)
+	}
+	return newLink, nil
+}
+
+// How many links could be created in a single bulk request?
+const BulkMaxRows = 1000
+
+// A single link requested through `CreateMany`
+type BulkRow struct {
+	Destination  string
+	MaxClicks    *uint
+	RedirectType *int
+}
+
+// Outcome of a single `BulkRow`. Either `Link` or `Err` is set
+type BulkResult struct {
+	Id   uint64 // Set along with `Link`
+	Link *shortening.Link
+	Err  error
+}
+
+// Creates many links at once. Rows that fail validation are reported in their
+// results and left out, while the rest go through a single subscription check
+// that approves or rejects them as a whole. The links are placed in the
+// workspace when `workspaceId` is given
+func (s Shortening) CreateMany(
+	userId uint64,
+	workspaceId *uint64,
+	rows []BulkRow,
+) ([]BulkResult, error) {
+	switch {
+	case len(rows) == 0:
+		err := oops.BadValues{
+			Err: errors.New("empty batch"),
+			Msg: "There should be at least one link to be created"}
+		return []BulkResult{}, fmt.Errorf("service<Shortening.CreateMany>: %w", err)
+	case len(rows) > BulkMaxRows:
+		err := oops.BadValues{
+			Err: errors.New("batch too large"),
+			Msg: fmt.Sprintf(
+				"Only up to %d links could be created at once (get: %d)",
+				BulkMaxRows, len(rows))}
+		return []BulkResult{}, fmt.Errorf("service<Shortening.CreateMany>: %w", err)
+	}
+
+	if workspaceId != nil {
+		if err := s.authorizeWorkspace(userId, *workspaceId, shortening.PermissionEdit); err != nil {
+			return []BulkResult{}, fmt.Errorf("service<Shortening.CreateMany>: %w", err)
+		}
+	}
+	p, err := s.GetPreference(userId)
+	if err != nil {
+		return []BulkResult{}, fmt.Errorf("service<Shortening.CreateMany>: %w", err)
+	}
+
+	// Destinations are checked all at once, as each may wait on a DNS lookup
+	destinations := []string{}
+	for _, r := range rows {
+		destinations = append(destinations, r.Destination)
+	}
+	checks := s.destinationPolicy.CheckMany(destinations)
+
+	results := make([]BulkResult, len(rows))
+	newLinks := []shortening.Link{}
+	newLinkRows := []int{} // Which row each of `newLinks` came from
+	for idx, r := range rows {
+		if checks[idx] != nil {
+			err := fmt.Errorf("service<Shortening.CreateMany>: %w", checks[idx])
+			results[idx] = BulkResult{Err: err}
+			continue
+		}
+		newLink, err := s.newLink(userId, workspaceId, r.Destination, r.MaxClicks, r.RedirectType, p)
+		if err != nil {
+			results[idx] = BulkResult{Err: err}
+			continue
+		}
+		newLinks = append(newLinks, newLink)
+		newLinkRows = append(newLinkRows, idx)
+	}
+
+	// The batch is stored as a whole, so only the colliding link gets a fresh
+	// code before the batch is tried again
+	attempts := make([]uint, len(newLinks))
+	var ids []uint64
+	for {
+		created, err := s.store.CreateMany(newLinks)
+		ids = created
+		taken := shortening.ShortenedTaken{}
+		if !errors.As(err, &taken) || attempts[taken.Index]+1 >= sHORTEN_MAX_ATTEMPTS {
+			if err != nil {
+				return []BulkResult{}, fmt.Errorf("service<Shortening.CreateMany>: %w", err)
+			}
+			break
+		}
+
+		attempts[taken.Index]++
+		if err := s.shorten(&newLinks[taken.Index], attempts[taken.Index]); err != nil {
+			return []BulkResult{}, fmt.Errorf("service<Shortening.CreateMany>: %w", err)
+		}
+	}
+
+	for idx := range newLinks {
+		results[newLinkRows[idx]] = BulkResult{Id: ids[idx], Link: &newLinks[idx]}
+	}
+	return results, nil
+}
+
+// Walks every link the user owns. Links placed in workspaces are left out, as
+// they belong to the workspace rather than whoever created them
+func (s Shortening) Export(userId uint64, each func(l shortening.Link) error) error {
+	if err := s.store.EachByUser(userId, each); err != nil {
+		return fmt.Errorf("service<Shortening.Export>: %w", err)
+	}
+	return nil
+}
+
+// A single link requested through `Import`
+type ImportRow struct {
+	Alias       string // Empty when the link had no custom alias
+	Destination string
+}
+
+// Re-creates exported links as a single batch, approved the same way as
+// `CreateMany`. Custom aliases are then requested through the subscription
+// gated path, hence they're only taken once the subscription allows. Rows
+// whose alias is unavailable are reported in their results and left out.
+// Results having both `Link` and `Err` are links created without their alias,
+// since it was taken in the meantime
+func (s Shortening) Import(userId uint64, rows []ImportRow) ([]BulkResult, error) {
+	results := make([]BulkResult, len(rows))
+	bulkRows := []BulkRow{}
+	bulkRowIdx := []int{} // Which row each of `bulkRows` came from
+	claimed := map[string]bool{}
+	for idx, r := range rows {
+		if r.Alias != "" {
+			if claimed[r.Alias] {
+				err := oops.BadValues{
+					Err: errors.New("alias is repeated"),
+					Msg: fmt.Sprintf("Alias `%s` had already been claimed by an earlier row", r.Alias)}
+				results[idx] = BulkResult{Err: err}
+				continue
+			}
+			claimed[r.Alias] = true
+
+			err := s.CheckAlias(r.Alias, 0)
+			switch {
+			case errors.As(err, &oops.BadValues{}):
+				results[idx] = BulkResult{Err: err}
+				continue
+			case err != nil:
+				return []BulkResult{}, fmt.Errorf("service<Shortening.Import>: %w", err)
+			}
+		}
+		bulkRows = append(bulkRows, BulkRow{Destination: r.Destination})
+		bulkRowIdx = append(bulkRowIdx, idx)
+	}
+	if len(rows) > 0 && len(bulkRows) == 0 {
+		return results, nil
+	}
+
+	created, err := s.CreateMany(userId, nil, bulkRows)
+	if err != nil {
+		return []BulkResult{}, fmt.Errorf("service<Shortening.Import>: %w", err)
+	}
+	for i, result := range created {
+		idx := bulkRowIdx[i]
+		results[idx] = result
+		if result.Err != nil || rows[idx].Alias == "" {
+			continue
+		}
+
+		// Stored links are the ones having their id, which the request needs.
+		// The link is requested open, so the request doesn't close it in case
+		// the batch was approved first. Opening it still takes the quota, so a
+		// batch the quota couldn't cover stays closed
+		newLink, err := s.store.GetById(result.Id)
+		if err != nil {
+			return []BulkResult{}, fmt.Errorf("service<Shortening.Import>: %w", err)
+		}
+		err = s.reconfigure(
+			userId,
+			newLink,
+			rows[idx].Alias,
+			newLink.Destination(),
+			true,
+			ShorteningSettings{},
+			true)
+		switch {
+		case errors.As(err, &oops.BadValues{}):
+			results[idx].Err = err
+		case err != nil:
+			return []BulkResult{}, fmt.Errorf("service<Shortening.Import>: %w", err)
+		}
+	}
+	return results, nil
+}
+
+// Optional settings of a link. Nil fields are left untouched
+type ShorteningSettings struct {
+	Password     *string // Empty removes the protection
+	MaxClicks    *uint   // Zero removes the limit
+	RedirectType *int
+	PassQuery    *bool
+	PassPath     *bool
+	Preview      *bool
+	Tags         *[]string            // Replaces every tag of the link
+	Folder       *string              // Empty takes the link out of its folder
+	ActiveFrom   *time.Time           // Zero time removes the bound
+	ActiveUntil  *time.Time           // Zero time removes the bound
+	Variants     *[]ShorteningVariant // Empty removes the split
+}
+
+// A single variant requested through `ShorteningSettings`
+type ShorteningVariant struct {
+	Destination string
+	Weight      uint
+}
+
+func (s Shortening) applySettings(l *shortening.Link, settings ShorteningSettings) error {
+	switch {
+	case settings.Password == nil:
+	case *settings.Password == "":
+		l.Unprotect()
+	default:
+		if err := shortening.ValidatePassword(*settings.Password); err != nil {
+			return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+		}
+		digest, err := s.hasher.Generate(*settings.Password)
+		if err != nil {
+			return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+		}
+		l.Protect(string(digest))
+	}
+
+	switch {
+	case settings.MaxClicks == nil:
+	case *settings.MaxClicks == 0:
+		l.UnlimitClicks()
+	default:
+		err := l.LimitClicks(*settings.MaxClicks, *settings.MaxClicks)
+		if err != nil {
+			return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+		}
+	}
+
+	if settings.RedirectType != nil {
+		rt, err := shortening.NewRedirectType(*settings.RedirectType)
+		if err != nil {
+			return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+		}
+		l.SetRedirectType(rt)
+	}
+
+	passQuery := l.PassesQuery()
+	if settings.PassQuery != nil {
+		passQuery = *settings.PassQuery
+	}
+	passPath := l.PassesPath()
+	if settings.PassPath != nil {
+		passPath = *settings.PassPath
+	}
+	l.SetPassthrough(passQuery, passPath)
+
+	if settings.Preview != nil {
+		l.SetPreview(*settings.Preview)
+	}
+	if settings.Tags != nil {
+		if err := l.Tag(*settings.Tags); err != nil {
+			return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+		}
+	}
+	if settings.Folder != nil {
+		if err := l.PutInFolder(*settings.Folder); err != nil {
+			return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+		}
+	}
+
+	activeFrom := l.ActiveFrom()
+	if settings.ActiveFrom != nil {
+		activeFrom = settings.ActiveFrom
+		if activeFrom.IsZero() {
+			activeFrom = nil
+		}
+	}
+	activeUntil := l.ActiveUntil()
+	if settings.ActiveUntil != nil {
+		activeUntil = settings.ActiveUntil
+		if activeUntil.IsZero() {
+			activeUntil = nil
+		}
+	}
+	if err := l.Schedule(activeFrom, activeUntil); err != nil {
+		return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+	}
+
+	if settings.Variants != nil {
+		known := map[string]struct{}{}
+		for _, v := range l.Variants() {
+			known[v.Destination()] = struct{}{}
+		}
+
+		variants := []shortening.Variant{}
+		for _, v := range *settings.Variants {
+			variant, err := shortening.NewVariant(nil, v.Destination, v.Weight, 0)
+			if err != nil {
+				return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+			}
+			if _, ok := known[v.Destination]; !ok {
+				if err := s.destinationPolicy.Check(v.Destination); err != nil {
+					return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+				}
+			}
+			variants = append(variants, variant)
+		}
+		if err := l.SplitTraffic(variants); err != nil {
+			return fmt.Errorf("service<Shortening.applySettings>: %w", err)
+		}
+	}
+	return nil
+}
+
+// Updates the link along with its optional settings
+func (s Shortening) UpdateById(
+	userId uint64,
+	id uint64,
+	alias string,
+	destination string,
+	isOpen bool,
+	settings ShorteningSettings,
+) error {
+	oldLink, err := s.getLink(userId, id, shortening.PermissionEdit)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.UpdateById>: %w", err)
+	}
+
+	requirePremiumSubscription := oldLink.HasCustomAlias()
+	err = s.reconfigure(
+		userId,
+		oldLink,
+		alias,
+		destination,
+		isOpen,
+		settings,
+		requirePremiumSubscription)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.UpdateById>: %w", err)
+	}
+	return nil
+}
+
+// Applies the new values to the link. When `requirePremiumSubscription`, only
+// the settings are applied right away, while the rest waits for the approval
+// through `shortConfigured` message
+func (s Shortening) reconfigure(
+	userId uint64,
+	oldLink shortening.Link,
+	alias string,
+	destination string,
+	isOpen bool,
+	settings ShorteningSettings,
+	requirePremiumSubscription bool,
+) error {
+	// Going back to the generated alias is always allowed
+	if alias != oldLink.Alias() && alias != oldLink.Shortened() {
+		if err := s.CheckAlias(alias, oldLink.Id()); err != nil {
+			return fmt.Errorf("service<Shortening.reconfigure>: %w", err)
+		}
+	}
+	if destination != oldLink.Destination() {
+		if err := s.destinationPolicy.Check(destination); err != nil {
+			return fmt.Errorf("service<Shortening.reconfigure>: %w", err)
+		}
+	}
+
+	newLink, err := oldLink.Reconfigure(
+		alias,
+		destination,
+		isOpen,
+		oldLink.UpdatedAt(),
+		oldLink.ExpiredAt())
+	if err != nil {
+		return fmt.Errorf("service<Shortening.reconfigure>: %w", err)
+	}
+	if err := s.applySettings(&newLink, settings); err != nil {
+		return fmt.Errorf("service<Shortening.reconfigure>: %w", err)
+	}
+
+	if requirePremiumSubscription {
+		err = s.store.UpdateWithSubscription(newLink, userId)
+	} else {
+		err = s.store.Update(newLink, &userId)
+	}
+	if err != nil {
+		return fmt.Errorf("service<Shortening.reconfigure>: %w", err)
+	}
+
+	// Settings are applied right away on both paths
+	if err := s.redirectCache.Invalidate(oldLink.Alias(), newLink.Alias()); err != nil {
+		return fmt.Errorf("service<Shortening.reconfigure>: %w", err)
+	}
+	return nil
+}
+
+// Moves the link into the trash, where it stops redirecting right away. The
+// link is only deleted for good once purged, see `PurgeTrash`
+func (s Shortening) DeleteById(userId, id uint64) error {
+	link, err := s.getLink(userId, id, shortening.PermissionManage)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.DeleteById>: %w", err)
+	}
+	if err := link.Trash(time.Now()); err != nil {
+		return fmt.Errorf("service<Shortening.DeleteById>: %w", err)
+	}
+
+	if err := s.store.SaveDeletion(link, &userId); err != nil {
+		return fmt.Errorf("service<Shortening.DeleteById>: %w", err)
+	}
+	if err := s.redirectCache.Invalidate(link.Alias()); err != nil {
+		return fmt.Errorf("service<Shortening.DeleteById>: %w", err)
+	}
+	return nil
+}
+
+// Retrieves the links in the trash that the user could restore, which are
+// their personal links and the ones of the workspaces they could manage
+func (s Shortening) GetTrash(userId uint64) ([]shortening.Link, error) {
+	roles := shortening.RolesAllowedTo(shortening.PermissionManage)
+	links, err := s.store.GetTrashByUser(userId, roles)
+	if err != nil {
+		return []shortening.Link{}, fmt.Errorf("service<Shortening.GetTrash>: %w", err)
+	}
+	return links, nil
+}
+
+// Takes the link out of the trash, keeping the alias it had reserved. The link
+// comes back closed, so reopening it goes through the same checks as any
+// other update
+func (s Shortening) Restore(userId, id uint64) error {
+	link, err := s.getAnyLink(userId, id, shortening.PermissionManage)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.Restore>: %w", err)
+	}
+	if err := link.Restore(); err != nil {
+		return fmt.Errorf("service<Shortening.Restore>: %w", err)
+	}
+
+	if err := s.store.SaveDeletion(link, &userId); err != nil {
+		return fmt.Errorf("service<Shortening.Restore>: %w", err)
+	}
+	if err := s.redirectCache.Invalidate(link.Alias()); err != nil {
+		return fmt.Errorf("service<Shortening.Restore>: %w", err)
+	}
+	return nil
+}
+
+// Deletes up to `limit` links for good, which had been in the trash for longer
+// than `retention`. Their aliases are free to be taken afterwards
+func (s Shortening) PurgeTrash(retention time.Duration, limit uint) error {
+	if err := s.store.PurgeDeletedBefore(time.Now().Add(-retention), limit); err != nil {
+		return fmt.Errorf("service<Shortening.PurgeTrash>: %w", err)
+	}
+	return nil
+}
+
+// ===================================
+// Revisions
+// ===================================
+
+func (s Shortening) GetRevisions(userId, linkId uint64) ([]shortening.Revision, error) {
+	if _, err := s.GetById(userId, linkId); err != nil {
+		return []shortening.Revision{}, fmt.Errorf("service<Shortening.GetRevisions>: %w", err)
+	}
+
+	revisions, err := s.revisionStore.GetRevisionsByLinkId(linkId)
+	if err != nil {
+		return []shortening.Revision{}, fmt.Errorf("service<Shortening.GetRevisions>: %w", err)
+	}
+	return revisions, nil
+}
+
+// Brings the link back to how it was right after the revision. Restoring a
+// custom alias goes through the subscription check, just like setting one
+func (s Shortening) RestoreRevision(userId, linkId uint64, number uint) error {
+	oldLink, err := s.getLink(userId, linkId, shortening.PermissionEdit)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.RestoreRevision>: %w", err)
+	}
+
+	revision, err := s.revisionStore.GetRevision(linkId, number)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.RestoreRevision>: %w", err)
+	}
+
+	restored := revision.After()
+	requirePremiumSubscription := restored.Alias() != oldLink.Shortened()
+	err = s.reconfigure(
+		userId,
+		oldLink,
+		restored.Alias(),
+		restored.Destination(),
+		restored.IsOpen(),
+		ShorteningSettings{},
+		requirePremiumSubscription)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.RestoreRevision>: %w", err)
+	}
+	return nil
+}
+
+// ===================================
+// Transfers
+// ===================================
+
+// Retrieves the transfers the user is involved in, either as the owner or as
+// the recipient
+func (s Shortening) GetTransfers(userId uint64) ([]shortening.Transfer, error) {
+	transfers, err := s.transferStore.GetTransfersByUser(userId)
+	if err != nil {
+		return []shortening.Transfer{}, fmt.Errorf("service<Shortening.GetTransfers>: %w", err)
+	}
+	return transfers, nil
+}
+
+// Starts handing the link over to the user having the username
+func (s Shortening) CreateTransfer(userId, linkId uint64, username string) error {
+	link, err := s.getLink(userId, linkId, shortening.PermissionManage)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.CreateTransfer>: %w", err)
+	}
+
+	if exists, err := s.transferStore.ExistsOpenTransferByLinkId(linkId); err != nil {
+		return fmt.Errorf("service<Shortening.CreateTransfer>: %w", err)
+	} else if exists {
+		err := oops.Forbidden{
+			Err: errors.New("transfer in progress"),
+			Msg: "Link is already being transferred"}
+		return fmt.Errorf("service<Shortening.CreateTransfer>: %w", err)
+	}
+
+	toUserId, err := s.transferStore.GetUserIdByUsername(username)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.CreateTransfer>: %w", err)
+	}
+	transfer, err := link.TransferTo(toUserId, time.Now())
+	if err != nil {
+		return fmt.Errorf("service<Shortening.CreateTransfer>: %w", err)
+	}
+
+	if _, err := s.transferStore.CreateTransfer(transfer); err != nil {
+		return fmt.Errorf("service<Shortening.CreateTransfer>: %w", err)
+	}
+	return nil
+}
+
+// Accepts the transfer on behalf of the recipient. The link only changes
+// hands once the recipient's subscription could take it, see
+// `HandleTransferAccepted`
+func (s Shortening) AcceptTransfer(userId, transferId uint64) error {
+	transfer, err := s.getTransfer(userId, transferId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.AcceptTransfer>: %w", err)
+	}
+
+	if err := transfer.Accept(userId, time.Now()); err != nil {
+		return fmt.Errorf("service<Shortening.AcceptTransfer>: %w", err)
+	}
+	if err := s.transferStore.AcceptTransfer(transfer); err != nil {
+		return fmt.Errorf("service<Shortening.AcceptTransfer>: %w", err)
+	}
+	return nil
+}
+
+func (s Shortening) DeclineTransfer(userId, transferId uint64) error {
+	transfer, err := s.getTransfer(userId, transferId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.DeclineTransfer>: %w", err)
+	}
+
+	if err := transfer.Decline(userId, time.Now()); err != nil {
+		return fmt.Errorf("service<Shortening.DeclineTransfer>: %w", err)
+	}
+	if err := s.transferStore.UpdateTransfer(transfer); err != nil {
+		return fmt.Errorf("service<Shortening.DeclineTransfer>: %w", err)
+	}
+	return nil
+}
+
+func (s Shortening) CancelTransfer(userId, transferId uint64) error {
+	transfer, err := s.getTransfer(userId, transferId)
 	if err != nil {
-		return []shortening.Link{}, fmt.Errorf("service<Shortening.GetSelf>: %w", err)
+		return fmt.Errorf("service<Shortening.CancelTransfer>: %w", err)
 	}
-	return links, nil
+
+	if err := transfer.Cancel(userId, time.Now()); err != nil {
+		return fmt.Errorf("service<Shortening.CancelTransfer>: %w", err)
+	}
+	if err := s.transferStore.UpdateTransfer(transfer); err != nil {
+		return fmt.Errorf("service<Shortening.CancelTransfer>: %w", err)
+	}
+	return nil
 }
 
-func (s Shortening) GetById(userId, id uint64) (shortening.Link, error) {
-	link, err := s.store.GetById(id)
+// Retrieves the transfer, making sure the user is involved in it
+func (s Shortening) getTransfer(userId, transferId uint64) (shortening.Transfer, error) {
+	transfer, err := s.transferStore.GetTransferById(transferId)
 	if err != nil {
-		return shortening.Link{}, fmt.Errorf("service<Shortening.GetById>: %w", err)
+		return shortening.Transfer{}, fmt.Errorf("service<Shortening.getTransfer>: %w", err)
+	} else if !transfer.InvolvedBy(userId) {
+		err := oops.NotFound{
+			Err: errors.New("transfer of other users"),
+			Msg: fmt.Sprintf("link_transfers(id:%d) not found", transferId)}
+		return shortening.Transfer{}, fmt.Errorf("service<Shortening.getTransfer>: %w", err)
+	}
+	return transfer, nil
+}
+
+// ===================================
+// Routing rules
+// ===================================
+
+func (s Shortening) GetRules(userId, linkId uint64) ([]shortening.Rule, error) {
+	if _, err := s.GetById(userId, linkId); err != nil {
+		return []shortening.Rule{}, fmt.Errorf("service<Shortening.GetRules>: %w", err)
 	}
 
-	if !link.AccessibleBy(userId) {
-		return shortening.Link{}, fmt.Errorf(
-			"service<Shortening.GetById>: %w",
-			oops.Forbidden{Msg: "You don't have access to this link"})
+	rules, err := s.ruleStore.GetRulesByLinkId(linkId)
+	if err != nil {
+		return []shortening.Rule{}, fmt.Errorf("service<Shortening.GetRules>: %w", err)
 	}
-	return link, nil
+	return rules, nil
 }
 
-func (s Shortening) Create(userId uint64, destination string) error {
-	now := time.Now()
-	newLink, err := shortening.NewLink(
-		nil,
-		userId,
-		"",
-		"",
-		destination,
-		false,
-		now,
-		now)
+// Adds a routing rule to the link. Nil `position` puts the rule last
+func (s Shortening) CreateRule(
+	userId uint64,
+	linkId uint64,
+	position *uint,
+	condition string,
+	values []string,
+	destination string,
+) error {
+	link, err := s.getLink(userId, linkId, shortening.PermissionEdit)
 	if err != nil {
-		return fmt.Errorf("service<Shortening.Create>: %w", err)
+		return fmt.Errorf("service<Shortening.CreateRule>: %w", err)
 	}
 
-	newLink.Shorten()
-	if err := s.store.Create(newLink); err != nil {
-		return fmt.Errorf("service<Shortening.Create>: %w", err)
+	count, err := s.ruleStore.CountRulesByLinkId(linkId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.CreateRule>: %w", err)
+	} else if err := shortening.CanAddRule(count); err != nil {
+		return fmt.Errorf("service<Shortening.CreateRule>: %w", err)
+	}
+
+	actualPosition := count
+	if position != nil {
+		actualPosition = *position
+	}
+	rule, err := shortening.NewRule(nil, linkId, actualPosition, condition, values, destination)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.CreateRule>: %w", err)
+	} else if err := s.destinationPolicy.Check(rule.Destination()); err != nil {
+		return fmt.Errorf("service<Shortening.CreateRule>: %w", err)
+	}
+
+	if _, err := s.ruleStore.CreateRule(rule); err != nil {
+		return fmt.Errorf("service<Shortening.CreateRule>: %w", err)
+	}
+	if err := s.redirectCache.Invalidate(link.Alias()); err != nil {
+		return fmt.Errorf("service<Shortening.CreateRule>: %w", err)
 	}
 	return nil
 }
 
-func (s Shortening) UpdateById(
+// Replaces the routing rule. Nil `position` keeps the rule where it was
+func (s Shortening) UpdateRuleById(
 	userId uint64,
-	id uint64,
-	alias string,
+	linkId uint64,
+	ruleId uint64,
+	position *uint,
+	condition string,
+	values []string,
 	destination string,
-	isOpen bool,
 ) error {
-	oldLink, err := s.store.GetById(id)
+	link, oldRule, err := s.getRule(userId, linkId, ruleId)
 	if err != nil {
-		return fmt.Errorf("service<Shortening.UpdateById>: %w", err)
-	} else if !oldLink.AccessibleBy(userId) {
-		return fmt.Errorf(
-			"service<Shortening.DeleteById>: %w",
-			oops.Forbidden{Msg: "You don't have access to this link"})
+		return fmt.Errorf("service<Shortening.UpdateRuleById>: %w", err)
 	}
 
-	requirePremiumSubscription := oldLink.HasCustomAlias()
-	newLink, err := shortening.NewLink(
-		&id,
-		userId,
-		oldLink.Shortened(),
-		alias,
-		destination,
-		isOpen,
-		oldLink.UpdatedAt(),
-		oldLink.ExpiredAt())
+	actualPosition := oldRule.Position()
+	if position != nil {
+		actualPosition = *position
+	}
+	id := oldRule.Id()
+	rule, err := shortening.NewRule(&id, linkId, actualPosition, condition, values, destination)
 	if err != nil {
-		return fmt.Errorf("service<Shortening.UpdateById>: %w", err)
+		return fmt.Errorf("service<Shortening.UpdateRuleById>: %w", err)
+	}
+	if rule.Destination() != oldRule.Destination() {
+		if err := s.destinationPolicy.Check(rule.Destination()); err != nil {
+			return fmt.Errorf("service<Shortening.UpdateRuleById>: %w", err)
+		}
 	}
 
-	if requirePremiumSubscription {
-		err = s.store.UpdateWithSubscription(newLink)
-	} else {
-		err = s.store.Update(newLink)
+	if err := s.ruleStore.UpdateRule(rule); err != nil {
+		return fmt.Errorf("service<Shortening.UpdateRuleById>: %w", err)
 	}
-	if err != nil {
-		return fmt.Errorf("service<Shortening.UpdateById>: %w", err)
+	if err := s.redirectCache.Invalidate(link.Alias()); err != nil {
+		return fmt.Errorf("service<Shortening.UpdateRuleById>: %w", err)
 	}
 	return nil
 }
 
-// CRUD delete
-func (s Shortening) DeleteById(userId, id uint64) error {
-	link, err := s.store.GetById(id)
+func (s Shortening) DeleteRuleById(userId, linkId, ruleId uint64) error {
+	link, rule, err := s.getRule(userId, linkId, ruleId)
 	if err != nil {
-		return fmt.Errorf("service<Shortening.DeleteById>: %w", err)
-	} else if !link.AccessibleBy(userId) {
-		return fmt.Errorf(
-			"service<Shortening.DeleteById>: %w",
-			oops.Forbidden{Msg: "You don't have access to this link"})
+		return fmt.Errorf("service<Shortening.DeleteRuleById>: %w", err)
 	}
 
-	if err := s.store.DeleteById(id); err != nil {
-		return fmt.Errorf("service<Shortening.DeleteById>: %w", err)
+	if err := s.ruleStore.DeleteRuleById(rule.Id()); err != nil {
+		return fmt.Errorf("service<Shortening.DeleteRuleById>: %w", err)
+	}
+	if err := s.redirectCache.Invalidate(link.Alias()); err != nil {
+		return fmt.Errorf("service<Shortening.DeleteRuleById>: %w", err)
 	}
 	return nil
 }
 
+// Retrieves the rule along with its link, making sure the user could edit
+// both
+func (s Shortening) getRule(
+	userId uint64,
+	linkId uint64,
+	ruleId uint64,
+) (shortening.Link, shortening.Rule, error) {
+	link, err := s.getLink(userId, linkId, shortening.PermissionEdit)
+	if err != nil {
+		return shortening.Link{}, shortening.Rule{}, fmt.Errorf("service<Shortening.getRule>: %w", err)
+	}
+
+	rule, err := s.ruleStore.GetRuleById(ruleId)
+	if err != nil {
+		return shortening.Link{}, shortening.Rule{}, fmt.Errorf("service<Shortening.getRule>: %w", err)
+	} else if rule.LinkId() != linkId {
+		err := oops.NotFound{
+			Err: errors.New("rule of another link"),
+			Msg: fmt.Sprintf("Rule(id:%d) not found on Link(id:%d)", ruleId, linkId)}
+		return shortening.Link{}, shortening.Rule{}, fmt.Errorf("service<Shortening.getRule>: %w", err)
+	}
+	return link, rule, nil
+}
+
 // ===================================
 // Events
 // ===================================
@@ -201,27 +1202,70 @@ func (s Shortening) PublishShortConfigured(
 	return nil
 }
 
-// # TODO
-//
-// There's a chance of race condition if this function was called in many goroutines
-// with the same userId.
-//
-// ## Reason
-//
-// Race condition "happens in the database-level". There's a chance that, after goroutine
-// `A` fetches current user stats, goroutine `B“ do writes that affects this stats. This
-// would disrupt the subscription perk handling.
-//
-// For example, goroutine `A` thought user `X` only has 1 link quota left. However,
-// after `A` deducted this, goroutine `B` adds a new link which reduces the quota
-// to 0. This makes `A`'s view on current user stats doesn't sync with what actually
-// in the database.
-//
-// ## Suggestions
-//
-// - optimistic locking (via updatedAt). Challenge: Requires retry mechanism whenever needed
-//
-// - row locking. Challenge: May need to inject business logic between database queries
+func (s Shortening) PublishBulkShortened(
+	maxMsg uint,
+	serialize func(msg shorteningMessaging.BulkShortened) ([]byte, error),
+) error {
+	msg, err := s.store.GetBulkShortened(maxMsg)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.PublishBulkShortened>: %w", err)
+	} else if len(msg) == 0 {
+		return nil
+	}
+
+	resolved := []uint64{}
+	for _, m := range msg {
+		payload, err := serialize(m)
+		if err != nil {
+			return fmt.Errorf("service<Shortening.PublishBulkShortened>: %w", err)
+		}
+
+		opts := utility.NewDefaultAmqpPublishOpts("", CheckSubscriptionQueue, "application/json")
+		if err = s.messenger.Publish("default", payload, opts); err != nil {
+			return fmt.Errorf("service<Shortening.PublishBulkShortened>: %w", err)
+		}
+		resolved = append(resolved, m.Id())
+	}
+
+	if err := s.store.ResolveBulkShortened(resolved); err != nil {
+		return fmt.Errorf("service<Shortening.PublishBulkShortened>: %w", err)
+	}
+	return nil
+}
+
+func (s Shortening) PublishTransferAccepted(
+	maxMsg uint,
+	serialize func(msg shorteningMessaging.TransferAccepted) ([]byte, error),
+) error {
+	msg, err := s.transferStore.GetTransferAccepted(maxMsg)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.PublishTransferAccepted>: %w", err)
+	} else if len(msg) == 0 {
+		return nil
+	}
+
+	resolved := []uint64{}
+	for _, m := range msg {
+		payload, err := serialize(m)
+		if err != nil {
+			return fmt.Errorf("service<Shortening.PublishTransferAccepted>: %w", err)
+		}
+
+		opts := utility.NewDefaultAmqpPublishOpts("", CheckSubscriptionQueue, "application/json")
+		if err = s.messenger.Publish("default", payload, opts); err != nil {
+			return fmt.Errorf("service<Shortening.PublishTransferAccepted>: %w", err)
+		}
+		resolved = append(resolved, m.Id())
+	}
+
+	if err := s.transferStore.ResolveTransferAccepted(resolved); err != nil {
+		return fmt.Errorf("service<Shortening.PublishTransferAccepted>: %w", err)
+	}
+	return nil
+}
+
+// The quota is counted while held by the store, so approvals running at once
+// couldn't both take the last of it
 func (s Shortening) HandleLinkShortened(
 	msgId uint64,
 	lifetime time.Duration,
@@ -235,7 +1279,7 @@ func (s Shortening) HandleLinkShortened(
 	oldLink, err := s.store.GetById(msgCtx.LinkId())
 	if err != nil {
 		return fmt.Errorf("service<Shortening.HandleLinkShortened>: %w", err)
-	} else if !oldLink.AccessibleBy(msgCtx.UserId()) {
+	} else if !oldLink.OwnedBy(msgCtx.UserId()) {
 		return fmt.Errorf(
 			"service<Shortening.HandleLinkShortened>: %w",
 			oops.Forbidden{Msg: fmt.Sprintf(
@@ -250,43 +1294,123 @@ func (s Shortening) HandleLinkShortened(
 		return nil
 	}
 
-	stats, err := s.store.CountByUserIdExcept(msgCtx.UserId(), msgCtx.LinkId())
+	// The link stays open once approved, while the activation window decides
+	// when it actually redirects. The perk lifetime still caps the window
+	now := time.Now()
+	expiredAt, err := oldLink.ExpiryFrom(now, lifetime)
 	if err != nil {
 		return fmt.Errorf("service<Shortening.HandleLinkShortened>: %w", err)
-	} else if !stats.HasQuota(linkCountLimit, 1) {
-		return fmt.Errorf(
-			"service<Shortening.HandleLinkShortened>: %w",
-			oops.Forbidden{Msg: fmt.Sprintf(
-				"Quota for simultaneous active shortened links had ran out (limit: %d; have: %d)",
-				linkCountLimit, stats.ActiveLinks())})
 	}
-
-	now := time.Now()
-	oldLinkId := oldLink.Id()
-	newLink, err := shortening.NewLink(
-		&oldLinkId,
-		oldLink.UserId(),
-		oldLink.Shortened(),
+	newLink, err := oldLink.Reconfigure(
 		oldLink.Alias(),
 		oldLink.Destination(),
 		true,
 		now,
-		now.Add(lifetime))
+		expiredAt)
 	if err != nil {
 		return fmt.Errorf("service<Shortening.HandleLinkShortened>: %w", err)
 	}
 
-	if err := s.store.Update(newLink); err != nil {
+	err = s.store.UpdateWithinQuota(
+		[]shortening.Link{newLink},
+		nil,
+		func(stats shortening.Stats) error {
+			if !stats.HasQuota(linkCountLimit, 1) {
+				return oops.Forbidden{Msg: fmt.Sprintf(
+					"Quota for simultaneous active shortened links had ran out (limit: %d; have: %d)",
+					linkCountLimit, stats.ActiveLinks())}
+			}
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("service<Shortening.HandleLinkShortened>: %w", err)
+	}
+	if err := s.redirectCache.Invalidate(newLink.Alias()); err != nil {
 		return fmt.Errorf("service<Shortening.HandleLinkShortened>: %w", err)
 	}
 	return nil
 }
 
-// # TODO
-//
-// (Read `HandleLinkShortened` and handle if the handler needs to fetch user stats).
+// Activates every link of the batch when the quota could cover all of them.
+// Otherwise, nothing is activated. The quota is held the same way as in
+// `HandleLinkShortened`
+func (s Shortening) HandleBulkShortened(
+	msgId uint64,
+	lifetime time.Duration,
+	linkCountLimit uint,
+) error {
+	msgCtx, err := s.store.GetBulkShortenedById(msgId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.HandleBulkShortened>: %w", err)
+	}
+
+	now := time.Now()
+	newLinks := []shortening.Link{}
+	for _, id := range msgCtx.LinkIds() {
+		oldLink, err := s.store.GetById(id)
+		if err != nil {
+			return fmt.Errorf("service<Shortening.HandleBulkShortened>: %w", err)
+		} else if !oldLink.OwnedBy(msgCtx.UserId()) {
+			return fmt.Errorf(
+				"service<Shortening.HandleBulkShortened>: %w",
+				oops.Forbidden{Msg: fmt.Sprintf(
+					"User(id:%d) doesn't have access to Link(id:%d)",
+					msgCtx.UserId(), id)})
+		}
+
+		// Same idempotency token as in `HandleLinkShortened`
+		if now.Sub(oldLink.ExpiredAt()) < 0 {
+			continue
+		}
+
+		expiredAt, err := oldLink.ExpiryFrom(now, lifetime)
+		if err != nil {
+			return fmt.Errorf("service<Shortening.HandleBulkShortened>: %w", err)
+		}
+		newLink, err := oldLink.Reconfigure(
+			oldLink.Alias(),
+			oldLink.Destination(),
+			true,
+			now,
+			expiredAt)
+		if err != nil {
+			return fmt.Errorf("service<Shortening.HandleBulkShortened>: %w", err)
+		}
+		newLinks = append(newLinks, newLink)
+	}
+	if len(newLinks) == 0 {
+		return nil
+	}
+
+	err = s.store.UpdateWithinQuota(newLinks, nil, func(stats shortening.Stats) error {
+		if need := uint(len(newLinks)); !stats.HasQuota(linkCountLimit, need) {
+			return oops.Forbidden{Msg: fmt.Sprintf(
+				"Quota for simultaneous active shortened links couldn't cover the batch (limit: %d; have: %d; need: %d)",
+				linkCountLimit, stats.ActiveLinks(), need)}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("service<Shortening.HandleBulkShortened>: %w", err)
+	}
+
+	aliases := []string{}
+	for _, l := range newLinks {
+		aliases = append(aliases, l.Alias())
+	}
+	if err := s.redirectCache.Invalidate(aliases...); err != nil {
+		return fmt.Errorf("service<Shortening.HandleBulkShortened>: %w", err)
+	}
+	return nil
+}
+
+// Opening a closed link takes one of the quota, held the same way as in
+// `HandleLinkShortened`. Otherwise, links that were closed meanwhile, like the
+// ones of an imported batch the quota couldn't cover, would be reopened past
+// the quota
 func (ss Shortening) HandleShortConfigured(
 	msgId uint64,
+	linkCountLimit uint,
 	allowEditShortUrl bool,
 ) error {
 	msgCtx, err := ss.store.GetShortConfiguredById(msgId)
@@ -297,23 +1421,24 @@ func (ss Shortening) HandleShortConfigured(
 	oldLink, err := ss.store.GetById(msgCtx.LinkId())
 	if err != nil {
 		return fmt.Errorf("service<Shortening.HandleShortConfigured>: %w", err)
-	} else if !oldLink.AccessibleBy(msgCtx.UserId()) {
+	} else if !oldLink.OwnedBy(msgCtx.UserId()) {
 		return fmt.Errorf(
 			"service<Shortening.HandleShortConfigured>: %w",
 			oops.Forbidden{Msg: "You don't have access to this link"})
 	}
 
-	if oldLink.HasCustomAlias() && !allowEditShortUrl {
+	// Covers both keeping and taking a custom alias
+	hasCustomAlias := oldLink.HasCustomAlias() || msgCtx.Alias() != oldLink.Shortened()
+	if hasCustomAlias && !allowEditShortUrl {
 		return fmt.Errorf(
 			"service<Shortening.HandleShortConfigured>: %w",
 			oops.Forbidden{Msg: "Your subscription doesn't allow short editing"})
 	}
 
-	linkId := oldLink.Id()
-	newLink, err := shortening.NewLink(
-		&linkId,
-		oldLink.UserId(),
-		oldLink.Shortened(),
+	// The password and the other settings aren't carried by the message. They
+	// were applied by `UpdateWithSubscription` already, so the stored ones are
+	// kept as is
+	newLink, err := oldLink.Reconfigure(
 		msgCtx.Alias(),
 		msgCtx.Destination(),
 		msgCtx.IsOpen(),
@@ -323,12 +1448,30 @@ func (ss Shortening) HandleShortConfigured(
 		return fmt.Errorf("service<Shortening.HandleShortConfigured>: %w", err)
 	}
 
-	if err := ss.store.Update(newLink); err != nil {
-		return fmt.Errorf("service<Shortening.HandleLinkShortened>: %w", err)
+	editorId := msgCtx.EditorId()
+	opening := newLink.IsOpen() && !oldLink.IsOpen()
+	err = ss.store.UpdateWithinQuota(
+		[]shortening.Link{newLink},
+		&editorId,
+		func(stats shortening.Stats) error {
+			if opening && !stats.HasQuota(linkCountLimit, 1) {
+				return oops.Forbidden{Msg: fmt.Sprintf(
+					"Quota for simultaneous active shortened links had ran out (limit: %d; have: %d)",
+					linkCountLimit, stats.ActiveLinks())}
+			}
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("service<Shortening.HandleShortConfigured>: %w", err)
+	}
+	if err := ss.redirectCache.Invalidate(oldLink.Alias(), newLink.Alias()); err != nil {
+		return fmt.Errorf("service<Shortening.HandleShortConfigured>: %w", err)
 	}
 	return nil
 }
 
+// Closes what the lapsed subscription no longer covers, both in the personal
+// links of the user and in the workspaces running on their subscription
 func (ss Shortening) HandleSubscriptionExpired(
 	userId uint64,
 	linkCountLimit uint,
@@ -338,10 +1481,36 @@ func (ss Shortening) HandleSubscriptionExpired(
 	if err != nil {
 		return fmt.Errorf("service<Shortening.HandleSubscriptionExpired>: %w", err)
 	}
+	if err := ss.expire(links, linkCountLimit); err != nil {
+		return fmt.Errorf("service<Shortening.HandleSubscriptionExpired>: %w", err)
+	}
+
+	workspaces, err := ss.workspaceStore.GetWorkspacesBySubscriber(userId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.HandleSubscriptionExpired>: %w", err)
+	}
+	for _, w := range workspaces {
+		links, err := ss.store.GetOpenedFromOldestByWorkspace(w.Id())
+		if err != nil {
+			return fmt.Errorf("service<Shortening.HandleSubscriptionExpired>: %w", err)
+		}
+		if err := ss.expire(links, linkCountLimit); err != nil {
+			return fmt.Errorf("service<Shortening.HandleSubscriptionExpired>: %w", err)
+		}
+	}
+	return nil
+}
+
+// Closes the links with custom alias, along with the oldest of the rest that
+// exceed the quota. `links` are the open links sharing a subscription, from
+// the oldest
+func (ss Shortening) expire(links []shortening.Link, linkCountLimit uint) error {
 
+	aliases := map[uint64]string{}
 	deactivatedLinks := []uint64{}
 	keptLinks := []uint64{}
 	for _, l := range links {
+		aliases[l.Id()] = l.Alias()
 		isPremiumLink := l.HasCustomAlias()
 		if isPremiumLink {
 			deactivatedLinks = append(deactivatedLinks, l.Id())
@@ -358,8 +1527,128 @@ func (ss Shortening) HandleSubscriptionExpired(
 		return nil
 	}
 
-	if err = ss.store.ApplySubscriptionExpiration(deactivatedLinks); err != nil {
-		return fmt.Errorf("service<Shortening.HandleSubscriptionExpired>: %w", err)
+	if err := ss.store.ApplySubscriptionExpiration(deactivatedLinks); err != nil {
+		return fmt.Errorf("service<Shortening.expire>: %w", err)
+	}
+
+	deactivatedAliases := []string{}
+	for _, id := range deactivatedLinks {
+		deactivatedAliases = append(deactivatedAliases, aliases[id])
+	}
+	if err := ss.redirectCache.Invalidate(deactivatedAliases...); err != nil {
+		return fmt.Errorf("service<Shortening.expire>: %w", err)
+	}
+	return nil
+}
+
+// Hands the link over to the recipient when their subscription could take it.
+// An open link takes one of the recipient's quota, which is held the same way
+// as in `HandleLinkShortened`, while a custom alias needs their subscription
+// to allow short editing
+func (ss Shortening) HandleTransferAccepted(
+	msgId uint64,
+	linkCountLimit uint,
+	allowEditShortUrl bool,
+) error {
+	msgCtx, err := ss.transferStore.GetTransferAcceptedById(msgId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.HandleTransferAccepted>: %w", err)
+	}
+
+	transfer, err := ss.transferStore.GetTransferById(msgCtx.TransferId())
+	if err != nil {
+		return fmt.Errorf("service<Shortening.HandleTransferAccepted>: %w", err)
+	}
+
+	// Settled transfers serve as the idempotency token
+	if transfer.Status() != shortening.TransferAccepted {
+		return nil
+	}
+
+	link, err := ss.store.GetById(transfer.LinkId())
+	if err != nil {
+		return fmt.Errorf("service<Shortening.HandleTransferAccepted>: %w", err)
+	}
+	if link.HasCustomAlias() && !allowEditShortUrl {
+		return fmt.Errorf(
+			"service<Shortening.HandleTransferAccepted>: %w",
+			oops.Forbidden{Msg: "Recipient's subscription doesn't allow short editing"})
+	}
+
+	var need uint = 0
+	if link.IsOpen() {
+		need = 1
+	}
+	if err := link.HandOver(transfer); err != nil {
+		return fmt.Errorf("service<Shortening.HandleTransferAccepted>: %w", err)
+	}
+	if err := transfer.Settle(true, time.Now()); err != nil {
+		return fmt.Errorf("service<Shortening.HandleTransferAccepted>: %w", err)
+	}
+	err = ss.transferStore.CompleteTransfer(transfer, link, func(stats shortening.Stats) error {
+		if !stats.HasQuota(linkCountLimit, need) {
+			return oops.Forbidden{Msg: fmt.Sprintf(
+				"Recipient's quota for simultaneous active shortened links had ran out (limit: %d; have: %d)",
+				linkCountLimit, stats.ActiveLinks())}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("service<Shortening.HandleTransferAccepted>: %w", err)
+	}
+	if err := ss.redirectCache.Invalidate(link.Alias()); err != nil {
+		return fmt.Errorf("service<Shortening.HandleTransferAccepted>: %w", err)
+	}
+	return nil
+}
+
+// Rejects the transfer, leaving the link with its owner
+func (ss Shortening) CompensateTransferAccepted(msgId uint64) error {
+	msgCtx, err := ss.transferStore.GetTransferAcceptedById(msgId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.CompensateTransferAccepted>: %w", err)
+	}
+
+	transfer, err := ss.transferStore.GetTransferById(msgCtx.TransferId())
+	if err != nil {
+		return fmt.Errorf("service<Shortening.CompensateTransferAccepted>: %w", err)
+	} else if transfer.Status() != shortening.TransferAccepted {
+		return nil
+	}
+
+	if err := transfer.Settle(false, time.Now()); err != nil {
+		return fmt.Errorf("service<Shortening.CompensateTransferAccepted>: %w", err)
+	}
+	if err := ss.transferStore.UpdateTransfer(transfer); err != nil {
+		return fmt.Errorf("service<Shortening.CompensateTransferAccepted>: %w", err)
+	}
+	return nil
+}
+
+// Removes every link of the rejected batch
+func (ss Shortening) CompensateBulkShortened(msgId uint64) error {
+	msgCtx, err := ss.store.GetBulkShortenedById(msgId)
+	if err != nil {
+		return fmt.Errorf("service<Shortening.CompensateBulkShortened>: %w", err)
+	}
+
+	aliases := []string{}
+	for _, id := range msgCtx.LinkIds() {
+		link, err := ss.store.GetById(id)
+		switch {
+		case errors.As(err, &oops.NotFound{}):
+			continue
+		case err != nil:
+			return fmt.Errorf("service<Shortening.CompensateBulkShortened>: %w", err)
+		}
+		aliases = append(aliases, link.Alias())
+	}
+
+	if err := ss.store.DeleteManyById(msgCtx.LinkIds()); err != nil {
+		return fmt.Errorf("service<Shortening.CompensateBulkShortened>: %w", err)
+	}
+	if err := ss.redirectCache.Invalidate(aliases...); err != nil {
+		return fmt.Errorf("service<Shortening.CompensateBulkShortened>: %w", err)
 	}
 	return nil
 }
@@ -371,7 +1660,19 @@ func (ss Shortening) CompensateLinkShortened(msgId uint64) error {
 		return fmt.Errorf("service<Shortening.CompensateLinkShortened>: %w", err)
 	}
 
-	if err := ss.store.DeleteById(msgCtx.LinkId()); err != nil {
+	// Already removed by an earlier delivery of the same message
+	link, err := ss.store.GetById(msgCtx.LinkId())
+	switch {
+	case errors.As(err, &oops.NotFound{}):
+		return nil
+	case err != nil:
+		return fmt.Errorf("service<Shortening.CompensateLinkShortened>: %w", err)
+	}
+
+	if err := ss.store.DeleteById(link.Id()); err != nil {
+		return fmt.Errorf("service<Shortening.CompensateLinkShortened>: %w", err)
+	}
+	if err := ss.redirectCache.Invalidate(link.Alias()); err != nil {
 		return fmt.Errorf("service<Shortening.CompensateLinkShortened>: %w", err)
 	}
 	return nil
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/solsteace/go-lib/oops"
+	"github.com/solsteace/kochira/link/internal/domain/shortening"
+	"github.com/solsteace/kochira/link/internal/domain/shortening/store"
+)
+
+type Workspace struct {
+	store store.Workspace
+}
+
+func NewWorkspace(store store.Workspace) Workspace {
+	return Workspace{store: store}
+}
+
+// Retrieves the workspaces the user is a member of
+func (ws Workspace) GetSelf(userId uint64) ([]shortening.Workspace, error) {
+	workspaces, err := ws.store.GetWorkspacesByUser(userId)
+	if err != nil {
+		return []shortening.Workspace{}, fmt.Errorf("service<Workspace.GetSelf>: %w", err)
+	}
+	return workspaces, nil
+}
+
+// Creates a workspace running on the user's subscription, with the user as
+// its first owner
+func (ws Workspace) Create(userId uint64, name string) (uint64, error) {
+	w, err := shortening.NewWorkspace(nil, name, userId, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("service<Workspace.Create>: %w", err)
+	}
+
+	id, err := ws.store.CreateWorkspace(w)
+	if err != nil {
+		return 0, fmt.Errorf("service<Workspace.Create>: %w", err)
+	}
+	return id, nil
+}
+
+func (ws Workspace) GetMembers(userId, workspaceId uint64) ([]shortening.Member, error) {
+	if _, err := ws.authorize(userId, workspaceId, shortening.PermissionView); err != nil {
+		return []shortening.Member{}, fmt.Errorf("service<Workspace.GetMembers>: %w", err)
+	}
+
+	members, err := ws.store.GetMembers(workspaceId)
+	if err != nil {
+		return []shortening.Member{}, fmt.Errorf("service<Workspace.GetMembers>: %w", err)
+	}
+	return members, nil
+}
+
+// Adds the user having the username to the workspace
+func (ws Workspace) AddMember(userId, workspaceId uint64, username, role string) error {
+	if _, err := ws.authorize(userId, workspaceId, shortening.PermissionManage); err != nil {
+		return fmt.Errorf("service<Workspace.AddMember>: %w", err)
+	}
+	r, err := shortening.NewRole(role)
+	if err != nil {
+		return fmt.Errorf("service<Workspace.AddMember>: %w", err)
+	}
+
+	memberId, err := ws.store.GetUserIdByUsername(username)
+	if err != nil {
+		return fmt.Errorf("service<Workspace.AddMember>: %w", err)
+	}
+	_, err = ws.store.GetMember(workspaceId, memberId)
+	switch {
+	case err == nil:
+		err := oops.BadValues{
+			Err: errors.New("already a member"),
+			Msg: fmt.Sprintf("User `%s` is already a member of this workspace", username)}
+		return fmt.Errorf("service<Workspace.AddMember>: %w", err)
+	case !errors.As(err, &oops.NotFound{}):
+		return fmt.Errorf("service<Workspace.AddMember>: %w", err)
+	}
+
+	if err := ws.store.SaveMember(shortening.NewMember(workspaceId, memberId, r)); err != nil {
+		return fmt.Errorf("service<Workspace.AddMember>: %w", err)
+	}
+	return nil
+}
+
+func (ws Workspace) UpdateMember(userId, workspaceId, memberId uint64, role string) error {
+	w, err := ws.authorize(userId, workspaceId, shortening.PermissionManage)
+	if err != nil {
+		return fmt.Errorf("service<Workspace.UpdateMember>: %w", err)
+	}
+	r, err := shortening.NewRole(role)
+	if err != nil {
+		return fmt.Errorf("service<Workspace.UpdateMember>: %w", err)
+	}
+
+	member, err := ws.store.GetMember(workspaceId, memberId)
+	if err != nil {
+		return fmt.Errorf("service<Workspace.UpdateMember>: %w", err)
+	} else if err := w.CanChange(member, &r); err != nil {
+		return fmt.Errorf("service<Workspace.UpdateMember>: %w", err)
+	}
+
+	if err := ws.store.SaveMember(shortening.NewMember(workspaceId, memberId, r)); err != nil {
+		return fmt.Errorf("service<Workspace.UpdateMember>: %w", err)
+	}
+	return nil
+}
+
+// Removes the member from the workspace. Besides the owners, members could
+// remove themselves to leave the workspace
+func (ws Workspace) RemoveMember(userId, workspaceId, memberId uint64) error {
+	permission := shortening.PermissionManage
+	if userId == memberId {
+		permission = shortening.PermissionView
+	}
+	w, err := ws.authorize(userId, workspaceId, permission)
+	if err != nil {
+		return fmt.Errorf("service<Workspace.RemoveMember>: %w", err)
+	}
+
+	member, err := ws.store.GetMember(workspaceId, memberId)
+	if err != nil {
+		return fmt.Errorf("service<Workspace.RemoveMember>: %w", err)
+	} else if err := w.CanChange(member, nil); err != nil {
+		return fmt.Errorf("service<Workspace.RemoveMember>: %w", err)
+	}
+
+	if err := ws.store.DeleteMember(workspaceId, memberId); err != nil {
+		return fmt.Errorf("service<Workspace.RemoveMember>: %w", err)
+	}
+	return nil
+}
+
+// Retrieves the workspace, making sure the user's role permits the act.
+// Workspaces the user isn't a member of are treated as missing
+func (ws Workspace) authorize(
+	userId uint64,
+	workspaceId uint64,
+	p shortening.Permission,
+) (shortening.Workspace, error) {
+	member, err := ws.store.GetMember(workspaceId, userId)
+	switch {
+	case errors.As(err, &oops.NotFound{}):
+		err := oops.NotFound{
+			Err: err,
+			Msg: fmt.Sprintf("workspace(id:%d) not found", workspaceId)}
+		return shortening.Workspace{}, fmt.Errorf("service<Workspace.authorize>: %w", err)
+	case err != nil:
+		return shortening.Workspace{}, fmt.Errorf("service<Workspace.authorize>: %w", err)
+	case !member.Role().Can(p):
+		return shortening.Workspace{}, fmt.Errorf(
+			"service<Workspace.authorize>: %w",
+			oops.Forbidden{Msg: "Your role in this workspace doesn't allow this"})
+	}
+
+	w, err := ws.store.GetWorkspaceById(workspaceId)
+	if err != nil {
+		return shortening.Workspace{}, fmt.Errorf("service<Workspace.authorize>: %w", err)
+	}
+	return w, nil
+}
@@ -0,0 +1,68 @@
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/solsteace/kochira/link/internal/utility/reloadable"
+)
+
+// Blocklist of hosts read from a file, one host per line. Blank lines and
+// lines starting with `#` are ignored. A host also blocks its subdomains
+type File struct {
+	hosts *reloadable.File[map[string]struct{}]
+}
+
+// An empty path makes a blocklist that blocks nothing
+func NewFile(path string) (*File, error) {
+	hosts, err := reloadable.NewFile(path, map[string]struct{}{}, parseHosts)
+	if err != nil {
+		return nil, fmt.Errorf("blocklist<NewFile>: %w", err)
+	}
+	return &File{hosts: hosts}, nil
+}
+
+// Re-reads the file when it had been modified since the last read. The last
+// good blocklist is kept when the file couldn't be read, so a broken
+// deployment wouldn't unblock every host
+func (f *File) Reload() error {
+	if err := f.hosts.Reload(); err != nil {
+		return fmt.Errorf("blocklist<File.Reload>: %w", err)
+	}
+	return nil
+}
+
+func (f *File) Blocks(host string) bool {
+	hosts := f.hosts.Value()
+
+	// Checks the host itself, followed by its parent domains
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for host != "" {
+		if _, ok := hosts[host]; ok {
+			return true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	return false
+}
+
+func parseHosts(r io.Reader) (map[string]struct{}, error) {
+	hosts := map[string]struct{}{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			hosts[strings.TrimSuffix(line, ".")] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("blocklist<parseHosts>: %w", err)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,138 @@
+package geoip
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/solsteace/kochira/link/internal/utility/reloadable"
+)
+
+// Tells which country an IP belongs to
+type Lookup interface {
+	Country(ip string) string // ISO 3166-1 alpha-2 code. Empty when unknown
+}
+
+type ipRange struct {
+	start   netip.Addr
+	end     netip.Addr
+	country string
+}
+
+// IP-to-country database read from a CSV file. Each line is either
+// `network,country` with the network in CIDR notation, or
+// `start,end,country` with an inclusive range of IPs. Blank lines and lines
+// starting with `#` are ignored
+type File struct {
+	ranges *reloadable.File[[]ipRange] // Sorted by their start
+}
+
+// An empty path makes a database that knows nothing
+func NewFile(path string) (*File, error) {
+	ranges, err := reloadable.NewFile(path, []ipRange{}, parseRanges)
+	if err != nil {
+		return nil, fmt.Errorf("geoip<NewFile>: %w", err)
+	}
+	return &File{ranges: ranges}, nil
+}
+
+// Re-reads the file when it had been modified since the last read. The
+// previous ranges are kept when the file couldn't be read
+func (f *File) Reload() error {
+	if err := f.ranges.Reload(); err != nil {
+		return fmt.Errorf("geoip<File.Reload>: %w", err)
+	}
+	return nil
+}
+
+func parseRanges(r io.Reader) ([]ipRange, error) {
+	ranges := []ipRange{}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r, err := parseRange(strings.Split(line, ","))
+		if err != nil {
+			return nil, fmt.Errorf("geoip<parseRanges>: line %d: %w", lineNo, err)
+		}
+		ranges = append(ranges, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("geoip<parseRanges>: %w", err)
+	}
+
+	slices.SortFunc(ranges, func(a, b ipRange) int { return a.start.Compare(b.start) })
+	return ranges, nil
+}
+
+func (f *File) Country(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	ranges := f.ranges.Value()
+
+	// Finds the last range starting at or before the IP
+	idx, found := slices.BinarySearchFunc(
+		ranges, addr,
+		func(r ipRange, target netip.Addr) int { return r.start.Compare(target) })
+	if !found {
+		idx--
+	}
+	if idx < 0 || ranges[idx].end.Compare(addr) < 0 {
+		return ""
+	}
+	return ranges[idx].country
+}
+
+func parseRange(fields []string) (ipRange, error) {
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	switch len(fields) {
+	case 2:
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			return ipRange{}, fmt.Errorf("geoip<parseRange>: %w", err)
+		}
+		prefix = prefix.Masked()
+		return ipRange{
+			start:   prefix.Addr().Unmap(),
+			end:     lastAddr(prefix).Unmap(),
+			country: strings.ToUpper(fields[1])}, nil
+	case 3:
+		start, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return ipRange{}, fmt.Errorf("geoip<parseRange>: %w", err)
+		}
+		end, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return ipRange{}, fmt.Errorf("geoip<parseRange>: %w", err)
+		}
+		return ipRange{
+			start:   start.Unmap(),
+			end:     end.Unmap(),
+			country: strings.ToUpper(fields[2])}, nil
+	}
+	return ipRange{}, fmt.Errorf(
+		"geoip<parseRange>: expected 2 or 3 fields, got %d", len(fields))
+}
+
+// Returns the last address covered by the prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
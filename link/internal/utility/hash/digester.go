@@ -0,0 +1,5 @@
+package hash
+
+type Digester interface {
+	Digest(payload string) string
+}
@@ -0,0 +1,6 @@
+package hash
+
+type Handler interface {
+	Generate(payload string) ([]byte, error)
+	Compare(digest, payload string) error
+}
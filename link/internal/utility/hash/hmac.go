@@ -0,0 +1,23 @@
+package hash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+type hmacSha256 struct {
+	secret []byte
+}
+
+func NewHmacSha256(secret string) hmacSha256 {
+	return hmacSha256{secret: []byte(secret)}
+}
+
+// Returns hex-encoded HMAC-SHA256 of the payload. The result is stable for
+// the same secret, so it could still be used to count distinct payloads
+func (h hmacSha256) Digest(payload string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
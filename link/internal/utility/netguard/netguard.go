@@ -0,0 +1,73 @@
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("address isn't reachable publicly")
+
+// Ranges `netip` doesn't treat as private nor special, yet aren't reachable
+// publicly either
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This network" (RFC 1122)
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT (RFC 6598)
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments (RFC 6890)
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking (RFC 2544)
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved for future use (RFC 1112)
+}
+
+// Tells whether the address is reachable publicly. Loopback, link-local,
+// multicast and unspecified addresses aren't global unicast to begin with
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Refuses to connect to addresses that aren't reachable publicly. Meant as
+// `net.Dialer.Control`, which is called after the host is resolved, right
+// before each connection is made
+func Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("netguard<Control>: %w", err)
+	}
+	if !IsPublic(addrPort.Addr()) {
+		return fmt.Errorf("netguard<Control>: %w", ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// Dialer that only connects to public addresses, hence couldn't be used to
+// reach the internal network, even by redirecting or by changing what a host
+// resolves to
+func NewDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: Control}
+}
+
+// Transport that only connects through `NewDialer`. Proxies from the
+// environment are ignored, as the proxy would be the one the guard checks
+// instead of the destination
+func NewTransport(timeout time.Duration) *http.Transport {
+	return &http.Transport{
+		Proxy:               nil,
+		DialContext:         NewDialer(timeout).DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        16,
+		IdleConnTimeout:     30 * time.Second}
+}
@@ -0,0 +1,52 @@
+package netguard
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	cases := []struct {
+		name     string
+		addr     string
+		expected bool
+	}{
+		{"public ipv4", "93.184.216.34", true},
+		{"public ipv6", "2606:2800:220:1:248:1893:25c8:1946", true},
+		{"loopback", "127.0.0.1", false},
+		{"loopback ipv6", "::1", false},
+		{"private", "10.0.0.1", false},
+		{"private ipv6", "fd00::1", false},
+		{"link-local", "169.254.169.254", false},
+		{"link-local ipv6", "fe80::1", false},
+		{"unspecified", "0.0.0.0", false},
+		{"this network", "0.1.2.3", false},
+		{"carrier-grade nat", "100.64.0.1", false},
+		{"carrier-grade nat end", "100.127.255.254", false},
+		{"next to carrier-grade nat", "100.128.0.1", true},
+		{"benchmarking", "198.18.0.1", false},
+		{"future use", "240.0.0.1", false},
+		{"broadcast", "255.255.255.255", false},
+		{"mapped loopback", "::ffff:127.0.0.1", false},
+		{"mapped private", "::ffff:192.168.1.1", false},
+		{"mapped carrier-grade nat", "::ffff:100.64.0.1", false},
+		{"mapped public", "::ffff:93.184.216.34", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := IsPublic(netip.MustParseAddr(c.addr)); got != c.expected {
+				t.Fatalf("expected %v for %s, got %v", c.expected, c.addr, got)
+			}
+		})
+	}
+}
+
+func TestControl(t *testing.T) {
+	if err := Control("tcp4", "93.184.216.34:443", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := Control("tcp6", "[::ffff:10.0.0.1]:80", nil); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("expected %v, got %v", ErrForbiddenAddress, err)
+	}
+}
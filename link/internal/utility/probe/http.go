@@ -0,0 +1,125 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Tells how a destination responds
+type Prober interface {
+	// Nil status along with the error means the destination couldn't be
+	// reached. Latency is measured until the response headers arrive
+	Probe(ctx context.Context, destination string) (status *int, latency time.Duration, err error)
+}
+
+// How many hosts are remembered before the stale ones are forgotten?
+const hOST_PRUNE_SIZE = 1024
+
+// Probes destinations over HTTP. A `HEAD` request is sent first, falling back
+// to `GET` for servers that don't handle it well. Redirects aren't followed,
+// hence a redirecting destination answers with its redirect
+type Http struct {
+	client       *http.Client
+	timeout      time.Duration // How long a single request could take?
+	hostInterval time.Duration // How long to wait between requests to the same host?
+
+	mu       sync.Mutex
+	nextSlot map[string]time.Time // When could each host be requested again?
+}
+
+// Nil `client` uses a fresh one. The client's redirect policy is replaced,
+// so other settings like the transport are kept
+func NewHttp(client *http.Client, timeout, hostInterval time.Duration) *Http {
+	actualClient := &http.Client{}
+	if client != nil {
+		*actualClient = *client
+	}
+	actualClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Http{
+		client:       actualClient,
+		timeout:      timeout,
+		hostInterval: hostInterval,
+		nextSlot:     map[string]time.Time{}}
+}
+
+func (h *Http) Probe(ctx context.Context, destination string) (*int, time.Duration, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return nil, 0, fmt.Errorf("probe<Http.Probe>: %w", err)
+	}
+
+	status, latency, err := h.request(ctx, http.MethodHead, u)
+	if err == nil && *status < http.StatusBadRequest {
+		return status, latency, nil
+	}
+
+	// Some servers refuse or mishandle `HEAD`, while serving `GET` just fine
+	status, latency, err = h.request(ctx, http.MethodGet, u)
+	if err != nil {
+		return nil, latency, fmt.Errorf("probe<Http.Probe>: %w", err)
+	}
+	return status, latency, nil
+}
+
+func (h *Http) request(ctx context.Context, method string, u *url.URL) (*int, time.Duration, error) {
+	if err := h.wait(ctx, u.Host); err != nil {
+		return nil, 0, fmt.Errorf("probe<Http.request>: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("probe<Http.request>: %w", err)
+	}
+	req.Header.Set("User-Agent", "kochira-link-checker/1.0")
+
+	start := time.Now()
+	res, err := h.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return nil, latency, fmt.Errorf("probe<Http.request>: %w", err)
+	}
+	// The body is of no use, so the connection is dropped instead of drained
+	res.Body.Close()
+
+	status := res.StatusCode
+	return &status, latency, nil
+}
+
+// Blocks until the host could be requested again, reserving the slot
+func (h *Http) wait(ctx context.Context, host string) error {
+	now := time.Now()
+	h.mu.Lock()
+	slot := now
+	if next, ok := h.nextSlot[host]; ok && next.After(now) {
+		slot = next
+	}
+	h.nextSlot[host] = slot.Add(h.hostInterval)
+	if len(h.nextSlot) > hOST_PRUNE_SIZE {
+		for k, next := range h.nextSlot {
+			if next.Before(now) {
+				delete(h.nextSlot, k)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	if delay := slot.Sub(now); delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
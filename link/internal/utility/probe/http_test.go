@@ -0,0 +1,151 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProbeFallsBackToGet(t *testing.T) {
+	var mu sync.Mutex
+	methods := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	status, _, err := NewHttp(nil, time.Second, 0).Probe(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status == nil || *status != http.StatusOK {
+		t.Fatalf("expected status %d, got %v", http.StatusOK, status)
+	}
+	if len(methods) != 2 || methods[0] != http.MethodHead || methods[1] != http.MethodGet {
+		t.Fatalf("expected HEAD followed by GET, got %v", methods)
+	}
+}
+
+func TestProbeSkipsGetWhenHeadSucceeds(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	status, _, err := NewHttp(nil, time.Second, 0).Probe(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status == nil || *status != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %v", http.StatusNoContent, status)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("expected a single request, got %d", got)
+	}
+}
+
+func TestProbeDoesNotFollowRedirects(t *testing.T) {
+	var followed atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/target" {
+			followed.Store(true)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/target", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	status, _, err := NewHttp(nil, time.Second, 0).Probe(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status == nil || *status != http.StatusFound {
+		t.Fatalf("expected status %d, got %v", http.StatusFound, status)
+	}
+	if followed.Load() {
+		t.Fatal("expected the redirect not to be followed")
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	status, _, err := NewHttp(nil, time.Second, 0).Probe(context.Background(), url)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if status != nil {
+		t.Fatalf("expected no status, got %d", *status)
+	}
+}
+
+func TestProbeSpacesRequestsToSameHost(t *testing.T) {
+	var mu sync.Mutex
+	arrivals := []time.Time{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals = append(arrivals, time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	interval := 100 * time.Millisecond
+	prober := NewHttp(nil, time.Second, interval)
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := prober.Probe(context.Background(), srv.URL); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(arrivals) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(arrivals))
+	}
+	// Some slack for the timers, which may fire slightly early
+	minGap := interval - 10*time.Millisecond
+	for idx := 1; idx < len(arrivals); idx++ {
+		if gap := arrivals[idx].Sub(arrivals[idx-1]); gap < minGap {
+			t.Fatalf("expected requests at least %v apart, got %v", interval, gap)
+		}
+	}
+}
+
+func TestProbeGivesUpWaitingOnCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	prober := NewHttp(nil, time.Second, time.Hour)
+	if _, _, err := prober.Probe(context.Background(), srv.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := prober.Probe(ctx, srv.URL); err == nil {
+		t.Fatal("expected an error while waiting for the host's slot")
+	}
+}
@@ -0,0 +1,169 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/solsteace/go-lib/oops"
+)
+
+const (
+	FormatPng = "png"
+	FormatSvg = "svg"
+
+	sIZE_MIN   = 64
+	sIZE_MAX   = 2048
+	mARGIN_MAX = 16 // in modules. 4 is what the spec recommends
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,     // ~7% could be restored
+	"M": qrcode.Medium,  // ~15% could be restored
+	"Q": qrcode.High,    // ~25% could be restored
+	"H": qrcode.Highest, // ~30% could be restored
+}
+
+type Options struct {
+	format string // Either `png` or `svg`
+	size   uint   // Width (and height) of the image, in pixels
+	margin uint   // Quiet zone around the code, in modules
+	level  string // Error correction level: L, M, Q or H
+}
+
+func (o Options) Format() string { return o.format }
+func (o Options) Size() uint     { return o.size }
+func (o Options) Margin() uint   { return o.margin }
+func (o Options) Level() string  { return o.level }
+
+func (o Options) ContentType() string {
+	if o.format == FormatSvg {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Validates the options. Zero values are replaced by the defaults
+func NewOptions(format string, size, margin *uint, level string) (Options, error) {
+	o := Options{format: FormatPng, size: 256, margin: 4, level: "M"}
+	if format != "" {
+		o.format = strings.ToLower(format)
+	}
+	if size != nil {
+		o.size = *size
+	}
+	if margin != nil {
+		o.margin = *margin
+	}
+	if level != "" {
+		o.level = strings.ToUpper(level)
+	}
+
+	var err error
+	switch _, levelOk := levels[o.level]; {
+	case o.format != FormatPng && o.format != FormatSvg:
+		err = oops.BadValues{
+			Err: errors.New("unknown format"),
+			Msg: fmt.Sprintf("Format should be either `%s` or `%s`", FormatPng, FormatSvg)}
+	case o.size < sIZE_MIN || o.size > sIZE_MAX:
+		err = oops.BadValues{
+			Err: errors.New("size out of range"),
+			Msg: fmt.Sprintf("Size should be between %d - %d pixels", sIZE_MIN, sIZE_MAX)}
+	case o.margin > mARGIN_MAX:
+		err = oops.BadValues{
+			Err: errors.New("margin out of range"),
+			Msg: fmt.Sprintf("Margin could only be %d modules at maximum", mARGIN_MAX)}
+	case !levelOk:
+		err = oops.BadValues{
+			Err: errors.New("unknown error correction level"),
+			Msg: "Error correction level should be one of L, M, Q or H"}
+	}
+	if err != nil {
+		return Options{}, fmt.Errorf("utility<qr.NewOptions>: %w", err)
+	}
+	return o, nil
+}
+
+// Encodes the content as QR code image, following the options
+func Render(content string, o Options) ([]byte, error) {
+	code, err := qrcode.New(content, levels[o.level])
+	if err != nil {
+		return []byte{}, fmt.Errorf("utility<qr.Render>: %w", err)
+	}
+	code.DisableBorder = true // the margin is drawn here instead
+	bitmap := code.Bitmap()
+
+	// Modules are scaled by a whole number to keep them crisp. The leftover
+	// pixels would be spread around as extra margin
+	modules := len(bitmap) + 2*int(o.margin)
+	scale := int(o.size) / modules
+	if scale < 1 {
+		err := oops.BadValues{
+			Err: errors.New("size too small"),
+			Msg: fmt.Sprintf(
+				"Size should be at least %d pixels for the code to be readable",
+				modules)}
+		return []byte{}, fmt.Errorf("utility<qr.Render>: %w", err)
+	}
+	offset := (int(o.size)-scale*modules)/2 + scale*int(o.margin)
+
+	switch o.format {
+	case FormatSvg:
+		return renderSvg(bitmap, int(o.size), scale, offset), nil
+	default:
+		body, err := renderPng(bitmap, int(o.size), scale, offset)
+		if err != nil {
+			return []byte{}, fmt.Errorf("utility<qr.Render>: %w", err)
+		}
+		return body, nil
+	}
+}
+
+func renderPng(bitmap [][]bool, size, scale, offset int) ([]byte, error) {
+	palette := color.Palette{color.White, color.Black}
+	img := image.NewPaletted(image.Rect(0, 0, size, size), palette)
+	for y, row := range bitmap {
+		for x, isDark := range row {
+			if !isDark {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	body := new(bytes.Buffer)
+	if err := png.Encode(body, img); err != nil {
+		return []byte{}, fmt.Errorf("utility<qr.renderPng>: %w", err)
+	}
+	return body.Bytes(), nil
+}
+
+func renderSvg(bitmap [][]bool, size, scale, offset int) []byte {
+	path := new(strings.Builder)
+	for y, row := range bitmap {
+		for x, isDark := range row {
+			if isDark {
+				fmt.Fprintf(path, "M%d %dh%dv%dh-%dz",
+					offset+x*scale, offset+y*scale, scale, scale, scale)
+			}
+		}
+	}
+
+	body := new(bytes.Buffer)
+	fmt.Fprintf(body,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, size, size)
+	fmt.Fprintf(body, `<rect width="%d" height="%d" fill="#fff"/>`, size, size)
+	fmt.Fprintf(body, `<path fill="#000" d="%s"/>`, path.String())
+	body.WriteString(`</svg>`)
+	return body.Bytes()
+}
@@ -0,0 +1,80 @@
+package realip
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Replaces `RemoteAddr` by the address of the client, as forwarded by the
+// trusted proxies in `X-Forwarded-For`. The header is read right to left and
+// the first address not belonging to the trusted proxies is taken, as anything
+// before it could had been written by the client. Requests not coming from the
+// trusted proxies are left as is, so the header couldn't be spoofed by
+// reaching the app directly
+func Middleware(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := forwarded(r, trusted); ok {
+				r.RemoteAddr = ip.String()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func forwarded(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil || !isTrusted(peer.Addr().Unmap(), trusted) {
+		return netip.Addr{}, false
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		} else if ip = ip.Unmap(); !isTrusted(ip, trusted) {
+			return ip, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+func isTrusted(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parses comma-separated CIDRs, or single addresses, of the trusted proxies.
+// Empty means no proxy is trusted
+func ParseTrusted(raw string) ([]netip.Prefix, error) {
+	trusted := []netip.Prefix{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			ip, err := netip.ParseAddr(item)
+			if err != nil {
+				return []netip.Prefix{}, fmt.Errorf("realip<ParseTrusted>: %w", err)
+			}
+			ip = ip.Unmap()
+			trusted = append(trusted, netip.PrefixFrom(ip, ip.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return []netip.Prefix{}, fmt.Errorf("realip<ParseTrusted>: %w", err)
+		}
+		trusted = append(trusted, prefix.Masked())
+	}
+	return trusted, nil
+}
@@ -0,0 +1,75 @@
+package reloadable
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Value parsed from a file, which is parsed again whenever the file is
+// modified. The last good value stays in effect when the file couldn't be
+// read or parsed, including when it went missing, so a broken deployment
+// wouldn't wipe what was already loaded
+type File[T any] struct {
+	path  string
+	parse func(r io.Reader) (T, error)
+
+	mu      sync.RWMutex
+	value   T
+	modTime time.Time
+}
+
+// An empty path makes a file that is never read, leaving `empty` in effect
+func NewFile[T any](path string, empty T, parse func(r io.Reader) (T, error)) (*File[T], error) {
+	f := &File[T]{
+		path:  path,
+		parse: parse,
+		value: empty}
+	if err := f.Reload(); err != nil {
+		return nil, fmt.Errorf("reloadable<NewFile>: %w", err)
+	}
+	return f, nil
+}
+
+// Parses the file again when it had been modified since the last read
+func (f *File[T]) Reload() error {
+	if f.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("reloadable<File.Reload>: %w", err)
+	}
+	f.mu.RLock()
+	unchanged := info.ModTime().Equal(f.modTime)
+	f.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("reloadable<File.Reload>: %w", err)
+	}
+	defer file.Close()
+	value, err := f.parse(file)
+	if err != nil {
+		return fmt.Errorf("reloadable<File.Reload>: %s: %w", f.path, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value = value
+	f.modTime = info.ModTime()
+	return nil
+}
+
+// The value parsed by the last successful read
+func (f *File[T]) Value() T {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.value
+}
@@ -0,0 +1,187 @@
+package unfurl
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/solsteace/kochira/link/internal/utility/netguard"
+)
+
+// What a page says about itself. URLs are absolute, and empty fields are the
+// ones the page didn't have
+type Page struct {
+	Title         string
+	OgTitle       string
+	OgDescription string
+	OgImage       string
+	Favicon       string
+}
+
+// Tells what a destination page says about itself
+type Fetcher interface {
+	Fetch(ctx context.Context, destination string) (Page, error)
+}
+
+// How many redirects are followed before giving up?
+const mAX_REDIRECTS = 5
+
+var (
+	headEndRe = regexp.MustCompile(`(?i)</head\s*>`)
+	titleRe   = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title\s*>`)
+	tagRe     = regexp.MustCompile(`(?is)<(meta|link)\b([^>]*)>`)
+	attrRe    = regexp.MustCompile(`(?is)([a-z][a-z0-9_:.-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// Fetches pages over HTTP, through `netguard.NewTransport`
+type Http struct {
+	client   *http.Client
+	timeout  time.Duration // How long fetching a page could take, including reading it?
+	maxBytes int64         // How much of the page is read? The rest is ignored
+}
+
+func NewHttp(timeout time.Duration, maxBytes int64) *Http {
+	client := &http.Client{
+		Transport: netguard.NewTransport(timeout),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= mAX_REDIRECTS {
+				return fmt.Errorf("stopped after %d redirects", mAX_REDIRECTS)
+			} else if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirected to unsupported scheme `%s`", req.URL.Scheme)
+			}
+			return nil
+		}}
+
+	return &Http{
+		client:   client,
+		timeout:  timeout,
+		maxBytes: maxBytes}
+}
+
+func (h *Http) Fetch(ctx context.Context, destination string) (Page, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return Page{}, fmt.Errorf("unfurl<Http.Fetch>: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		err := fmt.Errorf("unsupported scheme `%s`", u.Scheme)
+		return Page{}, fmt.Errorf("unfurl<Http.Fetch>: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Page{}, fmt.Errorf("unfurl<Http.Fetch>: %w", err)
+	}
+	req.Header.Set("User-Agent", "kochira-link-unfurler/1.0")
+	req.Header.Set("Accept", "text/html")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return Page{}, fmt.Errorf("unfurl<Http.Fetch>: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		err := fmt.Errorf("destination responded with %d", res.StatusCode)
+		return Page{}, fmt.Errorf("unfurl<Http.Fetch>: %w", err)
+	}
+	if contentType := res.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "text/html" {
+			err := fmt.Errorf("unsupported content type `%s`", contentType)
+			return Page{}, fmt.Errorf("unfurl<Http.Fetch>: %w", err)
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, h.maxBytes))
+	if err != nil {
+		return Page{}, fmt.Errorf("unfurl<Http.Fetch>: %w", err)
+	}
+	// Relative URLs are relative to where the redirects ended up
+	return parse(strings.ToValidUTF8(string(body), ""), res.Request.URL), nil
+}
+
+// Picks the metadata out of the page's head. Pages are scanned rather than
+// fully parsed, since only a handful of tags matter
+func parse(doc string, base *url.URL) Page {
+	if loc := headEndRe.FindStringIndex(doc); loc != nil {
+		doc = doc[:loc[0]]
+	}
+
+	page := Page{}
+	if m := titleRe.FindStringSubmatch(doc); m != nil {
+		page.Title = html.UnescapeString(m[1])
+	}
+	for _, tag := range tagRe.FindAllStringSubmatch(doc, -1) {
+		attrs := parseAttrs(tag[2])
+		switch strings.ToLower(tag[1]) {
+		case "meta":
+			key := attrs["property"]
+			if key == "" {
+				key = attrs["name"]
+			}
+			content := attrs["content"]
+			switch strings.ToLower(key) {
+			case "og:title":
+				page.OgTitle = firstOf(page.OgTitle, content)
+			case "og:description":
+				page.OgDescription = firstOf(page.OgDescription, content)
+			case "og:image":
+				page.OgImage = firstOf(page.OgImage, resolve(base, content))
+			}
+		case "link":
+			for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+				if rel == "icon" {
+					page.Favicon = firstOf(page.Favicon, resolve(base, attrs["href"]))
+					break
+				}
+			}
+		}
+	}
+
+	// Browsers look for one at the root when the page doesn't tell
+	if page.Favicon == "" {
+		page.Favicon = resolve(base, "/favicon.ico")
+	}
+	return page
+}
+
+func parseAttrs(raw string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range attrRe.FindAllStringSubmatch(raw, -1) {
+		name := strings.ToLower(m[1])
+		if _, ok := attrs[name]; ok {
+			continue
+		}
+		attrs[name] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+// Resolves the reference against the page's URL. References that aren't
+// fetchable over HTTP are dropped
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func firstOf(current, candidate string) string {
+	if current != "" {
+		return current
+	}
+	return candidate
+}
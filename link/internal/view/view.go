@@ -0,0 +1,29 @@
+package view
+
+import (
+	"embed"
+	"html/template"
+	"time"
+)
+
+//go:embed template/*.html
+var templateFs embed.FS
+
+// Asks the visitor for the password of a protected link
+var Unlock = template.Must(
+	template.ParseFS(templateFs, "template/layout.html", "template/unlock.html"))
+
+type UnlockData struct {
+	Msg string // Why the previous attempt failed, if any
+}
+
+// Shows where the link goes before the visitor decides to continue
+var Preview = template.Must(
+	template.ParseFS(templateFs, "template/layout.html", "template/preview.html"))
+
+type PreviewData struct {
+	Destination string
+	Owner       string
+	CreatedAt   time.Time
+	ExpiredAt   time.Time
+}
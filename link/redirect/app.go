@@ -0,0 +1,135 @@
+package redirect
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	_ "github.com/jackc/pgx/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/solsteace/kochira/link/internal/controller"
+	"github.com/solsteace/kochira/link/internal/persistence"
+	"github.com/solsteace/kochira/link/internal/route"
+	"github.com/solsteace/kochira/link/internal/service"
+	"github.com/solsteace/kochira/link/internal/utility/geoip"
+	"github.com/solsteace/kochira/link/internal/utility/hash"
+	"github.com/solsteace/kochira/link/internal/utility/realip"
+	"github.com/valkey-io/valkey-go"
+)
+
+type publisher struct {
+	interval time.Duration // In what interval the routine should be done?
+	callback func() error  // What to do in the routine?
+}
+
+const moduleName = "kochira/link/redirect"
+
+// Serves the shortened links on their own, apart from the rest of `link`
+// module. Any number of instances could run side by side, as they only share
+// the database and the cache
+func RunApp() {
+	// ========================================
+	// Utils
+	// ========================================
+	upSince := time.Now().Unix()
+	ipHasher := hash.NewHmacSha256(envClickSalt)
+	hasher := hash.NewBcrypt(10)
+
+	dbClient, err := sqlx.Connect("pgx", envDbUrl)
+	if err != nil {
+		log.Fatalf("%s: DB connect: %v", moduleName, err)
+	}
+	primaryDbClient := dbClient
+	if envPrimaryDbUrl != envDbUrl {
+		primaryDbClient, err = sqlx.Connect("pgx", envPrimaryDbUrl)
+		if err != nil {
+			log.Fatalf("%s: primary DB connect: %v", moduleName, err)
+		}
+	}
+
+	cacheClient, err := valkey.NewClient(
+		valkey.MustParseURL(envCacheUrl))
+	if err != nil {
+		log.Fatalf("%s: cache init: %v", moduleName, err)
+	}
+	defer cacheClient.Close()
+
+	// ========================================
+	// Layers
+	// ========================================
+	redirectRepo := persistence.NewPgRedirect(dbClient, primaryDbClient)
+	redirectCache := persistence.NewValkeyRedirect(
+		cacheClient,
+		redirectRepo,
+		envCacheRetention,
+		envCacheMissRetention,
+		envCacheStaleRetention)
+
+	geoipDb, err := geoip.NewFile(envGeoipDb)
+	if err != nil {
+		log.Fatalf("%s: geoip init: %v", moduleName, err)
+	}
+	redirectSerivce := service.NewRedirect(
+		redirectCache,
+		redirectRepo,
+		redirectRepo,
+		ipHasher,
+		hasher,
+		persistence.NewValkeyUnlockAttempt(cacheClient),
+		geoipDb,
+		4096)
+	redirectController := controller.NewRedirect(redirectSerivce)
+	redirectionRoute := route.NewRedirect(redirectController)
+
+	// ========================================
+	// Routings
+	// ========================================
+	app := chi.NewRouter()
+	v1 := chi.NewRouter()
+	app.Use(chiMiddleware.RequestID)
+	app.Use(realip.Middleware(envTrustedProxies))
+	app.Use(chiMiddleware.Logger)
+	app.Use(chiMiddleware.Recoverer)
+
+	redirectionRoute.Use(v1)
+	app.Mount("/api/v1", v1)
+	route.NewApi(upSince).Use(app)
+
+	// ========================================
+	// Side-effects
+	// ========================================
+	publishers := []publisher{
+		publisher{
+			interval: time.Second * 2,
+			callback: func() error {
+				return redirectSerivce.FlushClicks(500)
+			}},
+		publisher{
+			interval: time.Minute * 5,
+			callback: func() error {
+				// The previous ranges stay in effect meanwhile
+				if err := geoipDb.Reload(); err != nil {
+					log.Printf("%s: geoip reload: %v\n", moduleName, err)
+				}
+				return nil
+			}}}
+	for _, p := range publishers {
+		go func() {
+			t := time.NewTicker(p.interval)
+			for range t.C {
+				if err := p.callback(); err != nil {
+					log.Fatalf("%s: publisher callback: %v", moduleName, err)
+				}
+			}
+		}()
+	}
+
+	// ========================================
+	// Init
+	// ========================================
+	fmt.Printf("%s: Server's running at :%d\n", moduleName, envPort)
+	http.ListenAndServe(fmt.Sprintf(":%d", envPort), app)
+}
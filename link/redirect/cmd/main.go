@@ -0,0 +1,14 @@
+package main
+
+import (
+	"log"
+
+	"github.com/solsteace/kochira/link/redirect"
+)
+
+func main() {
+	if err := redirect.LoadEnv(); err != nil {
+		log.Fatal(err)
+	}
+	redirect.RunApp()
+}
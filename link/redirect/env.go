@@ -0,0 +1,94 @@
+package redirect
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/solsteace/kochira/link/internal/utility/realip"
+	"github.com/valkey-io/valkey-go"
+)
+
+var (
+	envPort int
+
+	envDbUrl        string // Where the links are read from. Could be a read replica
+	envPrimaryDbUrl string // Where the clicks are written to
+	envCacheUrl     string
+
+	envClickSalt string // Secret used for hashing visitors' IP
+	envGeoipDb   string // Path to the IP-to-country CSV, used by routing rules
+
+	envTrustedProxies []netip.Prefix // Proxies whose `X-Forwarded-For` tells the visitor address
+
+	// Shared with the link app, as both reach the same cache
+	envCacheRetention      time.Duration // How long a found link is cached? Changes made meanwhile invalidate it right away
+	envCacheMissRetention  time.Duration // How long a missing link is cached?
+	envCacheStaleRetention time.Duration // How long an invalidated link is read off the primary? Should outlast the replica lag
+)
+
+func LoadEnv() error {
+	switch port, err := strconv.ParseInt(os.Getenv("LINK_REDIRECT_PORT"), 10, 32); {
+	case err != nil:
+		err := fmt.Errorf("`LINK_REDIRECT_PORT`: %s", err)
+		return fmt.Errorf("redirect<LoadEnv>: %w", err)
+	case port < 0 || port > 65535:
+		err := fmt.Errorf("`LINK_REDIRECT_PORT`: port should be between 0 - 65535 (get: %d)", port)
+		return fmt.Errorf("redirect<LoadEnv>: %w", err)
+	default:
+		envPort = int(port)
+	}
+
+	// Without a replica, everything goes to the primary
+	envPrimaryDbUrl = os.Getenv("LINK_DB_URL")
+	envDbUrl = os.Getenv("LINK_REDIRECT_DB_URL")
+	if envDbUrl == "" {
+		envDbUrl = envPrimaryDbUrl
+	}
+	envCacheUrl = os.Getenv("LINK_CACHE_URL")
+	if _, err := valkey.ParseURL(envCacheUrl); err != nil {
+		err := fmt.Errorf("`LINK_CACHE_URL`: %s", err)
+		return fmt.Errorf("redirect<LoadEnv>: %w", err)
+	}
+	envClickSalt = os.Getenv("LINK_CLICK_SALT")
+	if envClickSalt == "" {
+		err := errors.New("`LINK_CLICK_SALT`: should be set, as visitors' IP would be hashed without a secret otherwise")
+		return fmt.Errorf("redirect<LoadEnv>: %w", err)
+	}
+	envGeoipDb = os.Getenv("LINK_GEOIP_DB")
+	trustedProxies, err := realip.ParseTrusted(os.Getenv("LINK_TRUSTED_PROXIES"))
+	if err != nil {
+		err := fmt.Errorf("`LINK_TRUSTED_PROXIES`: %s", err)
+		return fmt.Errorf("redirect<LoadEnv>: %w", err)
+	}
+	envTrustedProxies = trustedProxies
+
+	for key, dst := range map[string]struct {
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		"LINK_REDIRECT_CACHE_RETENTION":       {&envCacheRetention, time.Minute * 10},
+		"LINK_REDIRECT_CACHE_MISS_RETENTION":  {&envCacheMissRetention, time.Second * 30},
+		"LINK_REDIRECT_CACHE_STALE_RETENTION": {&envCacheStaleRetention, time.Minute},
+	} {
+		*dst.value = dst.fallback
+		raw := os.Getenv(key)
+		if raw == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			err := fmt.Errorf("`%s`: %s", key, err)
+			return fmt.Errorf("redirect<LoadEnv>: %w", err)
+		} else if d <= 0 {
+			err := fmt.Errorf("`%s`: duration should be positive (get: %s)", key, raw)
+			return fmt.Errorf("redirect<LoadEnv>: %w", err)
+		}
+		*dst.value = d
+	}
+	return nil
+}
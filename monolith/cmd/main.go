@@ -1,19 +1,32 @@
 package main
 
 import (
+	"log"
+
 	"github.com/solsteace/kochira/account"
 	"github.com/solsteace/kochira/link"
+	"github.com/solsteace/kochira/link/redirect"
 	"github.com/solsteace/kochira/subscription"
 )
 
 func main() {
 	done := make(chan struct{})
 
-	link.LoadEnv()
+	if err := link.LoadEnv(); err != nil {
+		log.Fatal(err)
+	}
 	go link.RunApp()
-	account.LoadEnv()
+	if err := redirect.LoadEnv(); err != nil {
+		log.Fatal(err)
+	}
+	go redirect.RunApp()
+	if err := account.LoadEnv(); err != nil {
+		log.Fatal(err)
+	}
 	go account.RunApp()
-	subscription.LoadEnv()
+	if err := subscription.LoadEnv(); err != nil {
+		log.Fatal(err)
+	}
 	go subscription.RunApp()
 
 	<-done
@@ -87,7 +87,7 @@ func RunApp() {
 	// ================================
 
 	createSubscription := messaging.CreateSubscriptionMessenger{Version: 1}
-	checkSubscription := messaging.CheckSubscriptionMessenger{Version: 1}
+	checkSubscription := messaging.CheckSubscriptionMessenger{Version: 2}
 	finishShortening := messaging.FinishShorteningMessenger{Version: 1}
 	subscriptionExpired := messaging.SubscriptionExpiredMessenger{Version: 1}
 	userContext := middleware.NewUserContext("X-User-Id")
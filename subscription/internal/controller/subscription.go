@@ -68,15 +68,22 @@ func (s Subscription) ListenCheckSubscription(msg []byte) error {
 		return fmt.Errorf("controller<Subscription.ListenCheckSubscription>: %w", err)
 	}
 
-	if s.checkSubscription.Version != payload.Meta.Version {
+	// Messages of v1 are still accepted, which were published before the
+	// subscriber was carried. They're checked against the user's own
+	// subscription instead
+	if payload.Meta.Version != 1 && payload.Meta.Version != s.checkSubscription.Version {
 		return fmt.Errorf(
 			"controller<Subscription.ListenCheckSubscription>: "+
 				"incompatible version between messenger(v:%d) and message(v:%d)",
-			s.createSubscription.Version, payload.Meta.Version)
+			s.checkSubscription.Version, payload.Meta.Version)
+	}
+	subscriberId := payload.Data.SubscriberId
+	if subscriberId == 0 {
+		subscriberId = payload.Data.UserId
 	}
 
 	err = s.service.Check(
-		payload.Data.UserId,
+		subscriberId,
 		payload.Data.CtxId,
 		payload.Data.Usecase)
 	if err != nil {
@@ -6,9 +6,10 @@ import (
 )
 
 type checkSubscriptionData struct {
-	CtxId   uint64 `json:"contextId"` // What is the context id of the message?
-	UserId  uint64 `json:"userId"`    // Whose subscription to check?
-	Usecase string `json:"usecase"`   // What is the purpose of the check?
+	CtxId        uint64 `json:"contextId"`    // What is the context id of the message?
+	UserId       uint64 `json:"userId"`       // Who asked for the check?
+	SubscriberId uint64 `json:"subscriberId"` // Whose subscription to check?
+	Usecase      string `json:"usecase"`      // What is the purpose of the check?
 }
 
 type checkSubscriptionPayload struct {
@@ -2,10 +2,13 @@ package persistence
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/solsteace/go-lib/oops"
 	"github.com/solsteace/kochira/subscription/internal/domain/subscription"
 	"github.com/solsteace/kochira/subscription/internal/domain/subscription/messaging"
 	"github.com/solsteace/kochira/subscription/internal/domain/subscription/value"
@@ -42,8 +45,17 @@ func (repo pg) GetByOwner(id uint64) (subscription.Subscription, error) {
 		WHERE user_id = $1`
 	args := []any{id}
 	if err := repo.db.Get(row, query, args...); err != nil {
-		return subscription.Subscription{}, fmt.Errorf(
-			"persistence<pg.GetByOwner>: %w", err)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return subscription.Subscription{}, fmt.Errorf(
+				"persistence<pg.GetByOwner>: %w",
+				oops.NotFound{
+					Err: err,
+					Msg: fmt.Sprintf("subscription(user_id:%d) not found", id)})
+		default:
+			return subscription.Subscription{}, fmt.Errorf(
+				"persistence<pg.GetByOwner>: %w", err)
+		}
 	}
 
 	return row.ToDomain()
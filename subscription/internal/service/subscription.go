@@ -59,12 +59,14 @@ func (s Subscription) Init(userId []uint64) error {
 	return nil
 }
 
+// Checks the subscription of `subscriberId`, who isn't necessarily the one
+// asking, as on workspaces running on someone else's subscription
 func (p Subscription) Check(
-	userId uint64,
+	subscriberId uint64,
 	contextId uint64,
 	usecase string,
 ) error {
-	subscription, err := p.store.GetByOwner(userId)
+	subscription, err := p.store.GetByOwner(subscriberId)
 	if err != nil {
 		return fmt.Errorf("service<Subscription.Check>: %w", err)
 	}